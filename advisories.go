@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// validateAdvisoryFlags validates the flags of advisory mode.
+func validateAdvisoryFlags(flags *cliFlags) {
+	if flags.configFile != "" || flags.lint || flags.policyFile != "" || flags.hasOperationFlags() {
+		fatalf("Error: Cannot use -advisories with -config, -lint, -policy, or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)")
+	}
+	if flags.reportFile != "" {
+		fatalf("Error: Cannot use -report-file with -advisories")
+	}
+	if !flags.fix && flags.hasUpdateBehaviourFlags() {
+		fatalf("Error: -preserve-operator, -allow-downgrade, -prerelease, and -follow-references require -advisories -fix")
+	}
+}
+
+// runAdvisoryMode checks every file against the advisory file and, when -fix is set, updates the
+// affected pins to their first fixed version before checking the files again.
+//
+// Parameters:
+//   - files: The files to check
+//   - flags: The command-line flags, including the advisory file and -fix
+//
+// Returns:
+//   - error: Non-nil when the advisories cannot be loaded, a file cannot be processed, or any
+//     affected pin remains
+func runAdvisoryMode(files []string, flags *cliFlags) error {
+	advisories, err := bump.LoadAdvisories(flags.advisoryFile)
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error loading advisory file: %w", err)
+	}
+
+	// A dry run prints every finding before the updates it would make; otherwise -fix reports only
+	// the pins that remain affected after updating
+	pins, fileErrors := checkAdvisories(files, advisories, flags, !flags.fix || flags.dryRun)
+	remaining := len(pins)
+	var updates, updateErrors int
+	if flags.fix {
+		updates, updateErrors, err = fixAdvisoryPins(files, pins, flags)
+		if err != nil {
+			return err
+		}
+		if flags.dryRun {
+			remaining = 0
+			for _, pin := range pins {
+				if pin.Target == "" || pin.Current == "" {
+					remaining++
+				}
+			}
+		} else {
+			pins, fileErrors = checkAdvisories(files, advisories, flags, true)
+			remaining = len(pins)
+		}
+	}
+
+	printAdvisorySummary(updates, remaining, len(files)-fileErrors, flags)
+	if fileErrors > 0 {
+		return fmt.Errorf("%d file(s) could not be checked", fileErrors)
+	}
+	if updateErrors > 0 {
+		return fmt.Errorf("%d error(s) occurred while updating affected pins", updateErrors)
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d affected pin(s)", remaining)
+	}
+	return nil
+}
+
+// checkAdvisories checks every file, printing the findings and file errors when print is set.
+//
+// Returns:
+//   - []bump.AffectedPin: The affected pins of every file
+//   - int: The number of files that could not be checked
+func checkAdvisories(files []string, advisories *bump.Advisories, flags *cliFlags, print bool) ([]bump.AffectedPin, int) {
+	var pins []bump.AffectedPin
+	fileErrors := 0
+	for _, file := range files {
+		findings, filePins, err := bump.CheckAdvisories(file, advisories, flags.options())
+		if err != nil {
+			if print {
+				log.Printf("Error processing %s: %v", file, err)
+			}
+			fileErrors++
+			continue
+		}
+		if print {
+			for _, finding := range findings {
+				fmt.Printf("%s:%d:%d: %s\n", file, finding.Pos.Line, finding.Pos.Column, finding.Message)
+			}
+		}
+		pins = append(pins, filePins...)
+	}
+	return pins, fileErrors
+}
+
+// fixAdvisoryPins updates the fixable pins through the module and provider update paths, limited
+// to the current constraints of the affected pins. Missing versions are never added.
+//
+// Returns:
+//   - int: The number of files updated (or that would be updated in dry-run mode), per update
+//   - int: The number of files that could not be processed
+//   - error: Any error encountered while preparing the updates
+func fixAdvisoryPins(files []string, pins []bump.AffectedPin, flags *cliFlags) (totalUpdates, totalErrors int, err error) {
+	engine := flags.engine()
+	engine.ForceAdd = false
+	result, err := engine.Apply(context.Background(), bump.AdvisoryFixes(pins), bump.Files(files))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, change := range result.Changes {
+		if change.Kind != bump.ReferenceChange {
+			totalUpdates++
+		}
+	}
+	return totalUpdates, len(result.Diagnostics), nil
+}
+
+// printAdvisorySummary prints the totals of an advisory run.
+func printAdvisorySummary(updates, remaining, files int, flags *cliFlags) {
+	switch {
+	case flags.fix && flags.dryRun:
+		fmt.Printf("\nAdvisories: would apply %d update(s), %d affected pin(s) remaining in %d file(s)\n", updates, remaining, files)
+	case flags.fix:
+		fmt.Printf("\nAdvisories: %d update(s) applied, %d affected pin(s) remaining in %d file(s)\n", updates, remaining, files)
+	case remaining == 0:
+		fmt.Printf("\n✓ No pins permit affected versions in %d file(s)\n", files)
+	default:
+		fmt.Printf("\nAdvisories: %d affected pin(s) in %d file(s)\n", remaining, files)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAdvisories = `[
+  {
+    "id": "TFVB-2024-001",
+    "summary": "NAT routes are dropped",
+    "affected": [
+      {
+        "package": {"ecosystem": "terraform-module", "name": "terraform-aws-modules/vpc/aws"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "5.0.0"}, {"fixed": "5.1.2"}]}],
+        "versions": ["5.0.1", "4.9.9"]
+      }
+    ]
+  },
+  {
+    "id": "TFVB-2024-002",
+    "affected": [
+      {
+        "package": {"ecosystem": "terraform-module", "name": "terraform-aws-modules/vpc/aws"},
+        "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "5.1.0"}, {"fixed": "5.2.0"}, {"introduced": "7.0.0"}]}]
+      },
+      {
+        "package": {"ecosystem": "terraform-provider", "name": "registry.terraform.io/hashicorp/aws"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "4.67.0"}, {"introduced": "5.10.0"}, {"fixed": "5.11.0"}]}]
+      }
+    ]
+  }
+]`
+
+func TestCommandChecksAndFixesAdvisories(t *testing.T) {
+	input := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+}
+
+module "unpinned" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+module "safe" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.2.0"
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "5.10.1"
+    }
+  }
+}
+`
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "main.tf", input)
+	advisories := writeTestFile(t, dir, "advisories.json", testAdvisories)
+	pattern := dir + "/*.tf"
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", pattern, "-advisories", advisories})
+	if result.exitCode != 1 || result.diagnostics != "3 affected pin(s)\n" {
+		t.Fatalf("result = %#v, want 3 affected pins", result)
+	}
+	for _, line := range []string{
+		file + ":3:13: Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '~> 5.0' permits versions affected by TFVB-2024-001 (NAT routes are dropped); fixed in '5.1.2'",
+		file + ":3:13: Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '~> 5.0' permits versions affected by TFVB-2024-002; fixed in '5.2.0'",
+		file + ":6:1: Module 'unpinned' (source: 'terraform-aws-modules/vpc/aws') has no version, so it permits versions affected by TFVB-2024-001 (NAT routes are dropped); no fixed version",
+		file + ":19:17: Provider 'aws' version '5.10.1' permits versions affected by TFVB-2024-002; fixed in '5.11.0'",
+		"Advisories: 3 affected pin(s) in 1 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+	if strings.Contains(result.stdout, "'safe'") {
+		t.Errorf("stdout = %q, want no finding for the fixed module", result.stdout)
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "-pattern", pattern, "-advisories", advisories, "-fix", "-dry-run"})
+	if result.exitCode != 1 || !strings.Contains(result.stdout, "Advisories: would apply 2 update(s), 1 affected pin(s) remaining in 1 file(s)") {
+		t.Fatalf("result = %#v, want a dry run with 1 remaining pin", result)
+	}
+	if readTestFile(t, file) != input {
+		t.Fatal("dry run modified main.tf")
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "-pattern", pattern, "-advisories", advisories, "-fix"})
+	if result.exitCode != 1 || result.diagnostics != "1 affected pin(s)\n" {
+		t.Fatalf("result = %#v, want 1 remaining pin", result)
+	}
+	for _, line := range []string{
+		"✓ Updated module source 'terraform-aws-modules/vpc/aws' from version(s) [~> 5.0] to '5.2.0' in " + file,
+		"✓ Updated provider 'aws' to version '5.11.0' in " + file,
+		"Advisories: 2 update(s) applied, 1 affected pin(s) remaining in 1 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+	want := strings.Replace(strings.Replace(input, `"~> 5.0"`, `"5.2.0"`, 1), `"5.10.1"`, `"5.11.0"`, 1)
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// validateAlignFlags validates the flags of align mode, which takes its targets from the files
+// rather than from -to or a config file.
+func validateAlignFlags(flags *cliFlags) {
+	if flags.configFile != "" || flags.lint || flags.policyFile != "" || flags.advisoryFile != "" || flags.toVersion != "" || flags.terraformVersion != "" || len(flags.fromVersions) > 0 ||
+		flags.providerSource != "" || flags.addMissing {
+		fatalf("Error: Cannot use -align with -config, -lint, -policy, -advisories, -to, -terraform-version, -from, -provider-source, or -add-missing")
+	}
+	if flags.alignScope != "" && !bump.ValidAlignScope(flags.alignScope) {
+		fatalf("Error: Invalid align scope '%s'. Must be 'repo' or 'directory'", flags.alignScope)
+	}
+}
+
+// runAlignMode moves every module block and provider entry in each scope to the highest version in
+// use for its source or provider, through the module and provider update paths. -module and
+// -provider limit the sources aligned; -ignore-modules and -ignore-version leave blocks out.
+//
+// Parameters:
+//   - files: The files to align
+//   - flags: The command-line flags, including the align scope and filters
+//
+// Returns:
+//   - error: Non-nil when a file cannot be read or updated
+func runAlignMode(files []string, flags *cliFlags) error {
+	opts := bump.AlignOptions{
+		Module:         flags.moduleSource,
+		Provider:       flags.providerName,
+		IgnoreModules:  ignorePatterns(flags.ignoreModules),
+		IgnoreVersions: flags.ignoreVersions,
+	}
+	scopes := []bump.Directory{{Files: files}}
+	if flags.alignScope == bump.AlignDirectory {
+		scopes = bump.Files(files).Directories()
+	}
+
+	targets, updates, totalErrors := 0, 0, 0
+	for _, scope := range scopes {
+		config, readable, fileErrors := alignScope(scope, opts, flags)
+		totalErrors += fileErrors
+		targets += len(config.Modules) + len(config.Providers)
+		if len(config.Modules)+len(config.Providers) == 0 {
+			continue
+		}
+		changes, errors, err := flags.apply(readable, config)
+		if err != nil {
+			return err
+		}
+		updates += changes[bump.ModuleChange] + changes[bump.ProviderChange]
+		totalErrors += errors[bump.ModuleChange] + errors[bump.ProviderChange]
+	}
+
+	printAlignSummary(targets, updates, flags.dryRun)
+	if totalErrors > 0 {
+		return fmt.Errorf("%d alignment error(s)", totalErrors)
+	}
+	return nil
+}
+
+// alignScope reads the pins of one scope and prints the target of each source or provider whose
+// versions differ.
+//
+// Returns:
+//   - bump.Config: The updates that align the scope
+//   - []string: The files of the scope that could be read
+//   - int: The number of files that could not be read
+func alignScope(scope bump.Directory, opts bump.AlignOptions, flags *cliFlags) (bump.Config, []string, int) {
+	var pins []bump.PinnedVersion
+	var readable []string
+	fileErrors := 0
+	for _, file := range scope.Files {
+		filePins, err := bump.FindPinnedVersions(file, flags.options())
+		if err != nil {
+			log.Printf("Error processing %s: %v", file, err)
+			fileErrors++
+			continue
+		}
+		pins = append(pins, filePins...)
+		readable = append(readable, file)
+	}
+
+	targets := bump.AlignTargets(pins, opts)
+	location := ""
+	if scope.Path != "" {
+		location = " in " + scope.Path
+	}
+	for _, target := range targets {
+		subject := "provider"
+		if target.Module {
+			subject = "module source"
+		}
+		fmt.Printf("Aligning %s %s on %s%s (replacing %s)\n", subject, quote(target.Name, flags.output),
+			quote(target.Version, flags.output), location, quoteVersions(target.From, flags.output))
+	}
+	return bump.AlignUpdates(targets, opts), readable, fileErrors
+}
+
+// quoteVersions returns versions quoted for the output format and separated by commas.
+func quoteVersions(versions []string, outputFormat string) string {
+	quoted := make([]string, len(versions))
+	for index, version := range versions {
+		quoted[index] = quote(version, outputFormat)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// printAlignSummary prints the totals of align mode.
+func printAlignSummary(targets, updates int, dryRun bool) {
+	switch {
+	case targets == 0:
+		fmt.Println("\n✓ Every selected module source and provider already uses one version")
+	case dryRun:
+		fmt.Printf("\nDry run: would apply %d update(s) to align %d module source(s) and provider(s)\n", updates, targets)
+	default:
+		fmt.Printf("\nSuccessfully applied %d update(s) to align %d module source(s) and provider(s)\n", updates, targets)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const alignTestModule = "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"%s\"\n}\n"
+
+func writeAlignTestFiles(t *testing.T, dir string) {
+	t.Helper()
+	for _, env := range []string{"dev", "prod"} {
+		if err := os.MkdirAll(filepath.Join(dir, env), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, dir, "dev/main.tf", strings.Replace(alignTestModule, "%s", "4.0.0", 1))
+	writeTestFile(t, dir, "dev/network.tf", strings.Replace(alignTestModule, "%s", "4.1.0", 1))
+	writeTestFile(t, dir, "prod/main.tf", strings.Replace(alignTestModule, "%s", "5.0.0", 1))
+	writeTestFile(t, dir, "prod/versions.tf", "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n  }\n}\n")
+	writeTestFile(t, dir, "dev/versions.tf", "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 6.0\"\n    }\n  }\n}\n")
+}
+
+func TestCommandAlignsRepository(t *testing.T) {
+	dir := t.TempDir()
+	writeAlignTestFiles(t, dir)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "**/*.tf", "-align", "-ignore-version", "4.1.0"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	for _, want := range []string{
+		"Aligning module source 'terraform-aws-modules/vpc/aws' on '5.0.0' (replacing '4.0.0')\n",
+		"Aligning provider 'aws' on '~> 6.0' (replacing '~> 5.0')\n",
+		"\nSuccessfully applied 2 update(s) to align 2 module source(s) and provider(s)\n",
+	} {
+		if !strings.Contains(result.stdout, want) {
+			t.Errorf("stdout = %q, want it to contain %q", result.stdout, want)
+		}
+	}
+	if got := readTestFile(t, filepath.Join("dev", "main.tf")); !strings.Contains(got, `"5.0.0"`) {
+		t.Errorf("dev/main.tf not aligned:\n%s", got)
+	}
+	if got := readTestFile(t, filepath.Join("dev", "network.tf")); !strings.Contains(got, `"4.1.0"`) {
+		t.Errorf("dev/network.tf changed despite -ignore-version:\n%s", got)
+	}
+	if got := readTestFile(t, filepath.Join("prod", "versions.tf")); !strings.Contains(got, `"~> 6.0"`) {
+		t.Errorf("prod/versions.tf not aligned:\n%s", got)
+	}
+}
+
+func TestCommandAlignsEachDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeAlignTestFiles(t, dir)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "**/*.tf", "-align", "-align-scope", "directory", "-module", "terraform-aws-modules/vpc/aws", "-dry-run"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	for _, want := range []string{
+		"Aligning module source 'terraform-aws-modules/vpc/aws' on '4.1.0' in dev (replacing '4.0.0')\n",
+		"\nDry run: would apply 1 update(s) to align 1 module source(s) and provider(s)\n",
+	} {
+		if !strings.Contains(result.stdout, want) {
+			t.Errorf("stdout = %q, want it to contain %q", result.stdout, want)
+		}
+	}
+	if strings.Contains(result.stdout, "Aligning provider") || strings.Contains(result.stdout, " in prod ") {
+		t.Errorf("stdout = %q, want only the module aligned, within dev", result.stdout)
+	}
+	if got := readTestFile(t, filepath.Join("dev", "main.tf")); !strings.Contains(got, `"4.0.0"`) {
+		t.Errorf("dev/main.tf written in a dry run:\n%s", got)
+	}
+}
+
+func TestAlignFlagValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "align with a target",
+			args: []string{"-pattern", "*.tf", "-align", "-to", "1.0.0"},
+			want: "Error: Cannot use -align with -config, -lint, -policy, -advisories, -to, -terraform-version, -from, -provider-source, or -add-missing",
+		},
+		{
+			name: "invalid scope",
+			args: []string{"-pattern", "*.tf", "-align", "-align-scope", "module"},
+			want: "Error: Invalid align scope 'module'. Must be 'repo' or 'directory'",
+		},
+		{
+			name: "scope without align",
+			args: []string{"-pattern", "*.tf", "-module", "example/module", "-to", "1.0.0", "-align-scope", "directory"},
+			want: "Error: -align-scope requires -align",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runMainCommand(t, append([]string{"tf-version-bump"}, tt.args...))
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+// Branch mode applies one update to several local branches. Each branch is checked out in its own
+// temporary Git worktree, so the caller's worktree, index, and checked-out branch are never
+// touched; changes are committed in the temporary worktree, which moves only the branch itself.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Branch result statuses recorded in the update report.
+const (
+	branchUpdated     = "updated"
+	branchWouldUpdate = "would_update"
+	branchUnchanged   = "unchanged"
+	branchSkipped     = "skipped"
+	branchFailed      = "failed"
+)
+
+// branchResult is the outcome of one branch in branch mode, as listed in the update report.
+type branchResult struct {
+	Branch                string `json:"branch"`
+	Status                string `json:"status"`
+	Commit                string `json:"commit,omitempty"`
+	Changes               int    `json:"changes"`
+	ModuleBlocksUpdated   int    `json:"module_blocks_updated"`
+	ProviderBlocksUpdated int    `json:"provider_blocks_updated"`
+	Message               string `json:"message,omitempty"`
+}
+
+// commitMessageData holds the values a -commit-message template can use.
+type commitMessageData struct {
+	Branch           string // Branch being committed to
+	Module           string // -module source
+	Provider         string // -provider name
+	TerraformVersion string // -terraform-version constraint
+	Version          string // -to version, or the -terraform-version constraint
+	Config           string // -config file
+	Group            string // -group name
+	Changes          int    // Number of updates applied on the branch
+}
+
+// validateBranchFlags validates the flags of branch mode.
+func validateBranchFlags(flags *cliFlags) {
+	if flags.branches == "" {
+		if flags.commitMessage != "" {
+			fatalf("Error: -commit-message requires -branches")
+		}
+		return
+	}
+	if flags.lint || flags.policyFile != "" || flags.advisoryFile != "" {
+		fatalf("Error: Cannot use -branches with -lint, -policy, or -advisories")
+	}
+	if !doublestar.ValidatePattern(flags.branches) {
+		fatalf("Error: Invalid -branches pattern '%s'", flags.branches)
+	}
+	tmpl, err := flags.commitTemplate()
+	if err == nil {
+		err = tmpl.Execute(io.Discard, commitMessageData{})
+	}
+	if err != nil {
+		fatalf("Error: Invalid -commit-message template: %v", err)
+	}
+}
+
+// commitTemplate parses the commit message template, or the default for the operation mode.
+func (flags *cliFlags) commitTemplate() (*template.Template, error) {
+	message := flags.commitMessage
+	if message == "" {
+		switch {
+		case flags.align:
+			message = "chore: align Terraform module and provider versions"
+		case flags.group != "":
+			message = "chore: apply tf-version-bump group {{.Group}}"
+		case flags.configFile != "":
+			message = "chore: apply tf-version-bump config"
+		case flags.terraformVersion != "":
+			message = "chore: set Terraform required_version to {{.Version}}"
+		case flags.providerName != "":
+			message = "chore: bump provider {{.Provider}} to {{.Version}}"
+		default:
+			message = "chore: bump {{.Module}} to {{.Version}}"
+		}
+	}
+	return template.New("commit-message").Option("missingkey=error").Parse(message)
+}
+
+// runBranchCommand runs branch mode, writes the update report, and exits non-zero when a branch
+// failed. The report is written even then, so that automation can see which branches failed.
+func runBranchCommand(flags *cliFlags) {
+	if flags.pattern == "" {
+		fatalf("Error: -pattern flag is required")
+	}
+	// A pattern outside the current directory could select files of the caller's worktree.
+	if pattern := filepath.Clean(flags.pattern); filepath.IsAbs(pattern) || pattern == ".." || strings.HasPrefix(pattern, ".."+string(filepath.Separator)) {
+		fatalf("Error: -branches requires a -pattern relative to and within the current directory")
+	}
+	validateRequiredOperationFlags(flags)
+	var inputFiles []string
+	if flags.configFile != "" {
+		configFile, err := filepath.Abs(flags.configFile)
+		if err != nil {
+			fatalf("Error resolving config file: %v", err)
+		}
+		flags.configFile = configFile
+		inputFiles = append(inputFiles, configFile)
+	}
+	preparedReport, err := prepareUpdateReport(flags.reportFile, inputFiles)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	err = runBranchMode(flags)
+	if preparedReport != nil && flags.report.Branches != nil {
+		flags.report.SchemaVersion = 1
+		if publishErr := preparedReport.publish(flags.report); publishErr != nil {
+			fatalf("Error writing update report: %v", publishErr)
+		}
+	} else if preparedReport != nil {
+		if discardErr := preparedReport.discard(); discardErr != nil {
+			err = fmt.Errorf("%w; failed to discard prepared report: %v", err, discardErr)
+		}
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// runBranchMode applies the update to every local branch matching the -branches glob.
+func runBranchMode(flags *cliFlags) error {
+	repository, err := git(".", "rev-parse", "--show-toplevel")
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error: -branches requires a Git repository: %w", err)
+	}
+	// The pattern is relative to the current directory, which is the same subdirectory of each
+	// temporary worktree.
+	prefix, err := git(".", "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	branches, checkedOut, err := listBranches(repository, flags.branches)
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error: No local branches matched: %s", flags.branches)
+	}
+
+	worktrees, err := os.MkdirTemp("", "tf-version-bump-branches-")
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error creating worktree directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(worktrees) }()
+
+	flags.report.Branches = []branchResult{}
+	for index, branch := range branches {
+		fmt.Printf("\nBranch %s\n", quote(branch, flags.output))
+		result := branchResult{Branch: branch}
+		if worktree, ok := checkedOut[branch]; ok {
+			result.Status = branchSkipped
+			result.Message = "checked out in " + worktree
+			fmt.Fprintf(os.Stderr, "Warning: Branch %s is checked out in %s, skipping\n", quote(branch, flags.output), worktree)
+		} else {
+			worktree := filepath.Join(worktrees, fmt.Sprint(index))
+			flags.processBranch(&result, repository, worktree, prefix)
+		}
+		flags.report.Branches = append(flags.report.Branches, result)
+	}
+
+	return printBranchSummary(flags.report.Branches, flags.dryRun)
+}
+
+// listBranches returns the local branches matching a glob, in the order Git lists them, and the
+// worktree that each checked-out branch is checked out in.
+func listBranches(repository, pattern string) (branches []string, checkedOut map[string]string, err error) {
+	refs, err := git(repository, "for-each-ref", "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, branch := range strings.Split(refs, "\n") {
+		if matched, _ := doublestar.Match(pattern, branch); matched && branch != "" {
+			branches = append(branches, branch)
+		}
+	}
+
+	list, err := git(repository, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, nil, err
+	}
+	checkedOut = make(map[string]string)
+	var worktree string
+	for _, line := range strings.Split(list, "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			worktree = path
+		} else if ref, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+			checkedOut[ref] = worktree
+		}
+	}
+	return branches, checkedOut, nil
+}
+
+// processBranch applies the update to one branch in a temporary worktree and, unless this is a
+// dry run, commits the changes there. A branch whose update fails is left unchanged.
+func (flags *cliFlags) processBranch(result *branchResult, repository, worktree, prefix string) {
+	fail := func(err error) {
+		result.Status, result.Message = branchFailed, err.Error()
+		log.Printf("Error processing branch %s: %v", result.Branch, err)
+	}
+
+	args := []string{"worktree", "add", "--quiet"}
+	if flags.dryRun {
+		args = append(args, "--detach")
+	}
+	if _, err := git(repository, append(args, worktree, result.Branch)...); err != nil {
+		fail(err)
+		return
+	}
+	defer func() {
+		if _, err := git(repository, "worktree", "remove", "--force", worktree); err != nil {
+			log.Printf("Error removing worktree %s: %v", worktree, err)
+		}
+	}()
+
+	moduleBlocks, providerBlocks := flags.report.ModuleBlocksUpdated, flags.report.ProviderBlocksUpdated
+	flags.changeCount, flags.directories = 0, nil
+	err := flags.updateWorktree(filepath.Join(worktree, prefix))
+	result.Changes = flags.changeCount
+	result.ModuleBlocksUpdated = flags.report.ModuleBlocksUpdated - moduleBlocks
+	result.ProviderBlocksUpdated = flags.report.ProviderBlocksUpdated - providerBlocks
+	// Each worktree is removed before the next is created, so its files' identities may be
+	// reused; blocks are told apart within a branch only.
+	flags.report.moduleBlockIDs, flags.report.providerBlockIDs, flags.report.fileIdentities = nil, nil, nil
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	status, err := git(worktree, "status", "--porcelain")
+	switch {
+	case err != nil:
+		fail(err)
+	case flags.dryRun && result.Changes > 0:
+		result.Status = branchWouldUpdate
+	case flags.dryRun || status == "":
+		result.Status = branchUnchanged
+	default:
+		flags.commitBranch(result, worktree, fail)
+	}
+}
+
+// updateWorktree runs the update on the files of a worktree directory. The files are selected
+// and reported relative to the directory, as they would be in a checkout of the branch.
+func (flags *cliFlags) updateWorktree(dir string) error {
+	original, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer func() { _ = os.Chdir(original) }()
+
+	files, err := flags.matchFiles()
+	if err != nil {
+		return fmt.Errorf("matching pattern: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Printf("No files matched pattern: %s\n", flags.pattern)
+		return nil
+	}
+	flags.printFileSelection(files)
+	return runUpdateMode(files, flags)
+}
+
+// commitBranch commits the changes of a worktree with the templated commit message.
+func (flags *cliFlags) commitBranch(result *branchResult, worktree string, fail func(error)) {
+	tmpl, err := flags.commitTemplate()
+	if err != nil {
+		fail(err)
+		return
+	}
+	version := flags.toVersion
+	if flags.terraformVersion != "" {
+		version = flags.terraformVersion
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, commitMessageData{
+		Branch:           result.Branch,
+		Module:           flags.moduleSource,
+		Provider:         flags.providerName,
+		TerraformVersion: flags.terraformVersion,
+		Version:          version,
+		Config:           flags.configFile,
+		Group:            flags.group,
+		Changes:          result.Changes,
+	}); err != nil {
+		fail(fmt.Errorf("commit message: %w", err))
+		return
+	}
+
+	if _, err := git(worktree, "add", "--update", "--", "."); err != nil {
+		fail(err)
+		return
+	}
+	if _, err := git(worktree, "commit", "--quiet", "--message", message.String()); err != nil {
+		fail(err)
+		return
+	}
+	commit, err := git(worktree, "rev-parse", "HEAD")
+	if err != nil {
+		fail(err)
+		return
+	}
+	result.Status, result.Commit = branchUpdated, commit
+	fmt.Printf("✓ Committed %s to %s\n", commit[:min(len(commit), 12)], quote(result.Branch, flags.output))
+}
+
+// printBranchSummary prints the totals of branch mode and returns an error when a branch failed.
+func printBranchSummary(results []branchResult, dryRun bool) error {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	if dryRun {
+		fmt.Printf("\nBranches: %d would be updated, %d unchanged, %d skipped, %d failed\n",
+			counts[branchWouldUpdate], counts[branchUnchanged], counts[branchSkipped], counts[branchFailed])
+	} else {
+		fmt.Printf("\nBranches: %d updated, %d unchanged, %d skipped, %d failed\n",
+			counts[branchUpdated], counts[branchUnchanged], counts[branchSkipped], counts[branchFailed])
+	}
+	if counts[branchFailed] > 0 {
+		return fmt.Errorf("%d branch(es) failed", counts[branchFailed])
+	}
+	return nil
+}
+
+// git runs the local git binary in a directory and returns its trimmed standard output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
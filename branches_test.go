@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupBranchRepository creates a Git repository with main.tf committed on main and on the
+// release/1 and release/2 branches; release/2 no longer uses the module.
+func setupBranchRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	module := func(source string) string {
+		return "module \"network\" {\n  source  = \"" + source + "\"\n  version = \"4.0.0\"\n}\n"
+	}
+	mustGit(t, dir, "init", "--quiet", "--initial-branch", "main")
+	writeTestFile(t, dir, "main.tf", module("terraform-aws-modules/vpc/aws"))
+	mustGit(t, dir, "add", "main.tf")
+	mustGit(t, dir, "commit", "--quiet", "--message", "initial")
+	mustGit(t, dir, "branch", "release/1")
+	mustGit(t, dir, "switch", "--quiet", "--create", "release/2")
+	writeTestFile(t, dir, "main.tf", module("example/network/aws"))
+	mustGit(t, dir, "commit", "--quiet", "--all", "--message", "switch module")
+	mustGit(t, dir, "switch", "--quiet", "main")
+	return dir
+}
+
+func mustGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return output
+}
+
+func readBranchReport(t *testing.T, path string) []branchResult {
+	t.Helper()
+	var report updateReport
+	if err := json.Unmarshal([]byte(readTestFile(t, path)), &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	return report.Branches
+}
+
+func TestBranchModeCommitsToMatchingBranches(t *testing.T) {
+	dir := setupBranchRepository(t)
+	// An uncommitted edit in the caller's worktree must survive branch mode.
+	writeTestFile(t, dir, "main.tf", "# local edit\n")
+	head := mustGit(t, dir, "rev-parse", "HEAD")
+	report := filepath.Join(t.TempDir(), "report.json")
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws",
+		"-to", "5.0.0", "-branches", "release/*", "-commit-message", "chore: bump vpc to {{.Version}} on {{.Branch}}",
+		"-report-file", report})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	for _, line := range []string{
+		"Branch 'release/1'",
+		"✓ Updated module source 'terraform-aws-modules/vpc/aws' to version '5.0.0' in main.tf",
+		"Branch 'release/2'",
+		"Branches: 1 updated, 1 unchanged, 0 skipped, 0 failed",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+
+	if got := mustGit(t, dir, "log", "-1", "--format=%s", "release/1"); got != "chore: bump vpc to 5.0.0 on release/1" {
+		t.Errorf("release/1 commit message = %q", got)
+	}
+	if got := mustGit(t, dir, "show", "release/1:main.tf"); !strings.Contains(got, `version = "5.0.0"`) {
+		t.Errorf("release/1 main.tf not updated:\n%s", got)
+	}
+	if got := mustGit(t, dir, "log", "-1", "--format=%s", "release/2"); got != "switch module" {
+		t.Errorf("release/2 commit message = %q, want unchanged branch", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "main.tf")); got != "# local edit\n" {
+		t.Errorf("caller's main.tf = %q, want local edit kept", got)
+	}
+	if got := mustGit(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+	if got := mustGit(t, dir, "worktree", "list", "--porcelain"); strings.Count(got, "worktree ") != 1 {
+		t.Errorf("temporary worktrees not removed:\n%s", got)
+	}
+
+	branches := readBranchReport(t, report)
+	if len(branches) != 2 {
+		t.Fatalf("report branches = %#v", branches)
+	}
+	commit := mustGit(t, dir, "rev-parse", "release/1")
+	if got, want := branches[0], (branchResult{Branch: "release/1", Status: branchUpdated, Commit: commit, Changes: 1, ModuleBlocksUpdated: 1}); got != want {
+		t.Errorf("release/1 result = %#v, want %#v", got, want)
+	}
+	if got, want := branches[1], (branchResult{Branch: "release/2", Status: branchUnchanged}); got != want {
+		t.Errorf("release/2 result = %#v, want %#v", got, want)
+	}
+}
+
+func TestBranchModeDryRunAndCheckedOutBranch(t *testing.T) {
+	dir := setupBranchRepository(t)
+	release := mustGit(t, dir, "rev-parse", "release/1")
+	report := filepath.Join(t.TempDir(), "report.json")
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws",
+		"-to", "5.0.0", "-branches", "{main,release/1}", "-dry-run", "-report-file", report})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if !strings.Contains(result.stdout, "Branches: 1 would be updated, 0 unchanged, 1 skipped, 0 failed") {
+		t.Errorf("stdout = %q, want dry-run summary", result.stdout)
+	}
+	if got := mustGit(t, dir, "rev-parse", "release/1"); got != release {
+		t.Errorf("release/1 = %s, want %s after dry run", got, release)
+	}
+
+	branches := readBranchReport(t, report)
+	if len(branches) != 2 {
+		t.Fatalf("report branches = %#v", branches)
+	}
+	if got, want := branches[0], (branchResult{Branch: "main", Status: branchSkipped, Message: "checked out in " + dir}); got != want {
+		t.Errorf("main result = %#v, want %#v", got, want)
+	}
+	if got, want := branches[1], (branchResult{Branch: "release/1", Status: branchWouldUpdate, Changes: 1}); got != want {
+		t.Errorf("release/1 result = %#v, want %#v", got, want)
+	}
+}
+
+func TestBranchModeNoMatchingBranches(t *testing.T) {
+	dir := setupBranchRepository(t)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws",
+		"-to", "5.0.0", "-branches", "hotfix/*"})
+	if result.exitCode != 1 || !strings.Contains(result.diagnostics, "Error: No local branches matched: hotfix/*") {
+		t.Errorf("result = %#v", result)
+	}
+}
+
+func TestBranchFlagValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "commit message without branches",
+			args: []string{"-commit-message", "chore: bump"},
+			want: "Error: -commit-message requires -branches",
+		},
+		{
+			name: "branches with lint",
+			args: []string{"-branches", "release/*", "-lint"},
+			want: "Error: Cannot use -branches with -lint, -policy, or -advisories",
+		},
+		{
+			name: "invalid template",
+			args: []string{"-branches", "release/*", "-commit-message", "{{.Unknown}}"},
+			want: "Error: Invalid -commit-message template:",
+		},
+		{
+			name: "pattern outside current directory",
+			args: []string{"-branches", "release/*", "-pattern", "../*.tf", "-module", "example/module", "-to", "1.0.0"},
+			want: "Error: -branches requires a -pattern relative to and within the current directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runMainCommand(t, append([]string{"tf-version-bump"}, tt.args...))
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package bump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Advisory mode checks module blocks and required_providers entries against an advisory file of
+// known-affected versions, reporting every version constraint that permits an affected version:
+//
+//	main.tf:3:13: Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '~> 5.0' permits versions affected by TFVB-2024-001 (NAT routes are dropped); fixed in '5.1.2'
+//
+// The advisory file is JSON in a subset of the OSV schema (https://ossf.github.io/osv-schema/):
+// one advisory object, or an array of them. Each affected package names a module source or a
+// provider source address, with its affected versions given as ranges of introduced, fixed, and
+// last_affected events, or listed individually.
+//
+// With -fix, each affected pin is updated to the first version that fixes every advisory it is
+// affected by, through the same update path as -module and -provider with a -from filter for the
+// pin's current constraint. Files are then checked again, so pins that the update path skipped,
+// or that -preserve-operator left permitting an affected version, are still reported. Pins without
+// a version and advisories without a fixed version are left for review.
+
+const (
+	ecosystemModule   = "terraform-module"
+	ecosystemProvider = "terraform-provider"
+)
+
+// Advisory is one entry of an advisory file.
+type Advisory struct {
+	ID       string             `json:"id"`
+	Summary  string             `json:"summary"`
+	Affected []AdvisoryAffected `json:"affected"`
+}
+
+// AdvisoryAffected lists the affected versions of one module or provider.
+type AdvisoryAffected struct {
+	Package  AdvisoryPackage `json:"package"`
+	Ranges   []AdvisoryRange `json:"ranges"`
+	Versions []string        `json:"versions"`
+}
+
+// AdvisoryPackage identifies a module by its source, or a provider by its source address.
+type AdvisoryPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+// AdvisoryRange is a sequence of events, in version order, that open and close affected ranges.
+type AdvisoryRange struct {
+	Type   string          `json:"type"`
+	Events []AdvisoryEvent `json:"events"`
+}
+
+// AdvisoryEvent opens an affected range at Introduced ("0" for every earlier version), or closes
+// the open range before Fixed or after LastAffected.
+type AdvisoryEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// affectedRange is a range of affected versions and the version that fixes it, which is empty
+// when no fix is known.
+type affectedRange struct {
+	versionRange
+	fixed string
+}
+
+// advisoryEntry is the affected versions of one package of an advisory.
+type advisoryEntry struct {
+	id      string
+	summary string
+	module  bool
+	name    string
+	ranges  []affectedRange
+}
+
+// Advisories is a loaded advisory file.
+type Advisories struct {
+	entries []advisoryEntry
+}
+
+// LoadAdvisories reads an advisory file and validates it.
+//
+// Parameters:
+//   - filename: Path to the JSON advisory file
+//
+// Returns:
+//   - *Advisories: The advisories of the file
+//   - error: Any error encountered while reading, parsing, or validating the file
+func LoadAdvisories(filename string) (*Advisories, error) {
+	entries, err := loadAdvisories(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &Advisories{entries: entries}, nil
+}
+
+// loadAdvisories reads an advisory file and validates it.
+//
+// Parameters:
+//   - filename: Path to the JSON advisory file
+//
+// Returns:
+//   - []advisoryEntry: One entry per affected package, in file order
+//   - error: Any error encountered while reading, parsing, or validating the file
+func loadAdvisories(filename string) ([]advisoryEntry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisory file: %w", err)
+	}
+
+	var advisories []Advisory
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &advisories)
+	} else {
+		advisories = make([]Advisory, 1)
+		err = json.Unmarshal(trimmed, &advisories[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse advisory file: %w", err)
+	}
+
+	var entries []advisoryEntry
+	for index, advisory := range advisories {
+		if advisory.ID == "" {
+			return nil, fmt.Errorf("advisory at index %d is missing 'id' field", index)
+		}
+		for affectedIndex, affected := range advisory.Affected {
+			entry, err := newAdvisoryEntry(advisory, affected)
+			if err != nil {
+				return nil, fmt.Errorf("advisory %q affected entry %d %w", advisory.ID, affectedIndex, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// newAdvisoryEntry validates one affected package and converts its events and versions to ranges.
+// A listed version inside one of the package's ranges adds nothing; any other listed version is a
+// range of its own with no known fix.
+func newAdvisoryEntry(advisory Advisory, affected AdvisoryAffected) (advisoryEntry, error) {
+	entry := advisoryEntry{id: advisory.ID, summary: advisory.Summary, name: affected.Package.Name}
+	switch affected.Package.Ecosystem {
+	case ecosystemModule:
+		entry.module = true
+	case ecosystemProvider:
+	default:
+		return entry, fmt.Errorf("has unsupported ecosystem %q: must be '%s' or '%s'", affected.Package.Ecosystem, ecosystemModule, ecosystemProvider)
+	}
+	if entry.name == "" {
+		return entry, fmt.Errorf("is missing 'package.name' field")
+	}
+	if !entry.module {
+		if err := ValidateProviderSource(entry.name); err != nil {
+			return entry, fmt.Errorf("has invalid provider source %q: %w", entry.name, err)
+		}
+	}
+
+	for _, advisoryRange := range affected.Ranges {
+		ranges, err := eventRanges(advisoryRange)
+		if err != nil {
+			return entry, err
+		}
+		entry.ranges = append(entry.ranges, ranges...)
+	}
+	for _, listed := range affected.Versions {
+		version, ok := parseSemanticVersion(listed)
+		if !ok {
+			return entry, fmt.Errorf("has invalid version %q", listed)
+		}
+		exact := versionRange{lower: &versionBound{version: version, inclusive: true}, upper: &versionBound{version: version, inclusive: true}}
+		if _, covered := entry.affects(exact); !covered {
+			entry.ranges = append(entry.ranges, affectedRange{versionRange: exact})
+		}
+	}
+	if len(entry.ranges) == 0 {
+		return entry, fmt.Errorf("lists no affected ranges or versions")
+	}
+	return entry, nil
+}
+
+// eventRanges converts the events of a range to the affected ranges they open and close. A range
+// left open by its last event has no upper bound.
+func eventRanges(advisoryRange AdvisoryRange) ([]affectedRange, error) {
+	if advisoryRange.Type != "SEMVER" && advisoryRange.Type != "ECOSYSTEM" {
+		return nil, fmt.Errorf("has unsupported range type %q: must be 'SEMVER' or 'ECOSYSTEM'", advisoryRange.Type)
+	}
+
+	var ranges []affectedRange
+	var open *affectedRange
+	for index, event := range advisoryRange.Events {
+		set := 0
+		for _, text := range []string{event.Introduced, event.Fixed, event.LastAffected} {
+			if text != "" {
+				set++
+			}
+		}
+		text := event.Introduced + event.Fixed + event.LastAffected
+		version, ok := parseSemanticVersion(text)
+		if set != 1 || (!ok && event.Introduced != "0") {
+			return nil, fmt.Errorf("has invalid event at index %d: must set one of 'introduced', 'fixed', or 'last_affected' to a version", index)
+		}
+
+		switch {
+		case event.Introduced == "0":
+			open = &affectedRange{}
+		case event.Introduced != "":
+			open = &affectedRange{versionRange: versionRange{lower: &versionBound{version: version, inclusive: true}}}
+		case open == nil:
+			// A fixed or last_affected event without an introduced event before it closes nothing
+		case event.Fixed != "":
+			open.upper, open.fixed = &versionBound{version: version}, event.Fixed
+			ranges, open = append(ranges, *open), nil
+		default:
+			open.upper = &versionBound{version: version, inclusive: true}
+			ranges, open = append(ranges, *open), nil
+		}
+	}
+	if open != nil {
+		ranges = append(ranges, *open)
+	}
+	return ranges, nil
+}
+
+// affects reports whether a pin permitting the given range permits an affected version, and
+// returns the first version that fixes every affected range it permits.
+//
+// Returns:
+//   - string: The highest fixed version of the overlapping ranges, or empty when one of them has
+//     no known fix
+//   - bool: true if the permitted range overlaps an affected range
+func (entry *advisoryEntry) affects(permitted versionRange) (string, bool) {
+	var fixed *semanticVersion
+	fixedText := ""
+	affected, fixable := false, true
+	for _, candidate := range entry.ranges {
+		if !rangesOverlap(permitted, candidate.versionRange) {
+			continue
+		}
+		affected = true
+		version, ok := parseSemanticVersion(candidate.fixed)
+		if !ok {
+			fixable = false
+			continue
+		}
+		if fixed == nil || compareVersions(version, *fixed) > 0 {
+			fixed, fixedText = &version, candidate.fixed
+		}
+	}
+	if !fixable {
+		return "", affected
+	}
+	return fixedText, affected
+}
+
+// matches reports whether the entry covers a module source or a provider source address.
+func (entry *advisoryEntry) matches(module bool, source string, opentofu bool) bool {
+	if entry.module != module {
+		return false
+	}
+	if module {
+		return sameModuleSource(source, entry.name, opentofu)
+	}
+	return sameProviderSource(source, entry.name)
+}
+
+// rangesOverlap reports whether some version lies in both ranges.
+func rangesOverlap(a, b versionRange) bool {
+	return boundsMeet(a.lower, b.upper) && boundsMeet(b.lower, a.upper)
+}
+
+// boundsMeet reports whether some version is permitted by both a lower and an upper bound.
+func boundsMeet(lower, upper *versionBound) bool {
+	if lower == nil || upper == nil {
+		return true
+	}
+	switch compareVersions(lower.version, upper.version) {
+	case -1:
+		return true
+	case 0:
+		return lower.inclusive && upper.inclusive
+	}
+	return false
+}
+
+// AffectedPin is a pin that permits versions affected by at least one advisory.
+type AffectedPin struct {
+	Module  bool   // true for a module block, false for a required_providers entry
+	Name    string // The module source or provider name that updates match
+	Source  string // The source address of a provider, which updates also match
+	Current string // The literal constraint, or empty when the pin has no version
+	Target  string // The first fixed version, or empty when no fix is known
+}
+
+// AdvisoryFinding is one advisory that a pin is affected by.
+type AdvisoryFinding struct {
+	Pos     hcl.Pos
+	Message string
+}
+
+// advisoryChecker collects the affected pins of one file.
+type advisoryChecker struct {
+	entries      []advisoryEntry
+	opentofu     bool
+	outputFormat string
+	pins         []AffectedPin
+	findings     []AdvisoryFinding
+}
+
+// CheckAdvisories reads a file and checks its module blocks and required_providers entries against
+// the advisories, without modifying it.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to check
+//   - advisories: The advisories, from LoadAdvisories
+//   - opts: Terragrunt and OpenTofu apply as for updates; OutputFormat selects the quoting of
+//     messages
+//
+// Returns:
+//   - []AdvisoryFinding: One finding per advisory a pin is affected by, in written order
+//   - []AffectedPin: The affected pins, in written order
+//   - error: Any error encountered while reading or parsing the file
+func CheckAdvisories(filename string, advisories *Advisories, opts Options) ([]AdvisoryFinding, []AffectedPin, error) {
+	checker := &advisoryChecker{entries: advisories.entries, opentofu: opts.OpenTofu, outputFormat: opts.OutputFormat}
+	if err := checker.checkFile(filename, opts.Terragrunt); err != nil {
+		return nil, nil, err
+	}
+	return checker.findings, checker.pins, nil
+}
+
+// AdvisoryFixes returns the updates that move affected pins to their first fixed version: one
+// update per module source, or provider name and source address, and target version, limited to
+// the current constraints of the pins. Pins without a version or without a fix are left out, so that applying
+// the updates with ForceAdd unset never adds a version.
+func AdvisoryFixes(pins []AffectedPin) Config {
+	var config Config
+	for _, pin := range pins {
+		if pin.Target == "" || pin.Current == "" {
+			continue
+		}
+		if pin.Module {
+			index := slices.IndexFunc(config.Modules, func(update ModuleUpdate) bool {
+				return update.Source == pin.Name && update.Version == pin.Target
+			})
+			if index < 0 {
+				config.Modules, index = append(config.Modules, ModuleUpdate{Source: pin.Name, Version: pin.Target}), len(config.Modules)
+			}
+			if !containsVersion(config.Modules[index].From, pin.Current) {
+				config.Modules[index].From = append(config.Modules[index].From, pin.Current)
+			}
+			continue
+		}
+		index := slices.IndexFunc(config.Providers, func(update ProviderUpdate) bool {
+			return update.Name == pin.Name && update.fromSource == pin.Source && update.Version == pin.Target
+		})
+		if index < 0 {
+			config.Providers, index = append(config.Providers, ProviderUpdate{Name: pin.Name, Version: pin.Target, fromSource: pin.Source}), len(config.Providers)
+		}
+		if !containsVersion(config.Providers[index].from, pin.Current) {
+			config.Providers[index].from = append(config.Providers[index].from, pin.Current)
+		}
+	}
+	return config
+}
+
+// checkFile collects a file's affected pins and findings in written order.
+func (c *advisoryChecker) checkFile(filename string, terragrunt bool) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := scanPins(filename, src, terragrunt, c); err != nil {
+		return err
+	}
+	slices.SortStableFunc(c.findings, func(a, b AdvisoryFinding) int {
+		return a.Pos.Byte - b.Pos.Byte
+	})
+	return nil
+}
+
+// visitModule checks a module block against the advisories for its source.
+func (c *advisoryChecker) visitModule(name string, pos hcl.Pos, source string, version *pinValue) {
+	subject := fmt.Sprintf("Module %s (source: %s)", quote(name, c.outputFormat), quote(source, c.outputFormat))
+	c.check(AffectedPin{Module: true, Name: source}, subject, pos, source, version)
+}
+
+// visitProvider checks a required_providers entry against the advisories for its source address.
+// An entry without a source uses the provider of that name in the hashicorp namespace.
+func (c *advisoryChecker) visitProvider(name string, pos hcl.Pos, source string, version *pinValue) {
+	if source == "" {
+		source = "hashicorp/" + name
+	}
+	c.check(AffectedPin{Name: name, Source: source}, fmt.Sprintf("Provider %s", quote(name, c.outputFormat)), pos, source, version)
+}
+
+// visitTerragruntSource ignores Terragrunt sources, which advisories do not cover.
+func (c *advisoryChecker) visitTerragruntSource(string, *pinValue) {}
+
+// check records a finding for each advisory whose affected versions a pin permits. A missing
+// version permits every version. Versions that are not literal constraints are not checked.
+func (c *advisoryChecker) check(pin AffectedPin, subject string, pos hcl.Pos, source string, version *pinValue) {
+	var permitted versionRange
+	described := subject + " has no version, so it"
+	if version != nil {
+		clauses, ok := parseConstraint(version.text)
+		if !version.literal || !ok {
+			return
+		}
+		permitted = constraintRange(clauses)
+		pin.Current = version.text
+		pos = version.pos
+		described = fmt.Sprintf("%s version %s", subject, quote(version.text, c.outputFormat))
+	}
+
+	var matching []*advisoryEntry
+	affectedBy := 0
+	for index := range c.entries {
+		entry := &c.entries[index]
+		if !entry.matches(pin.Module, source, c.opentofu) {
+			continue
+		}
+		matching = append(matching, entry)
+		fixed, affected := entry.affects(permitted)
+		if !affected {
+			continue
+		}
+		message := fmt.Sprintf("%s permits versions affected by %s", described, entry.id)
+		if entry.summary != "" {
+			message += " (" + entry.summary + ")"
+		}
+		if fixed != "" {
+			message += "; fixed in " + quote(fixed, c.outputFormat)
+		} else {
+			message += "; no fixed version"
+		}
+		c.findings = append(c.findings, AdvisoryFinding{Pos: pos, Message: message})
+		affectedBy++
+	}
+	if affectedBy > 0 {
+		pin.Target = firstFixedVersion(matching, permitted)
+		c.pins = append(c.pins, pin)
+	}
+}
+
+// firstFixedVersion returns the lowest version that is not affected by any of the advisories and
+// fixes every affected range the permitted range overlaps, or an empty string when one of those
+// ranges has no known fix. Each fixed version lies above the affected range it closes, so raising
+// the candidate until no advisory affects it terminates.
+func firstFixedVersion(entries []*advisoryEntry, permitted versionRange) string {
+	target := ""
+	for {
+		var next *semanticVersion
+		nextText := ""
+		for _, entry := range entries {
+			fixed, affected := entry.affects(permitted)
+			if !affected {
+				continue
+			}
+			version, ok := parseSemanticVersion(fixed)
+			if !ok {
+				return ""
+			}
+			if next == nil || compareVersions(version, *next) > 0 {
+				next, nextText = &version, fixed
+			}
+		}
+		if next == nil {
+			return target
+		}
+		target = nextText
+		permitted = versionRange{lower: &versionBound{version: *next, inclusive: true}, upper: &versionBound{version: *next, inclusive: true}}
+	}
+}
@@ -0,0 +1,135 @@
+package bump
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testAdvisories = `[
+  {
+    "id": "TFVB-2024-001",
+    "summary": "NAT routes are dropped",
+    "affected": [
+      {
+        "package": {"ecosystem": "terraform-module", "name": "terraform-aws-modules/vpc/aws"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "5.0.0"}, {"fixed": "5.1.2"}]}],
+        "versions": ["5.0.1", "4.9.9"]
+      }
+    ]
+  },
+  {
+    "id": "TFVB-2024-002",
+    "affected": [
+      {
+        "package": {"ecosystem": "terraform-module", "name": "terraform-aws-modules/vpc/aws"},
+        "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "5.1.0"}, {"fixed": "5.2.0"}, {"introduced": "7.0.0"}]}]
+      },
+      {
+        "package": {"ecosystem": "terraform-provider", "name": "registry.terraform.io/hashicorp/aws"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "4.67.0"}, {"introduced": "5.10.0"}, {"fixed": "5.11.0"}]}]
+      }
+    ]
+  }
+]`
+
+func TestAdvisoryEntryAffects(t *testing.T) {
+	entries, err := loadAdvisories(writeTestFile(t, t.TempDir(), "advisories.json", testAdvisories))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %#v, want 3", entries)
+	}
+	modules := []*advisoryEntry{&entries[0], &entries[1]}
+
+	tests := []struct {
+		entry      *advisoryEntry
+		constraint string
+		affected   bool
+		fixed      string
+	}{
+		{&entries[0], "~> 5.0", true, "5.1.2"},
+		{&entries[0], "5.1.2", false, ""},
+		{&entries[0], "< 5.0.0", true, ""}, // the listed 4.9.9 has no fix
+		{&entries[0], "~> 4.8.0", false, ""},
+		{&entries[1], ">= 6.0, < 7.0", false, ""},
+		{&entries[1], "~> 7.1", true, ""},
+		{&entries[2], "~> 4.0", true, ""},
+		{&entries[2], "5.10.3", true, "5.11.0"},
+		{&entries[2], "> 5.0, < 5.10", false, ""},
+	}
+	for _, tt := range tests {
+		clauses, _ := parseConstraint(tt.constraint)
+		fixed, affected := tt.entry.affects(constraintRange(clauses))
+		if affected != tt.affected || fixed != tt.fixed {
+			t.Errorf("%s affects(%q) = %q, %v, want %q, %v", tt.entry.id, tt.constraint, fixed, affected, tt.fixed, tt.affected)
+		}
+	}
+
+	// The first version fixing both advisories is the higher of their fixed versions
+	clauses, _ := parseConstraint("~> 5.0")
+	if got := firstFixedVersion(modules, constraintRange(clauses)); got != "5.2.0" {
+		t.Errorf("firstFixedVersion(~> 5.0) = %q, want 5.2.0", got)
+	}
+}
+
+func TestLoadAdvisoriesRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"missing id", `{"affected": []}`, "advisory at index 0 is missing 'id' field"},
+		{"ecosystem", `{"id": "A-1", "affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}}]}`, `advisory "A-1" affected entry 0 has unsupported ecosystem "npm": must be 'terraform-module' or 'terraform-provider'`},
+		{"range type", `{"id": "A-1", "affected": [{"package": {"ecosystem": "terraform-module", "name": "a/b/c"}, "ranges": [{"type": "GIT", "events": []}]}]}`, `advisory "A-1" affected entry 0 has unsupported range type "GIT": must be 'SEMVER' or 'ECOSYSTEM'`},
+		{"event", `{"id": "A-1", "affected": [{"package": {"ecosystem": "terraform-module", "name": "a/b/c"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0", "fixed": "2.0"}]}]}]}`, `advisory "A-1" affected entry 0 has invalid event at index 0: must set one of 'introduced', 'fixed', or 'last_affected' to a version`},
+		{"no versions", `{"id": "A-1", "affected": [{"package": {"ecosystem": "terraform-module", "name": "a/b/c"}}]}`, `advisory "A-1" affected entry 0 lists no affected ranges or versions`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadAdvisories(writeTestFile(t, t.TempDir(), "advisories.json", tt.contents))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("loadAdvisories() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvisoryFixesMatchProviderSource(t *testing.T) {
+	dir := t.TempDir()
+	provider := func(source string) string {
+		return "terraform {\n  required_providers {\n    aws = {\n      source  = \"" + source + "\"\n      version = \"5.10.1\"\n    }\n  }\n}\n"
+	}
+	affected := writeTestFile(t, dir, "affected.tf", provider("hashicorp/aws"))
+	legacy := writeTestFile(t, dir, "legacy.tf", "terraform {\n  required_providers {\n    aws {\n      version = \"5.10.1\"\n    }\n  }\n}\n")
+	fork := writeTestFile(t, dir, "fork.tf", provider("example/aws"))
+	advisories := writeTestFile(t, dir, "advisories.json", testAdvisories)
+	loaded, err := LoadAdvisories(advisories)
+	if err != nil {
+		t.Fatalf("LoadAdvisories() error = %v", err)
+	}
+
+	var pins []AffectedPin
+	for _, file := range []string{affected, legacy, fork} {
+		_, filePins, err := CheckAdvisories(file, loaded, Options{})
+		if err != nil {
+			t.Fatalf("CheckAdvisories(%s) error = %v", file, err)
+		}
+		pins = append(pins, filePins...)
+	}
+	config := AdvisoryFixes(pins)
+	if len(config.Providers) != 1 || config.Providers[0].fromSource != "hashicorp/aws" {
+		t.Fatalf("providers = %#v, want one update limited to hashicorp/aws", config.Providers)
+	}
+
+	engine := &Engine{Options: Options{OutputFormat: "text"}}
+	if _, err := engine.Apply(context.Background(), config, Files{affected, legacy, fork}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	for file, want := range map[string]string{affected: "5.11.0", legacy: "5.11.0", fork: "5.10.1"} {
+		if got := readTestFile(t, file); !strings.Contains(got, `version = "`+want+`"`) {
+			t.Errorf("%s: want version %q:\n%s", file, want, got)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Align mode completes a partial rollout without naming a target: each module source or provider
+// in a scope is moved to the highest version already in use for it. Constraints are ranked by the
+// lowest version they permit, then by the highest, so "5.2.0" outranks "~> 5.0" and "~> 5.1"
+// outranks ">= 5.1, < 5.5".
+
+// Align scopes choose the files whose versions are converged together.
+const (
+	AlignRepository = "repo"      // Every selected file
+	AlignDirectory  = "directory" // The selected files of each module directory separately
+)
+
+// ValidAlignScope reports whether scope is one of the recognised align scopes.
+func ValidAlignScope(scope string) bool {
+	return scope == AlignRepository || scope == AlignDirectory
+}
+
+// PinnedVersion is the literal version of a module block or required_providers entry.
+type PinnedVersion struct {
+	File    string
+	Pos     hcl.Pos
+	Module  bool   // true for a module block, false for a required_providers entry
+	Name    string // The module source or provider name that updates match
+	Block   string // The module block label, matched by ignore patterns; the provider name otherwise
+	Version string
+}
+
+// AlignOptions selects the pins that align mode converges.
+type AlignOptions struct {
+	Module         string   // If set, only module blocks with this source are aligned
+	Provider       string   // If set, only entries for this provider are aligned
+	IgnoreModules  []string // Module names or patterns whose blocks are left out
+	IgnoreVersions []string // Versions left out, both as targets and as pins to update
+}
+
+// pinCollector collects the literal versions of a file.
+type pinCollector struct {
+	filename string
+	pins     []PinnedVersion
+}
+
+// FindPinnedVersions reads a file and returns the literal versions of its module blocks and
+// required_providers entries, without modifying it. Terragrunt sources are not included.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to read
+//   - opts: Terragrunt selects how .hcl files are read
+//
+// Returns:
+//   - []PinnedVersion: The pins, in written order
+//   - error: Any error encountered while reading or parsing the file
+func FindPinnedVersions(filename string, opts Options) ([]PinnedVersion, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	collector := &pinCollector{filename: filename}
+	if err := scanPins(filename, src, opts.Terragrunt, collector); err != nil {
+		return nil, err
+	}
+	return collector.pins, nil
+}
+
+func (c *pinCollector) visitModule(name string, pos hcl.Pos, source string, version *pinValue) {
+	c.add(PinnedVersion{Pos: pos, Module: true, Name: source, Block: name}, version)
+}
+
+func (c *pinCollector) visitProvider(name string, pos hcl.Pos, _ string, version *pinValue) {
+	c.add(PinnedVersion{Pos: pos, Name: name, Block: name}, version)
+}
+
+func (c *pinCollector) visitTerragruntSource(string, *pinValue) {}
+
+// add records a pin whose version is a literal constraint.
+func (c *pinCollector) add(pin PinnedVersion, version *pinValue) {
+	if version == nil || !version.literal {
+		return
+	}
+	if _, ok := parseConstraint(version.text); !ok {
+		return
+	}
+	pin.File, pin.Pos, pin.Version = c.filename, version.pos, version.text
+	c.pins = append(c.pins, pin)
+}
+
+// AlignTarget is the highest version in use for a module source or provider within a scope, with
+// the other versions in use that align mode replaces.
+type AlignTarget struct {
+	Module  bool   // true for a module source, false for a provider
+	Name    string // The module source or provider name
+	Version string
+	From    []string // The other versions in use, in written order
+}
+
+// AlignTargets returns the target of each module source or provider whose selected pins use more
+// than one version. Pins excluded by the options neither set a target nor are replaced.
+//
+// Parameters:
+//   - pins: The pins of one scope, from FindPinnedVersions
+//   - opts: The module, provider, and ignore filters
+//
+// Returns:
+//   - []AlignTarget: The targets, in the order each source or provider first appears
+func AlignTargets(pins []PinnedVersion, opts AlignOptions) []AlignTarget {
+	var targets []AlignTarget
+	for _, group := range groupPins(pins, opts) {
+		target := AlignTarget{Module: group[0].Module, Name: group[0].Name, Version: group[0].Version}
+		for _, pin := range group[1:] {
+			if compareConstraints(pin.Version, target.Version) > 0 {
+				target.Version = pin.Version
+			}
+		}
+		for _, pin := range group {
+			if pin.Version != target.Version && !containsVersion(target.From, pin.Version) {
+				target.From = append(target.From, pin.Version)
+			}
+		}
+		if len(target.From) > 0 {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// AlignUpdates returns the updates that move pins to their targets: one update per target, limited
+// to the versions it replaces, so that applying them with ForceAdd unset never adds a version.
+func AlignUpdates(targets []AlignTarget, opts AlignOptions) Config {
+	var config Config
+	for _, target := range targets {
+		if target.Module {
+			config.Modules = append(config.Modules, ModuleUpdate{
+				Source:         target.Name,
+				Version:        target.Version,
+				From:           target.From,
+				IgnoreVersions: opts.IgnoreVersions,
+				IgnoreModules:  opts.IgnoreModules,
+			})
+			continue
+		}
+		config.Providers = append(config.Providers, ProviderUpdate{Name: target.Name, Version: target.Version, from: target.From})
+	}
+	return config
+}
+
+// groupPins groups the selected pins by module source or provider name, in the order each first
+// appears.
+func groupPins(pins []PinnedVersion, opts AlignOptions) [][]PinnedVersion {
+	var groups [][]PinnedVersion
+	for _, pin := range pins {
+		if !opts.selects(pin) {
+			continue
+		}
+		index := slices.IndexFunc(groups, func(group []PinnedVersion) bool {
+			return group[0].Module == pin.Module && group[0].Name == pin.Name
+		})
+		if index < 0 {
+			groups = append(groups, nil)
+			index = len(groups) - 1
+		}
+		groups[index] = append(groups[index], pin)
+	}
+	return groups
+}
+
+// selects reports whether a pin is aligned under the options.
+func (opts *AlignOptions) selects(pin PinnedVersion) bool {
+	if containsVersion(opts.IgnoreVersions, pin.Version) {
+		return false
+	}
+	if pin.Module && shouldIgnoreModule(pin.Block, opts.IgnoreModules) {
+		return false
+	}
+	if opts.Module == "" && opts.Provider == "" {
+		return true
+	}
+	if pin.Module {
+		return pin.Name == opts.Module
+	}
+	return pin.Name == opts.Provider
+}
+
+// compareConstraints ranks two constraints by the lowest version each permits, then by the highest;
+// an open bound ranks below every version as a floor and above every version as a ceiling. It
+// returns 1 when a ranks higher, -1 when b does, and 0 when they rank the same.
+func compareConstraints(a, b string) int {
+	aClauses, _ := parseConstraint(a)
+	bClauses, _ := parseConstraint(b)
+	aRange, bRange := constraintRange(aClauses), constraintRange(bClauses)
+	if comparison := compareBounds(aRange.lower, bRange.lower, -1); comparison != 0 {
+		return comparison
+	}
+	return compareBounds(aRange.upper, bRange.upper, 1)
+}
+
+// compareBounds compares two bounds of the same side. A nil bound compares as open, the sign of
+// which open is: -1 for a floor and 1 for a ceiling. Of two bounds at the same version, the
+// inclusive floor and the exclusive ceiling rank lower.
+func compareBounds(a, b *versionBound, open int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return open
+	case b == nil:
+		return -open
+	}
+	if comparison := compareVersions(a.version, b.version); comparison != 0 {
+		return comparison
+	}
+	switch {
+	case a.inclusive == b.inclusive:
+		return 0
+	case a.inclusive:
+		return open
+	default:
+		return -open
+	}
+}
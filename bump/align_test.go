@@ -0,0 +1,96 @@
+package bump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareConstraints(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "5.2.0", b: "~> 5.0", want: 1},
+		{a: "4.0.0", b: "4.1.0", want: -1},
+		{a: "~> 5.1", b: ">= 5.1, < 5.5", want: 1},
+		{a: ">= 5.0", b: "~> 5.0", want: 1},
+		{a: "> 5.0", b: ">= 5.0", want: 1},
+		{a: "5.0", b: "5.0.0", want: 0},
+	}
+	for _, tt := range tests {
+		if got := compareConstraints(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareConstraints(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAlignTargets(t *testing.T) {
+	pins := []PinnedVersion{
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Block: "vpc", Version: "4.0.0"},
+		{Name: "aws", Block: "aws", Version: "~> 5.0"},
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Block: "network", Version: "5.1.0"},
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Block: "legacy-vpc", Version: "6.0.0"},
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Block: "edge", Version: "4.1.0"},
+		{Name: "aws", Block: "aws", Version: "~> 5.0"},
+		{Module: true, Name: "terraform-aws-modules/eks/aws", Block: "eks", Version: "20.0.0"},
+		{Name: "random", Block: "random", Version: "~> 3.5"},
+		{Name: "random", Block: "random", Version: "3.6.0"},
+	}
+	opts := AlignOptions{IgnoreModules: []string{"legacy-*"}, IgnoreVersions: []string{"4.1.0"}}
+	want := []AlignTarget{
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Version: "5.1.0", From: []string{"4.0.0"}},
+		{Name: "random", Version: "3.6.0", From: []string{"~> 3.5"}},
+	}
+	targets := AlignTargets(pins, opts)
+	if !reflect.DeepEqual(targets, want) {
+		t.Fatalf("AlignTargets() = %#v, want %#v", targets, want)
+	}
+
+	config := AlignUpdates(targets, opts)
+	wantConfig := Config{
+		Modules: []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.1.0", From: FromVersions{"4.0.0"},
+			IgnoreVersions: FromVersions{"4.1.0"}, IgnoreModules: []string{"legacy-*"}}},
+		Providers: []ProviderUpdate{{Name: "random", Version: "3.6.0", from: []string{"~> 3.5"}}},
+	}
+	if !reflect.DeepEqual(config, wantConfig) {
+		t.Errorf("AlignUpdates() = %#v, want %#v", config, wantConfig)
+	}
+
+	if targets := AlignTargets(pins, AlignOptions{Provider: "random"}); len(targets) != 1 || targets[0].Name != "random" {
+		t.Errorf("AlignTargets() with a provider = %#v, want only random", targets)
+	}
+}
+
+func TestFindPinnedVersions(t *testing.T) {
+	filename := writeTestFile(t, t.TempDir(), "main.tf", `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+
+module "local" {
+  source = "./modules/local"
+}
+
+module "computed" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = var.eks_version
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+`)
+	pins, err := FindPinnedVersions(filename, Options{})
+	if err != nil {
+		t.Fatalf("FindPinnedVersions() error = %v", err)
+	}
+	if len(pins) != 2 || pins[0].Block != "vpc" || pins[0].Version != "5.0.0" || pins[0].Pos.Line != 3 ||
+		pins[1].Module || pins[1].Name != "aws" || pins[1].Version != "~> 5.0" {
+		t.Errorf("FindPinnedVersions() = %#v", pins)
+	}
+}
@@ -0,0 +1,520 @@
+package bump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+	"go.yaml.in/yaml/v3"
+)
+
+// FromVersions is a custom type that can unmarshal both string and []string from YAML
+type FromVersions []string
+
+// UnmarshalYAML implements custom unmarshaling to handle both string and array formats
+func (f *FromVersions) UnmarshalYAML(value *yaml.Node) error {
+	// Check if it's a sequence (array)
+	if value.Kind == yaml.SequenceNode {
+		for _, item := range value.Content {
+			if item.ShortTag() != "!!str" {
+				return fmt.Errorf("version filter array contains non-string values")
+			}
+		}
+
+		var slice []string
+		if err := value.Decode(&slice); err != nil {
+			return fmt.Errorf("version filter array contains non-string values: %w", err)
+		}
+		*f = FromVersions(slice)
+		return nil
+	}
+
+	// Check if it's a scalar (single value)
+	if value.Kind == yaml.ScalarNode {
+		// Only accept string scalars, reject numbers and booleans
+		if value.Tag != "!!str" {
+			return fmt.Errorf("version filter must be a string or array of strings, got %s", value.Tag)
+		}
+
+		str := value.Value
+
+		if str == "" {
+			*f = FromVersions{}
+		} else {
+			*f = FromVersions{str}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("version filter must be either a string or an array of strings, got node kind %v", value.Kind)
+}
+
+// ModuleUpdate represents a single module source and its target version.
+// It is used both for single module updates via CLI flags and for batch
+// updates from YAML configuration files.
+type ModuleUpdate struct {
+	Source           string       `yaml:"source"`            // Module source (e.g., "terraform-aws-modules/vpc/aws")
+	Version          string       `yaml:"version"`           // Target version (e.g., "5.0.0")
+	From             FromVersions `yaml:"from"`              // Optional: only update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
+	IgnoreVersions   FromVersions `yaml:"ignore_versions"`   // Optional: skip update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
+	IgnoreModules    []string     `yaml:"ignore_modules"`    // Optional: list of module names or patterns to ignore (e.g., ["vpc", "legacy-*"])
+	PreserveOperator bool         `yaml:"preserve_operator"` // Optional: keep the existing constraint operator and precision (e.g., "~> 4.2" becomes "~> 5.1")
+	AllowDowngrade   bool         `yaml:"allow_downgrade"`   // Optional: apply the update even when the current version is newer than the target
+	Prerelease       string       `yaml:"prerelease"`        // Optional: pre-release policy ("allow", "if-current" or "exclude"), overriding the top-level policy
+}
+
+// ProviderUpdate represents a provider version update in required_providers blocks
+type ProviderUpdate struct {
+	Name             string `yaml:"name"`              // Provider name (e.g., "aws", "azurerm")
+	Version          string `yaml:"version"`           // Target version (e.g., "~> 5.0")
+	PreserveOperator bool   `yaml:"preserve_operator"` // Optional: keep the existing constraint operator and precision
+	AllowDowngrade   bool   `yaml:"allow_downgrade"`   // Optional: apply the update even when the current version is newer than the target
+	Prerelease       string `yaml:"prerelease"`        // Optional: pre-release policy, overriding the top-level policy
+	Source           string `yaml:"source"`            // Optional: provider source address used when add_missing is set (e.g., "hashicorp/aws")
+	AddMissing       bool   `yaml:"add_missing"`       // Optional: add the provider to required_providers blocks that do not declare it
+
+	from       []string // Only entries whose current version is listed are updated; set by AdvisoryFixes
+	fromSource string   // Only entries with this source address are updated; set by AdvisoryFixes
+}
+
+// ModuleMigration moves matching module blocks to a new source, setting or removing the version
+// argument to suit the new source
+type ModuleMigration struct {
+	FromSource    string   `yaml:"from_source"`    // Module source to replace (e.g., "git::https://example.com/vpc.git")
+	ToSource      string   `yaml:"to_source"`      // New module source (e.g., "app.terraform.io/example/vpc/aws")
+	Version       string   `yaml:"version"`        // Version for the new source; required for registry sources and rejected otherwise
+	IgnoreModules []string `yaml:"ignore_modules"` // Optional: list of module names or patterns to leave on the old source
+}
+
+// ProviderMigration moves required_providers entries from one provider source address to another
+// and sets a new version constraint
+type ProviderMigration struct {
+	FromSource string `yaml:"from_source"` // Provider source to replace (e.g., "example-community/widget")
+	ToSource   string `yaml:"to_source"`   // New provider source (e.g., "example/widget")
+	Version    string `yaml:"version"`     // Version constraint for the new source (e.g., "~> 2.0")
+}
+
+// Policy represents a YAML policy file that declares the versions each module source and
+// provider may be pinned to. Policies are checked with -policy rather than applied as updates.
+//
+// Example YAML:
+//
+//	modules:
+//	  - source: "terraform-aws-modules/vpc/aws"
+//	    min_version: "5.1.0"
+//	    max_version: "6.0.0" # Optional: "~> 5.1" is accepted, "~> 6.0" is not
+//
+//	providers:
+//	  - name: "aws"
+//	    min_version: "5.30"
+type Policy struct {
+	Modules   []ModulePolicy   `yaml:"modules"`   // Optional: List of module source policies
+	Providers []ProviderPolicy `yaml:"providers"` // Optional: List of provider policies
+}
+
+// ModulePolicy sets the range of versions that blocks of a module source may permit
+type ModulePolicy struct {
+	Source     string `yaml:"source"`      // Module source (e.g., "terraform-aws-modules/vpc/aws")
+	MinVersion string `yaml:"min_version"` // Optional: lowest version a constraint may permit (e.g., "5.1.0")
+	MaxVersion string `yaml:"max_version"` // Optional: highest version a constraint may permit (e.g., "6.0.0")
+}
+
+// ProviderPolicy sets the range of versions that required_providers entries may permit
+type ProviderPolicy struct {
+	Name       string `yaml:"name"`        // Provider name (e.g., "aws")
+	MinVersion string `yaml:"min_version"` // Optional: lowest version a constraint may permit (e.g., "5.30")
+	MaxVersion string `yaml:"max_version"` // Optional: highest version a constraint may permit
+}
+
+// Config represents the structure of a YAML configuration file for batch updates.
+// The YAML file can contain:
+// - A "modules" key with a list of module updates
+// - A "terraform_version" key to update Terraform required_version
+// - A "providers" key with a list of provider updates
+// - A "migrations" key with a list of module source migrations
+// - A "provider_migrations" key with a list of provider source migrations
+// - A "prerelease" key with the default pre-release policy for every update
+// - A "groups" key naming sets of module and provider entries to apply on their own
+//
+// Example YAML:
+//
+//	terraform_version: ">= 1.5"
+//	prerelease: "if-current"     # Optional: "allow" (default), "if-current" or "exclude"
+//
+//	providers:
+//	  - name: "aws"
+//	    version: "~> 5.0"
+//	  - name: "azurerm"
+//	    version: "~> 3.5"
+//	  - name: "random"
+//	    version: "~> 3.6"
+//	    source: "hashicorp/random" # Required with add_missing
+//	    add_missing: true          # Optional: declare the provider where it is missing
+//
+//	provider_migrations:
+//	  - from_source: "example-community/widget"
+//	    to_source: "example/widget"
+//	    version: "~> 2.0"
+//
+//	migrations:
+//	  - from_source: "git::https://github.com/example/terraform-aws-vpc.git"
+//	    to_source: "app.terraform.io/example/vpc/aws"
+//	    version: "5.0.0"       # Required for registry sources; the ref query parameter is dropped
+//	  - from_source: "community/eks/aws"
+//	    to_source: "git::https://github.com/example/terraform-aws-eks.git?ref=v20.1.0"
+//	                           # No version: Terraform accepts version only for registry sources
+//
+//	modules:
+//	  - source: "terraform-aws-modules/vpc/aws"
+//	    version: "5.0.0"
+//	    from: "4.0.0"          # Optional: only update if current version is 4.0.0
+//	    ignore_versions:       # Optional: versions to skip
+//	      - "3.0.0"
+//	      - "~> 3.0"
+//	    ignore_modules:        # Optional: module names or patterns to ignore
+//	      - "legacy-vpc"
+//	      - "test-*"
+//	    preserve_operator: true # Optional: "~> 4.2" becomes "~> 5.0" rather than "5.0.0"
+//	  - source: "terraform-aws-modules/s3-bucket/aws"
+//	    version: "4.0.0"
+//	    allow_downgrade: true  # Optional: roll back blocks that are newer than 4.0.0
+//
+//	groups:
+//	  - name: "aws-core"       # Apply with -group aws-core
+//	    modules:
+//	      - "terraform-aws-modules/vpc/aws"
+//	    providers:
+//	      - "aws"
+type Config struct {
+	TerraformVersion   string              `yaml:"terraform_version"`   // Optional: Terraform required_version to set
+	Prerelease         string              `yaml:"prerelease"`          // Optional: default pre-release policy for every update
+	Providers          []ProviderUpdate    `yaml:"providers"`           // Optional: List of provider updates
+	Modules            []ModuleUpdate      `yaml:"modules"`             // Optional: List of module updates
+	Migrations         []ModuleMigration   `yaml:"migrations"`          // Optional: List of module source migrations, applied before module updates
+	ProviderMigrations []ProviderMigration `yaml:"provider_migrations"` // Optional: List of provider source migrations, applied before provider updates
+	Groups             []UpdateGroup       `yaml:"groups"`              // Optional: Named sets of module and provider entries, applied with Config.Group
+
+	Custom []CustomUpdate `yaml:"-"` // Optional: updates applied by registered updaters, after module updates; set in Go only
+}
+
+// LoadConfig reads and parses a YAML configuration file containing module, terraform version,
+// and provider updates. It validates the file against the rules of the config schema, returning
+// every problem as ConfigErrors, then that the updates are consistent.
+//
+// Parameters:
+//   - filename: Path to the YAML configuration file
+//
+// Returns:
+//   - *Config: Configuration structure with all updates
+//   - error: Any error encountered during reading, parsing, or validation
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if errs := ValidateConfigDocument(&document); errs != nil {
+		return nil, errs
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Strict mode: error on unknown fields
+	if err := decoder.Decode(&config); err != nil {
+		// EOF indicates an empty file or a file with only comments, which is valid
+		if err == io.EOF {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	if err := config.sanitize(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// sanitize trims the values of a config and validates that all required fields are present.
+func (config *Config) sanitize() error {
+	// Trim and validate terraform_version
+	config.TerraformVersion = strings.TrimSpace(config.TerraformVersion)
+
+	config.Prerelease = strings.TrimSpace(config.Prerelease)
+	if !ValidPrereleasePolicy(config.Prerelease) {
+		return fmt.Errorf("invalid prerelease policy %q: must be 'allow', 'if-current', or 'exclude'", config.Prerelease)
+	}
+
+	if err := sanitizeProviderUpdates(config.Providers); err != nil {
+		return err
+	}
+	if err := sanitizeModuleUpdates(config.Modules); err != nil {
+		return err
+	}
+	if err := sanitizeModuleMigrations(config.Migrations); err != nil {
+		return err
+	}
+	if err := sanitizeProviderMigrations(config.ProviderMigrations); err != nil {
+		return err
+	}
+	if err := sanitizeCustomUpdates(config.Custom); err != nil {
+		return err
+	}
+	if err := config.sanitizeGroups(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// clone returns a copy of a config whose update lists can be sanitized without changing the
+// original's.
+func (config Config) clone() Config {
+	config.Providers = slices.Clone(config.Providers)
+	config.Modules = slices.Clone(config.Modules)
+	config.Migrations = slices.Clone(config.Migrations)
+	config.ProviderMigrations = slices.Clone(config.ProviderMigrations)
+	config.Custom = slices.Clone(config.Custom)
+	config.Groups = slices.Clone(config.Groups)
+	return config
+}
+
+func sanitizeProviderUpdates(providers []ProviderUpdate) error {
+	for i := range providers {
+		providers[i].Name = strings.TrimSpace(providers[i].Name)
+		providers[i].Version = strings.TrimSpace(providers[i].Version)
+
+		if providers[i].Name == "" {
+			return fmt.Errorf("provider at index %d is missing 'name' field", i)
+		}
+		if providers[i].Version == "" {
+			return fmt.Errorf("provider at index %d is missing 'version' field", i)
+		}
+		providers[i].Prerelease = strings.TrimSpace(providers[i].Prerelease)
+		if !ValidPrereleasePolicy(providers[i].Prerelease) {
+			return fmt.Errorf("provider at index %d has invalid prerelease policy %q", i, providers[i].Prerelease)
+		}
+		providers[i].Source = strings.TrimSpace(providers[i].Source)
+		if providers[i].AddMissing && providers[i].Source == "" {
+			return fmt.Errorf("provider at index %d sets 'add_missing' without a 'source' field", i)
+		}
+		if providers[i].Source != "" {
+			if err := ValidateProviderSource(providers[i].Source); err != nil {
+				return fmt.Errorf("provider at index %d has invalid source %q: %w", i, providers[i].Source, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sanitizeModuleUpdates(modules []ModuleUpdate) error {
+	for i := range modules {
+		modules[i].Source = strings.TrimSpace(modules[i].Source)
+		modules[i].Version = strings.TrimSpace(modules[i].Version)
+		modules[i].From = FromVersions(trimNonEmptyStrings(modules[i].From))
+		modules[i].IgnoreVersions = FromVersions(trimNonEmptyStrings(modules[i].IgnoreVersions))
+		modules[i].IgnoreModules = trimNonEmptyStrings(modules[i].IgnoreModules)
+
+		if modules[i].Source == "" {
+			return fmt.Errorf("module at index %d is missing 'source' field", i)
+		}
+		if modules[i].Version == "" {
+			return fmt.Errorf("module at index %d is missing 'version' field", i)
+		}
+		modules[i].Prerelease = strings.TrimSpace(modules[i].Prerelease)
+		if !ValidPrereleasePolicy(modules[i].Prerelease) {
+			return fmt.Errorf("module at index %d has invalid prerelease policy %q", i, modules[i].Prerelease)
+		}
+	}
+
+	return nil
+}
+
+func sanitizeModuleMigrations(migrations []ModuleMigration) error {
+	for i := range migrations {
+		migrations[i].FromSource = strings.TrimSpace(migrations[i].FromSource)
+		migrations[i].ToSource = strings.TrimSpace(migrations[i].ToSource)
+		migrations[i].Version = strings.TrimSpace(migrations[i].Version)
+		migrations[i].IgnoreModules = trimNonEmptyStrings(migrations[i].IgnoreModules)
+
+		if migrations[i].FromSource == "" {
+			return fmt.Errorf("migration at index %d is missing 'from_source' field", i)
+		}
+		if migrations[i].ToSource == "" {
+			return fmt.Errorf("migration at index %d is missing 'to_source' field", i)
+		}
+		if sameModuleSource(migrations[i].FromSource, migrations[i].ToSource, false) {
+			return fmt.Errorf("migration at index %d has the same 'from_source' and 'to_source'", i)
+		}
+		registry := isRegistryModule(migrations[i].ToSource)
+		if registry && migrations[i].Version == "" {
+			return fmt.Errorf("migration at index %d moves to registry source %q without a 'version' field", i, migrations[i].ToSource)
+		}
+		if !registry && migrations[i].Version != "" {
+			return fmt.Errorf("migration at index %d sets 'version' but %q is not a registry source", i, migrations[i].ToSource)
+		}
+	}
+
+	return nil
+}
+
+func sanitizeProviderMigrations(migrations []ProviderMigration) error {
+	for i := range migrations {
+		migrations[i].FromSource = strings.TrimSpace(migrations[i].FromSource)
+		migrations[i].ToSource = strings.TrimSpace(migrations[i].ToSource)
+		migrations[i].Version = strings.TrimSpace(migrations[i].Version)
+
+		for _, field := range []struct{ name, value string }{
+			{"from_source", migrations[i].FromSource},
+			{"to_source", migrations[i].ToSource},
+			{"version", migrations[i].Version},
+		} {
+			if field.value == "" {
+				return fmt.Errorf("provider migration at index %d is missing '%s' field", i, field.name)
+			}
+		}
+		for _, source := range []string{migrations[i].FromSource, migrations[i].ToSource} {
+			if err := ValidateProviderSource(source); err != nil {
+				return fmt.Errorf("provider migration at index %d has invalid source %q: %w", i, source, err)
+			}
+		}
+		if sameProviderSource(migrations[i].FromSource, migrations[i].ToSource) {
+			return fmt.Errorf("provider migration at index %d has the same 'from_source' and 'to_source'", i)
+		}
+	}
+
+	return nil
+}
+
+func sanitizeCustomUpdates(updates []CustomUpdate) error {
+	for i := range updates {
+		updates[i].Updater = strings.TrimSpace(updates[i].Updater)
+		updates[i].Name = strings.TrimSpace(updates[i].Name)
+		updates[i].Version = strings.TrimSpace(updates[i].Version)
+
+		if updates[i].Updater == "" {
+			return fmt.Errorf("custom update at index %d is missing 'updater' field", i)
+		}
+		if updates[i].Version == "" {
+			return fmt.Errorf("custom update at index %d is missing 'version' field", i)
+		}
+	}
+
+	return nil
+}
+
+// LoadPolicy reads and parses a YAML policy file. It validates that every entry names a module
+// source or provider and at least one version bound.
+//
+// Parameters:
+//   - filename: Path to the YAML policy file
+//
+// Returns:
+//   - *Policy: Policy structure with all module and provider entries
+//   - error: Any error encountered during reading, parsing, or validation
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Strict mode: error on unknown fields
+	if err := decoder.Decode(&policy); err != nil {
+		if err == io.EOF {
+			return &Policy{}, nil
+		}
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	for i := range policy.Modules {
+		entry := &policy.Modules[i]
+		entry.Source = strings.TrimSpace(entry.Source)
+		if entry.Source == "" {
+			return nil, fmt.Errorf("module policy at index %d is missing 'source' field", i)
+		}
+		if err := sanitizeVersionBounds(&entry.MinVersion, &entry.MaxVersion); err != nil {
+			return nil, fmt.Errorf("module policy at index %d %w", i, err)
+		}
+	}
+	for i := range policy.Providers {
+		entry := &policy.Providers[i]
+		entry.Name = strings.TrimSpace(entry.Name)
+		if entry.Name == "" {
+			return nil, fmt.Errorf("provider policy at index %d is missing 'name' field", i)
+		}
+		if err := sanitizeVersionBounds(&entry.MinVersion, &entry.MaxVersion); err != nil {
+			return nil, fmt.Errorf("provider policy at index %d %w", i, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+// sanitizeVersionBounds trims a policy entry's bounds and checks that they are versions in order.
+// Its errors continue a sentence that names the entry.
+func sanitizeVersionBounds(minVersion, maxVersion *string) error {
+	*minVersion = strings.TrimSpace(*minVersion)
+	*maxVersion = strings.TrimSpace(*maxVersion)
+	if *minVersion == "" && *maxVersion == "" {
+		return fmt.Errorf("sets neither 'min_version' nor 'max_version'")
+	}
+
+	bounds := make([]semanticVersion, 0, 2)
+	for _, field := range []struct{ name, value string }{
+		{"min_version", *minVersion},
+		{"max_version", *maxVersion},
+	} {
+		if field.value == "" {
+			continue
+		}
+		version, ok := parseSemanticVersion(field.value)
+		if !ok {
+			return fmt.Errorf("has invalid '%s' %q: must be a version such as \"5.1.0\"", field.name, field.value)
+		}
+		bounds = append(bounds, version)
+	}
+	if len(bounds) == 2 && compareVersions(bounds[0], bounds[1]) > 0 {
+		return fmt.Errorf("has 'min_version' %q above 'max_version' %q", *minVersion, *maxVersion)
+	}
+	return nil
+}
+
+func trimNonEmptyStrings(values []string) []string {
+	filtered := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			filtered = append(filtered, trimmed)
+		}
+	}
+	return filtered
+}
+
+// sameProviderSource reports whether two provider source addresses name the same provider, so
+// "hashicorp/aws" matches "registry.terraform.io/hashicorp/aws". Addresses that do not parse must
+// match exactly.
+func sameProviderSource(a, b string) bool {
+	first, err := tfaddr.ParseProviderSource(a)
+	if err != nil {
+		return a == b
+	}
+	second, err := tfaddr.ParseProviderSource(b)
+	return err == nil && first.Equals(second)
+}
+
+// ValidateProviderSource checks that a provider source address, such as "hashicorp/aws" or
+// "registry.example.com/acme/widget", is one Terraform accepts in required_providers.
+func ValidateProviderSource(source string) error {
+	_, err := tfaddr.ParseProviderSource(source)
+	return err
+}
@@ -0,0 +1,273 @@
+package bump
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type fromDocument struct {
+	From FromVersions `yaml:"from"`
+}
+
+func TestFromVersionsUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name, yaml, errString string
+		want                  FromVersions
+	}{
+		{name: "scalar", yaml: "from: 4.0.0\n", want: FromVersions{"4.0.0"}},
+		{name: "array", yaml: "from: [4.0.0, \"~> 3.0\"]\n", want: FromVersions{"4.0.0", "~> 3.0"}},
+		{name: "empty scalar", yaml: "from: \"\"\n", want: FromVersions{}},
+		{name: "number", yaml: "from: 4\n", errString: "must be a string or array of strings"},
+		{name: "array with number", yaml: "from: [4.0.0, 4]\n", errString: "array contains non-string values"},
+		{name: "mapping", yaml: "from: {old: 4.0.0}\n", errString: "must be either a string or an array of strings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got fromDocument
+			err := yaml.NewDecoder(strings.NewReader(tt.yaml)).Decode(&got)
+			if tt.errString != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errString) {
+					t.Fatalf("error = %v, want component %q", err, tt.errString)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if !slices.Equal(got.From, tt.want) {
+				t.Errorf("from = %#v, want %#v", got.From, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSanitisesAllOperations(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := `terraform_version: "  >= 1.6  "
+providers:
+  - name: " aws "
+    version: " ~> 5.0 "
+modules:
+  - source: " terraform-aws-modules/vpc/aws "
+    version: " 5.0.0 "
+    from: [" 4.0.0 ", "", "   "]
+    ignore_versions: [" 3.0.0 ", " ~> 3.0 ", "", "   "]
+    ignore_modules: [" legacy-* ", " ", " *-test "]
+`
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	want := &Config{
+		TerraformVersion: ">= 1.6",
+		Providers:        []ProviderUpdate{{Name: "aws", Version: "~> 5.0"}},
+		Modules: []ModuleUpdate{{
+			Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0",
+			From: FromVersions{"4.0.0"}, IgnoreVersions: FromVersions{"3.0.0", "~> 3.0"},
+			IgnoreModules: []string{"legacy-*", "*-test"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("config = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadConfigRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+		exact            bool
+	}{
+		{name: "unknown top-level field", data: "unknown: true\nmodules: []\n", want: "line 1, column 1: config has unknown field 'unknown'", exact: true},
+		{name: "malformed YAML", data: "modules:\n  - source: \"unterminated\n", want: "failed to parse YAML"},
+		{name: "module missing source", data: "modules:\n  - version: 5.0.0\n", want: "line 2, column 5: modules[0] is missing 'source' field", exact: true},
+		{name: "module missing version", data: "modules:\n  - source: example/module\n", want: "line 2, column 5: modules[0] is missing 'version' field", exact: true},
+		{name: "later module missing source", data: "modules:\n  - source: example/module\n    version: 1.0.0\n  - version: 5.0.0\n", want: "line 4, column 5: modules[1] is missing 'source' field", exact: true},
+		{name: "provider missing name", data: "providers:\n  - version: 5.0.0\n", want: "line 2, column 5: providers[0] is missing 'name' field", exact: true},
+		{name: "provider missing version", data: "providers:\n  - name: aws\n", want: "line 2, column 5: providers[0] is missing 'version' field", exact: true},
+		{name: "later provider missing name", data: "providers:\n  - name: aws\n    version: 5.0.0\n  - version: 6.0.0\n", want: "line 4, column 5: providers[1] is missing 'name' field", exact: true},
+		{name: "invalid top-level prerelease", data: "prerelease: never\nmodules: []\n", want: `line 1, column 13: prerelease "never" must be 'allow', 'if-current', or 'exclude'`, exact: true},
+		{name: "invalid module prerelease", data: "modules:\n  - source: example/module\n    version: 5.0.0\n    prerelease: sometimes\n", want: `line 4, column 17: modules[0].prerelease "sometimes" must be 'allow', 'if-current', or 'exclude'`, exact: true},
+		{name: "invalid provider prerelease", data: "providers:\n  - name: aws\n    version: 5.0.0\n    prerelease: yes-please\n", want: `line 4, column 17: providers[0].prerelease "yes-please" must be 'allow', 'if-current', or 'exclude'`, exact: true},
+		{name: "provider add_missing without source", data: "providers:\n  - name: aws\n    version: 5.0.0\n    add_missing: true\n", want: "line 2, column 5: providers[0] sets 'add_missing' without a 'source' field", exact: true},
+		{name: "provider invalid source", data: "providers:\n  - name: aws\n    version: 5.0.0\n    source: a/b/c/d\n", want: `provider at index 0 has invalid source "a/b/c/d"`},
+		{name: "migration missing from_source", data: "migrations:\n  - to_source: example/vpc/aws\n    version: 5.0.0\n", want: "line 2, column 5: migrations[0] is missing 'from_source' field", exact: true},
+		{name: "migration missing to_source", data: "migrations:\n  - from_source: example/vpc/aws\n", want: "line 2, column 5: migrations[0] is missing 'to_source' field", exact: true},
+		{name: "migration to same source", data: "migrations:\n  - from_source: example/vpc/aws\n    to_source: registry.terraform.io/example/vpc/aws\n    version: 5.0.0\n", want: "migration at index 0 has the same 'from_source' and 'to_source'", exact: true},
+		{name: "migration to registry without version", data: "migrations:\n  - from_source: git::https://example.com/vpc.git\n    to_source: example/vpc/aws\n", want: `migration at index 0 moves to registry source "example/vpc/aws" without a 'version' field`, exact: true},
+		{name: "migration to git with version", data: "migrations:\n  - from_source: example/vpc/aws\n    to_source: git::https://example.com/vpc.git\n    version: 5.0.0\n", want: `migration at index 0 sets 'version' but "git::https://example.com/vpc.git" is not a registry source`, exact: true},
+		{name: "provider migration missing version", data: "provider_migrations:\n  - from_source: example-community/widget\n    to_source: example/widget\n", want: "line 2, column 5: provider_migrations[0] is missing 'version' field", exact: true},
+		{name: "provider migration invalid source", data: "provider_migrations:\n  - from_source: a/b/c/d\n    to_source: example/widget\n    version: 2.0.0\n", want: `provider migration at index 0 has invalid source "a/b/c/d"`},
+		{name: "provider migration to same source", data: "provider_migrations:\n  - from_source: hashicorp/aws\n    to_source: registry.terraform.io/hashicorp/aws\n    version: 6.0.0\n", want: "provider migration at index 0 has the same 'from_source' and 'to_source'", exact: true},
+		{name: "group missing name", data: "modules:\n  - source: example/module\n    version: 5.0.0\ngroups:\n  - modules: [example/module]\n", want: "line 5, column 5: groups[0] is missing 'name' field", exact: true},
+		{name: "group repeated name", data: "modules:\n  - source: example/module\n    version: 5.0.0\ngroups:\n  - name: core\n    modules: [example/module]\n  - name: core\n    modules: [example/module]\n", want: `group at index 1 repeats the name "core"`, exact: true},
+		{name: "empty group", data: "groups:\n  - name: core\n", want: "line 2, column 5: groups[0] lists no modules or providers", exact: true},
+		{name: "group unknown module", data: "modules:\n  - source: example/module\n    version: 5.0.0\ngroups:\n  - name: core\n    modules: [example/other]\n", want: `group "core" lists module "example/other", which is not in the modules list`, exact: true},
+		{name: "group unknown provider", data: "groups:\n  - name: core\n    providers: [aws]\n", want: `group "core" lists provider "aws", which is not in the providers list`, exact: true},
+		{name: "provider in two groups", data: "providers:\n  - name: aws\n    version: 6.0.0\ngroups:\n  - name: core\n    providers: [aws]\n  - name: extra\n    providers: [aws]\n", want: `group "extra" lists provider "aws", which is already in group "core"`, exact: true},
+		{name: "ignore versions non-string", data: "modules:\n  - source: example/module\n    version: 5.0.0\n    ignore_versions: [4]\n", want: "line 4, column 23: modules[0].ignore_versions[0] must be a string", exact: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(configFile, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(configFile)
+			if err == nil || (tt.exact && err.Error() != tt.want) || (!tt.exact && !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("error = %v, want component %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReadError(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yml"))
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error = %v, want os.ErrNotExist", err)
+	}
+	if !strings.Contains(err.Error(), "failed to read config file:") {
+		t.Errorf("error = %v, want read wrapper", err)
+	}
+}
+
+func TestLoadConfigEmptyDocument(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "empty.yml")
+	if err := os.WriteFile(configFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, &Config{}) {
+		t.Errorf("config = %#v, want empty config", got)
+	}
+}
+
+func TestLoadConfigPreserveOperator(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := "providers:\n  - name: aws\n    version: 5.30.0\n    preserve_operator: true\nmodules:\n  - source: terraform-aws-modules/vpc/aws\n    version: 5.1.0\n    preserve_operator: true\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if !got.Providers[0].PreserveOperator || !got.Modules[0].PreserveOperator {
+		t.Errorf("config = %#v, want preserve_operator on both entries", got)
+	}
+}
+
+func TestLoadConfigAllowDowngrade(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := "providers:\n  - name: aws\n    version: 5.0.0\n    allow_downgrade: true\nmodules:\n  - source: terraform-aws-modules/vpc/aws\n    version: 5.0.0\n    allow_downgrade: true\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if !got.Providers[0].AllowDowngrade || !got.Modules[0].AllowDowngrade {
+		t.Errorf("config = %#v, want allow_downgrade on both entries", got)
+	}
+}
+
+func TestLoadConfigPrereleasePolicy(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := "prerelease: exclude\nproviders:\n  - name: aws\n    version: 6.0.0-beta1\n    prerelease: allow\nmodules:\n  - source: terraform-aws-modules/vpc/aws\n    version: 6.0.0-rc.1\n    prerelease: \" if-current \"\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if got.Prerelease != prereleaseExclude || got.Providers[0].Prerelease != prereleaseAllow || got.Modules[0].Prerelease != prereleaseIfCurrent {
+		t.Errorf("config = %#v, want the top-level and per-entry policies", got)
+	}
+}
+
+func TestLoadConfigProviderAddMissing(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := "providers:\n  - name: random\n    version: \"~> 3.6\"\n    source: \" hashicorp/random \"\n    add_missing: true\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	want := []ProviderUpdate{{Name: "random", Version: "~> 3.6", Source: "hashicorp/random", AddMissing: true}}
+	if !reflect.DeepEqual(got.Providers, want) {
+		t.Errorf("providers = %#v, want %#v", got.Providers, want)
+	}
+}
+
+func TestLoadConfigModuleMigrations(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := "migrations:\n  - from_source: \" git::https://example.com/vpc.git \"\n    to_source: app.terraform.io/example/vpc/aws\n    version: 5.0.0\n    ignore_modules: [\" legacy \", \"\"]\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	want := []ModuleMigration{{FromSource: "git::https://example.com/vpc.git", ToSource: "app.terraform.io/example/vpc/aws", Version: "5.0.0", IgnoreModules: []string{"legacy"}}}
+	if !reflect.DeepEqual(got.Migrations, want) {
+		t.Errorf("migrations = %#v, want %#v", got.Migrations, want)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policyFile := writeTestFile(t, t.TempDir(), "policy.yml", "modules:\n  - source: \" terraform-aws-modules/vpc/aws \"\n    min_version: \" 5.1.0 \"\nproviders:\n  - name: aws\n    min_version: \"5.30\"\n    max_version: \"6.0.0\"\n")
+	got, err := LoadPolicy(policyFile)
+	if err != nil {
+		t.Fatalf("loadPolicy failed: %v", err)
+	}
+	want := &Policy{
+		Modules:   []ModulePolicy{{Source: "terraform-aws-modules/vpc/aws", MinVersion: "5.1.0"}},
+		Providers: []ProviderPolicy{{Name: "aws", MinVersion: "5.30", MaxVersion: "6.0.0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("policy = %#v, want %#v", got, want)
+	}
+
+	tests := []struct {
+		name, data, want string
+	}{
+		{"unknown field", "modules:\n  - source: example/vpc/aws\n    minimum: 1.0.0\n", "failed to parse YAML: yaml: unmarshal errors:\n  line 3: field minimum not found in type bump.ModulePolicy"},
+		{"module missing source", "modules:\n  - min_version: 1.0.0\n", "module policy at index 0 is missing 'source' field"},
+		{"provider missing name", "providers:\n  - min_version: 1.0.0\n", "provider policy at index 0 is missing 'name' field"},
+		{"no bounds", "providers:\n  - name: aws\n", "provider policy at index 0 sets neither 'min_version' nor 'max_version'"},
+		{"constraint as bound", "modules:\n  - source: example/vpc/aws\n    min_version: \"~> 5.0\"\n", `module policy at index 0 has invalid 'min_version' "~> 5.0": must be a version such as "5.1.0"`},
+		{"bounds out of order", "providers:\n  - name: aws\n    min_version: \"6.0\"\n    max_version: \"5.30\"\n", `provider policy at index 0 has 'min_version' "6.0" above 'max_version' "5.30"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPolicy(writeTestFile(t, t.TempDir(), "policy.yml", tt.data)); err == nil || err.Error() != tt.want {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package bump
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config files are validated against the rules of schema/config-schema.json before they are
+// decoded, so that every problem is reported at once with the position of its YAML node rather
+// than as the first error of the decoder or of sanitize. The field table below mirrors the schema;
+// a test keeps the two in step. Beyond the schema, a version constraint must permit some version.
+// The schema's requirement that a config name at least one update is not enforced, as an empty
+// config has always been accepted.
+
+// versionConstraintPattern is the pattern of the schema's versionConstraint definition.
+var versionConstraintPattern = regexp.MustCompile(`^\s*(?:~>|>=|<=|>|<|=|!=)?\s*v?[0-9]+(?:\.[0-9]+){0,2}(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?(?:\s*,\s*(?:~>|>=|<=|>|<|=|!=)?\s*v?[0-9]+(?:\.[0-9]+){0,2}(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)*\s*$`)
+
+// ConfigError is a problem with one value of a config file, at the position of its YAML node.
+type ConfigError struct {
+	Line    int
+	Column  int
+	Message string // Names the value by its path, such as "modules[0].version"
+}
+
+// Error returns the message with its position.
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ConfigErrors lists every problem found in a config file, in document order.
+type ConfigErrors []ConfigError
+
+// Error returns the problems one per line.
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fieldKind is the kind of value a config field holds.
+type fieldKind int
+
+const (
+	stringField     fieldKind = iota // A non-empty string
+	versionField                     // A version constraint
+	versionsField                    // A version constraint, or a list of them in which blank items are skipped
+	patternsField                    // A list of strings in which blank items are skipped
+	namesField                       // A list of non-empty strings
+	boolField                        // true or false
+	prereleaseField                  // A pre-release policy
+	entriesField                     // A list of mappings with the fields of items
+)
+
+// configField is a field of a config mapping and the rules for its value.
+type configField struct {
+	name     string
+	kind     fieldKind
+	required bool
+	items    []configField // The fields of each entry of an entriesField
+	// rule checks an entry of an entriesField across its fields, returning the problem or ""
+	rule func(pairs []mappingPair) string
+}
+
+// configFields are the fields of a config file, in the order of the schema.
+var configFields = []configField{
+	{name: "terraform_version", kind: versionField},
+	{name: "prerelease", kind: prereleaseField},
+	{name: "providers", kind: entriesField, rule: addMissingRule, items: []configField{
+		{name: "name", kind: stringField, required: true},
+		{name: "version", kind: versionField, required: true},
+		{name: "preserve_operator", kind: boolField},
+		{name: "allow_downgrade", kind: boolField},
+		{name: "prerelease", kind: prereleaseField},
+		{name: "source", kind: stringField},
+		{name: "add_missing", kind: boolField},
+	}},
+	{name: "modules", kind: entriesField, items: []configField{
+		{name: "source", kind: stringField, required: true},
+		{name: "version", kind: versionField, required: true},
+		{name: "from", kind: versionsField},
+		{name: "ignore_versions", kind: versionsField},
+		{name: "ignore_modules", kind: patternsField},
+		{name: "preserve_operator", kind: boolField},
+		{name: "allow_downgrade", kind: boolField},
+		{name: "prerelease", kind: prereleaseField},
+	}},
+	{name: "provider_migrations", kind: entriesField, items: []configField{
+		{name: "from_source", kind: stringField, required: true},
+		{name: "to_source", kind: stringField, required: true},
+		{name: "version", kind: versionField, required: true},
+	}},
+	{name: "migrations", kind: entriesField, items: []configField{
+		{name: "from_source", kind: stringField, required: true},
+		{name: "to_source", kind: stringField, required: true},
+		{name: "version", kind: versionField},
+		{name: "ignore_modules", kind: patternsField},
+	}},
+	{name: "groups", kind: entriesField, rule: groupEntriesRule, items: []configField{
+		{name: "name", kind: stringField, required: true},
+		{name: "modules", kind: namesField},
+		{name: "providers", kind: namesField},
+	}},
+}
+
+// configValidator collects the problems of a config document.
+type configValidator struct {
+	errs ConfigErrors
+}
+
+// ValidateConfigDocument checks a parsed config file against the rules of the config schema.
+//
+// Parameters:
+//   - document: The YAML document node of the config file
+//
+// Returns:
+//   - ConfigErrors: Every problem found, in document order; nil when the config is valid
+func ValidateConfigDocument(document *yaml.Node) ConfigErrors {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil
+	}
+	root := resolveAlias(document.Content[0])
+	// A document of only comments or null is an empty config
+	if isNull(root) {
+		return nil
+	}
+	validator := &configValidator{}
+	validator.mapping(root, "config", configFields)
+	slices.SortStableFunc(validator.errs, func(a, b ConfigError) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return validator.errs
+}
+
+// add records a problem at the position of a node.
+func (v *configValidator) add(node *yaml.Node, format string, args ...any) {
+	v.errs = append(v.errs, ConfigError{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// mapping checks a mapping against its fields: every key must be a field, required fields must be
+// set, and each value must suit its field.
+func (v *configValidator) mapping(node *yaml.Node, path string, fields []configField) []mappingPair {
+	if node.Kind != yaml.MappingNode {
+		v.add(node, "%s must be a mapping", path)
+		return nil
+	}
+	pairs := mappingPairs(node)
+	for _, pair := range pairs {
+		index := slices.IndexFunc(fields, func(field configField) bool { return field.name == pair.key.Value })
+		if index < 0 {
+			v.add(pair.key, "%s has unknown field '%s'", path, pair.key.Value)
+			continue
+		}
+		v.value(pair.value, fieldPath(path, pair.key.Value), fields[index])
+	}
+	for _, field := range fields {
+		// A blank string is reported by its value
+		if value := lookupPair(pairs, field.name); field.required && (value == nil || isNull(value)) {
+			v.add(node, "%s is missing '%s' field", path, field.name)
+		}
+	}
+	return pairs
+}
+
+// entry checks an entry of an entriesField and the rule across its fields.
+func (v *configValidator) entry(node *yaml.Node, path string, field configField) {
+	pairs := v.mapping(node, path, field.items)
+	if pairs == nil || field.rule == nil {
+		return
+	}
+	if problem := field.rule(pairs); problem != "" {
+		v.add(node, "%s %s", path, problem)
+	}
+}
+
+// addMissingRule requires a source for a provider entry that sets add_missing.
+func addMissingRule(pairs []mappingPair) string {
+	var addMissing bool
+	if value := lookupPair(pairs, "add_missing"); value != nil && value.Decode(&addMissing) == nil && addMissing &&
+		isBlank(lookupPair(pairs, "source")) {
+		return "sets 'add_missing' without a 'source' field"
+	}
+	return ""
+}
+
+// groupEntriesRule requires a group to list at least one module or provider.
+func groupEntriesRule(pairs []mappingPair) string {
+	if isEmptyList(lookupPair(pairs, "modules")) && isEmptyList(lookupPair(pairs, "providers")) {
+		return "lists no modules or providers"
+	}
+	return ""
+}
+
+// value checks the value of a field. A null value is treated as unset.
+func (v *configValidator) value(node *yaml.Node, path string, field configField) {
+	if isNull(node) {
+		return
+	}
+	switch field.kind {
+	case versionField:
+		v.version(node, path)
+	case versionsField:
+		if node.Kind == yaml.SequenceNode {
+			v.list(node, path, v.optionalVersion, true)
+		} else {
+			v.optionalVersion(node, path)
+		}
+	case patternsField:
+		v.list(node, path, func(item *yaml.Node, itemPath string) { v.scalar(item, itemPath) }, false)
+	case namesField:
+		v.list(node, path, v.nonEmpty, false)
+	case entriesField:
+		v.list(node, path, func(item *yaml.Node, itemPath string) { v.entry(item, itemPath, field) }, false)
+	default:
+		v.scalarValue(node, path, field.kind)
+	}
+}
+
+// scalarValue checks the value of a string, boolean, or pre-release policy field.
+func (v *configValidator) scalarValue(node *yaml.Node, path string, kind fieldKind) {
+	switch kind {
+	case stringField:
+		v.nonEmpty(node, path)
+	case boolField:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			v.add(node, "%s must be true or false", path)
+		}
+	case prereleaseField:
+		if v.scalar(node, path) && !ValidPrereleasePolicy(strings.TrimSpace(node.Value)) {
+			v.add(node, "%s %q must be 'allow', 'if-current', or 'exclude'", path, node.Value)
+		}
+	}
+}
+
+// nonEmpty checks that a node is a string that is not blank.
+func (v *configValidator) nonEmpty(node *yaml.Node, path string) {
+	if v.scalar(node, path) && strings.TrimSpace(node.Value) == "" {
+		v.add(node, "%s must not be empty", path)
+	}
+}
+
+// scalar reports whether a node is a string, recording a problem when it is not. Numbers are
+// rejected so that a version such as 5.0 is quoted rather than read as a float.
+func (v *configValidator) scalar(node *yaml.Node, path string) bool {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		v.add(node, "%s must be a string", path)
+		return false
+	}
+	return true
+}
+
+// version checks that a node is a non-empty version constraint.
+func (v *configValidator) version(node *yaml.Node, path string) {
+	if !v.scalar(node, path) {
+		return
+	}
+	if strings.TrimSpace(node.Value) == "" {
+		v.add(node, "%s must not be empty", path)
+		return
+	}
+	if !versionConstraintPattern.MatchString(node.Value) {
+		v.add(node, "%s %q is not a valid version constraint", path, node.Value)
+		return
+	}
+	// The pattern accepts any comma-separated clauses, so a typo such as "5,0.0" is only caught by
+	// the clauses contradicting each other
+	if clauses, ok := parseConstraint(node.Value); ok && constraintRange(clauses).empty() {
+		v.add(node, "%s %q permits no version: its comma-separated clauses contradict each other", path, node.Value)
+	}
+}
+
+// optionalVersion checks a version filter item, in which a blank string is skipped.
+func (v *configValidator) optionalVersion(node *yaml.Node, path string) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && strings.TrimSpace(node.Value) == "" {
+		return
+	}
+	v.version(node, path)
+}
+
+// list checks that a node is a list and checks each item. nonEmpty requires at least one item.
+func (v *configValidator) list(node *yaml.Node, path string, item func(*yaml.Node, string), nonEmpty bool) {
+	if node.Kind != yaml.SequenceNode {
+		v.add(node, "%s must be a list", path)
+		return
+	}
+	if nonEmpty && len(node.Content) == 0 {
+		v.add(node, "%s must not be an empty list", path)
+	}
+	for i, child := range node.Content {
+		item(resolveAlias(child), fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+// mappingPair is a key and value of a mapping.
+type mappingPair struct {
+	key, value *yaml.Node
+}
+
+// mappingPairs returns the pairs of a mapping with merge keys expanded; keys written in the mapping
+// take precedence over merged ones.
+func mappingPairs(node *yaml.Node) []mappingPair {
+	var pairs, merged []mappingPair
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		if key.Value != "<<" || key.ShortTag() != "!!merge" {
+			pairs = append(pairs, mappingPair{key: key, value: value})
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			if source = resolveAlias(source); source.Kind == yaml.MappingNode {
+				merged = append(merged, mappingPairs(source)...)
+			}
+		}
+	}
+	for _, pair := range merged {
+		if lookupPair(pairs, pair.key.Value) == nil {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// lookupPair returns the value of a key, or nil when the key is not set.
+func lookupPair(pairs []mappingPair, key string) *yaml.Node {
+	for _, pair := range pairs {
+		if pair.key.Value == key {
+			return pair.value
+		}
+	}
+	return nil
+}
+
+// resolveAlias returns the node an alias refers to, or the node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// isNull reports whether a node is an explicit or empty null.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+// isBlank reports whether a value is unset, null, or a blank string.
+func isBlank(node *yaml.Node) bool {
+	return node == nil || isNull(node) || (node.Kind == yaml.ScalarNode && strings.TrimSpace(node.Value) == "")
+}
+
+// isEmptyList reports whether a value is unset, null, or a list without items.
+func isEmptyList(node *yaml.Node) bool {
+	return node == nil || isNull(node) || (node.Kind == yaml.SequenceNode && len(node.Content) == 0)
+}
+
+// fieldPath returns the path of a field of the mapping at path.
+func fieldPath(path, name string) string {
+	if path == "config" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,168 @@
+package bump
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestLoadConfigReportsEveryProblemWithPosition(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	data := `terraform_version: ">= 1.5"
+providers:
+  - name: aws
+    version: "5,0.0"
+    preserve_operator: "yes"
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: 5.0
+    from: ["4.0.0", "4.x"]
+  - version: "6.0.0"
+    tags: [core]
+`
+	if err := os.WriteFile(configFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(configFile)
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want ConfigErrors", err)
+	}
+	want := ConfigErrors{
+		{Line: 4, Column: 14, Message: `providers[0].version "5,0.0" permits no version: its comma-separated clauses contradict each other`},
+		{Line: 5, Column: 24, Message: "providers[0].preserve_operator must be true or false"},
+		{Line: 8, Column: 14, Message: "modules[0].version must be a string"},
+		{Line: 9, Column: 21, Message: `modules[0].from[1] "4.x" is not a valid version constraint`},
+		{Line: 10, Column: 5, Message: "modules[1] is missing 'source' field"},
+		{Line: 11, Column: 5, Message: "modules[1] has unknown field 'tags'"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors =\n%v\nwant\n%v", errs, want)
+	}
+	if got := want[2].Error(); got != `line 8, column 14: modules[0].version must be a string` {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestValidateConfigDocumentAcceptsAnchorsAndBlankFilters(t *testing.T) {
+	data := `defaults: &defaults
+  version: "5.0.0"
+  preserve_operator: true
+`
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateConfigDocument(&document); len(errs) != 1 || errs[0].Message != "config has unknown field 'defaults'" {
+		t.Errorf("errors = %v, want the unknown field only", errs)
+	}
+
+	data = `modules:
+  - &vpc
+    source: terraform-aws-modules/vpc/aws
+    version: "5.0.0"
+    from: ["", " 4.0.0 "]
+  - <<: *vpc
+    source: terraform-aws-modules/eks/aws
+  - *vpc
+`
+	if err := yaml.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateConfigDocument(&document); errs != nil {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
+
+func TestConfigSanitizeRejectsInvalidEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"module missing source", Config{Modules: []ModuleUpdate{{Version: "5.0.0"}}}, "module at index 0 is missing 'source' field"},
+		{"provider missing version", Config{Providers: []ProviderUpdate{{Name: "aws"}}}, "provider at index 0 is missing 'version' field"},
+		{"invalid prerelease", Config{Prerelease: "never"}, `invalid prerelease policy "never": must be 'allow', 'if-current', or 'exclude'`},
+		{"add_missing without source", Config{Providers: []ProviderUpdate{{Name: "aws", Version: "6.0.0", AddMissing: true}}}, "provider at index 0 sets 'add_missing' without a 'source' field"},
+		{"empty group", Config{Groups: []UpdateGroup{{Name: "core"}}}, `group "core" lists no modules or providers`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.sanitize(); err == nil || err.Error() != tt.want {
+				t.Errorf("sanitize() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// schemaNode is the part of a JSON schema node that the config field table mirrors.
+type schemaNode struct {
+	Pattern    string                `json:"pattern"`
+	Required   []string              `json:"required"`
+	Properties map[string]schemaNode `json:"properties"`
+	Items      *schemaNode           `json:"items"`
+}
+
+func TestConfigFieldsMatchSchema(t *testing.T) {
+	data, err := os.ReadFile("../schema/config-schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		schemaNode
+		Definitions struct {
+			VersionConstraint schemaNode `json:"versionConstraint"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := versionConstraintPattern.String(); got != schema.Definitions.VersionConstraint.Pattern {
+		t.Errorf("version pattern = %q, want the schema's %q", got, schema.Definitions.VersionConstraint.Pattern)
+	}
+	assertFieldsMatchSchema(t, "config", configFields, schema.schemaNode)
+}
+
+func assertFieldsMatchSchema(t *testing.T, path string, fields []configField, schema schemaNode) {
+	t.Helper()
+	var names, required []string
+	for _, field := range fields {
+		names = append(names, field.name)
+		if field.required {
+			required = append(required, field.name)
+		}
+		if field.kind == entriesField {
+			property := schema.Properties[field.name]
+			if property.Items == nil {
+				t.Errorf("schema %s.%s has no items", path, field.name)
+				continue
+			}
+			assertFieldsMatchSchema(t, path+"."+field.name, field.items, *property.Items)
+		}
+	}
+	var schemaNames []string
+	for name := range schema.Properties {
+		schemaNames = append(schemaNames, name)
+	}
+	slices.Sort(names)
+	slices.Sort(schemaNames)
+	if !slices.Equal(names, schemaNames) {
+		t.Errorf("%s fields = %v, want the schema's %v", path, names, schemaNames)
+	}
+	// The top-level anyOf is not enforced, so only entries have required fields
+	if path != "config" {
+		slices.Sort(required)
+		schemaRequired := slices.Sorted(slices.Values(schema.Required))
+		if !slices.Equal(required, schemaRequired) {
+			t.Errorf("%s required = %v, want the schema's %v", path, required, schemaRequired)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+)
+
+// Terraform loads every configuration file of a directory as one module, so a provider that two
+// files of a directory declare differently is one requirement that the module states twice.
+// Override files (override.tf and *_override.tf) replace what other files declare and are not
+// compared with them.
+
+// Directory is the selected files of one module directory, in the order they were listed.
+type Directory struct {
+	Path  string
+	Files []string
+}
+
+// Directories groups the files by directory, in the order each directory is first listed.
+func (files Files) Directories() []Directory {
+	var directories []Directory
+	for _, file := range files {
+		path := filepath.Dir(file)
+		index := slices.IndexFunc(directories, func(directory Directory) bool { return directory.Path == path })
+		if index < 0 {
+			directories = append(directories, Directory{Path: path})
+			index = len(directories) - 1
+		}
+		directories[index].Files = append(directories[index].Files, file)
+	}
+	return directories
+}
+
+// ProviderDeclaration is one required_providers entry for a provider.
+type ProviderDeclaration struct {
+	File    string
+	Pos     hcl.Pos
+	Source  string // Empty when the entry sets no literal source
+	Version string // Empty when the entry sets no literal version
+}
+
+// ProviderConflict is a provider that the files of one directory declare with different sources
+// or version constraints.
+type ProviderConflict struct {
+	Directory    string
+	Name         string                // Local provider name
+	Field        string                // "source" or "version"
+	Declarations []ProviderDeclaration // Every entry that sets the field, in file order
+}
+
+// providerCollector collects the required_providers entries of the files of a directory.
+type providerCollector struct {
+	filename     string
+	declarations map[string][]ProviderDeclaration
+	names        []string // Provider names in the order first declared
+}
+
+// FindProviderConflicts reads the files of a directory and reports each provider that they
+// declare with different sources or different version constraints. Entries without a literal
+// source or version, override files, and Terragrunt configuration are not compared.
+//
+// Parameters:
+//   - directory: The files of one module directory, from Files.Directories
+//   - opts: Terragrunt selects how .hcl files are read; OpenTofu matches registry.opentofu.org
+//     sources to their Terraform equivalents
+//
+// Returns:
+//   - []ProviderConflict: The conflicts, in the order the providers are first declared
+//   - error: Any error encountered while reading or parsing a file
+func FindProviderConflicts(directory Directory, opts Options) ([]ProviderConflict, error) {
+	collector := &providerCollector{declarations: make(map[string][]ProviderDeclaration)}
+	for _, file := range directory.Files {
+		if isOverrideFile(file) || fileSyntax(file, opts.Terragrunt) == terragruntSyntax {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		collector.filename = file
+		if err := scanPins(file, src, opts.Terragrunt, collector); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	var conflicts []ProviderConflict
+	for _, name := range collector.names {
+		declarations := collector.declarations[name]
+		sources := slices.DeleteFunc(slices.Clone(declarations), func(d ProviderDeclaration) bool { return d.Source == "" })
+		if slices.ContainsFunc(sources, func(d ProviderDeclaration) bool {
+			return !sameProviderAddress(d.Source, sources[0].Source, opts.OpenTofu)
+		}) {
+			conflicts = append(conflicts, ProviderConflict{Directory: directory.Path, Name: name, Field: "source", Declarations: sources})
+		}
+		versions := slices.DeleteFunc(slices.Clone(declarations), func(d ProviderDeclaration) bool { return d.Version == "" })
+		if slices.ContainsFunc(versions, func(d ProviderDeclaration) bool { return d.Version != versions[0].Version }) {
+			conflicts = append(conflicts, ProviderConflict{Directory: directory.Path, Name: name, Field: "version", Declarations: versions})
+		}
+	}
+	return conflicts, nil
+}
+
+func (c *providerCollector) visitModule(string, hcl.Pos, string, *pinValue) {}
+
+func (c *providerCollector) visitProvider(name string, pos hcl.Pos, source string, version *pinValue) {
+	if _, ok := c.declarations[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.declarations[name] = append(c.declarations[name], ProviderDeclaration{File: c.filename, Pos: pos, Source: source, Version: literalText(version)})
+}
+
+func (c *providerCollector) visitTerragruntSource(string, *pinValue) {}
+
+// isOverrideFile reports whether Terraform merges a file over the other files of its directory.
+func isOverrideFile(filename string) bool {
+	base := filepath.Base(filename)
+	for _, extension := range []string{".tf.json", ".tofu.json", ".tf", ".tofu"} {
+		if stem, ok := strings.CutSuffix(base, extension); ok {
+			return stem == "override" || strings.HasSuffix(stem, "_override")
+		}
+	}
+	return false
+}
+
+// sameProviderAddress reports whether two provider sources name the same provider. With openTofu
+// set, registry.opentofu.org addresses also match their registry.terraform.io equivalents.
+func sameProviderAddress(a, b string, openTofu bool) bool {
+	if !openTofu {
+		return sameProviderSource(a, b)
+	}
+	first, err := tfaddr.ParseProviderSource(a)
+	if err != nil {
+		return a == b
+	}
+	second, err := tfaddr.ParseProviderSource(b)
+	if err != nil {
+		return false
+	}
+	for _, address := range []*tfaddr.Provider{&first, &second} {
+		if address.Hostname.String() == openTofuRegistryHost {
+			address.Hostname = tfaddr.DefaultProviderRegistryHost
+		}
+	}
+	return first.Equals(second)
+}
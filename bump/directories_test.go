@@ -0,0 +1,72 @@
+package bump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilesDirectories(t *testing.T) {
+	files := Files{"envs/prod/main.tf", "envs/dev/main.tf", "envs/prod/providers.tf", "main.tf"}
+	want := []Directory{
+		{Path: "envs/prod", Files: []string{"envs/prod/main.tf", "envs/prod/providers.tf"}},
+		{Path: "envs/dev", Files: []string{"envs/dev/main.tf"}},
+		{Path: ".", Files: []string{"main.tf"}},
+	}
+	if got := files.Directories(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Directories() = %#v, want %#v", got, want)
+	}
+}
+
+func TestFindProviderConflicts(t *testing.T) {
+	dir := t.TempDir()
+	main := writeTestFile(t, dir, "main.tf", `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.6"
+    }
+  }
+}
+`)
+	versions := writeTestFile(t, dir, "versions.tf.json", `{
+  "terraform": {
+    "required_providers": {
+      "aws": {"source": "registry.terraform.io/hashicorp/aws", "version": "~> 6.0"},
+      "random": {"source": "example/random"},
+      "tls": {"source": "hashicorp/tls", "version": "~> 4.0"}
+    }
+  }
+}
+`)
+	tls := writeTestFile(t, dir, "tls.tf", "terraform {\n  required_providers {\n    tls = {\n      source = \"hashicorp/tls\"\n    }\n  }\n}\n")
+	override := writeTestFile(t, dir, "providers_override.tf", "terraform {\n  required_providers {\n    tls = {\n      version = \"~> 5.0\"\n    }\n  }\n}\n")
+
+	conflicts, err := FindProviderConflicts(Directory{Path: dir, Files: []string{main, versions, tls, override}}, Options{})
+	if err != nil {
+		t.Fatalf("FindProviderConflicts() error = %v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("conflicts = %#v, want the aws version and the random source", conflicts)
+	}
+	aws, random := conflicts[0], conflicts[1]
+	if aws.Name != "aws" || aws.Field != "version" || aws.Directory != dir || len(aws.Declarations) != 2 ||
+		aws.Declarations[0].Version != "~> 5.0" || aws.Declarations[1].File != versions || aws.Declarations[1].Pos.Line != 4 {
+		t.Errorf("aws conflict = %#v", aws)
+	}
+	if random.Name != "random" || random.Field != "source" || random.Declarations[0].Source != "hashicorp/random" || random.Declarations[1].Source != "example/random" {
+		t.Errorf("random conflict = %#v", random)
+	}
+}
+
+func TestSameProviderAddressOpenTofu(t *testing.T) {
+	if sameProviderAddress("registry.opentofu.org/hashicorp/aws", "hashicorp/aws", false) {
+		t.Error("registry.opentofu.org address matched without OpenTofu rules")
+	}
+	if !sameProviderAddress("registry.opentofu.org/hashicorp/aws", "hashicorp/aws", true) {
+		t.Error("registry.opentofu.org address did not match with OpenTofu rules")
+	}
+}
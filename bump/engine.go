@@ -0,0 +1,458 @@
+// Package bump updates Terraform module versions, Terraform required_version constraints, and
+// provider versions across multiple files, and checks the version pins of those files against
+// lint rules, version policies, and advisories.
+//
+// It is the engine behind the tf-version-bump command, which is a thin wrapper around it:
+//
+//	engine := &bump.Engine{Options: bump.Options{DryRun: true}}
+//	result, err := engine.Apply(ctx, bump.Config{
+//		Modules: []bump.ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}},
+//	}, bump.Files{"main.tf"})
+//
+// Apply returns the changes it made, the blocks it skipped, and the files it could not process as
+// typed values rather than printing them. The same values are passed to Engine.OnEvent as they
+// happen, in the order the command line prints them.
+//
+// Each kind of config entry is applied by an Updater. Register your own in a Registry to apply
+// the CustomUpdate entries of a config.
+//
+// Files are parsed and edited with the official HashiCorp HCL library, which retains comments and
+// HCL structure. Changed files are normalised by hclwrite.Format. Terraform JSON syntax (.tf.json)
+// files are edited in place so that their layout is unchanged.
+package bump
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Options holds the behaviour switches that apply to every update of a run.
+type Options struct {
+	DryRun           bool   // Report what would change without modifying files
+	ForceAdd         bool   // Add a missing version to registry modules and provider entries
+	PreserveOperator bool   // Keep the operator and precision of existing constraints
+	AllowDowngrade   bool   // Apply updates that would move a constraint below its current version
+	Prerelease       string // Pre-release policy: "allow" (default), "if-current", or "exclude"
+	Terragrunt       bool   // Treat .hcl files as Terragrunt configuration
+	OpenTofu         bool   // Match registry.opentofu.org addresses to their Terraform equivalents
+	FollowReferences bool   // Update the literal behind a version written as local.<name> or var.<name>
+	OutputFormat     string // Quoting of names in messages: "text" (default) or "md" (Markdown)
+
+	// Confirm, when set, is asked before each module version, provider version, or
+	// required_version change is made to a block; false leaves the block unchanged and reports a
+	// filtered skip. Source migrations and custom updaters are not confirmed.
+	Confirm func(Proposal) bool
+
+	// BeforeWrite, when set, is called with the name of each file before it is written, including
+	// files changed by followed references or a policy fix; an error leaves that file unwritten and
+	// is reported as a Diagnostic.
+	BeforeWrite func(filename string) error
+}
+
+// Engine applies the updates of a Config to a set of files.
+type Engine struct {
+	Options
+
+	// OnEvent, when set, receives every Change, Skip, and Diagnostic as it happens.
+	OnEvent func(Event)
+
+	// Updaters applies the entries of a config; nil means NewRegistry(). Register an updater in it
+	// to apply the CustomUpdate entries that name it.
+	Updaters *Registry
+}
+
+// Files lists the paths of the files an update is applied to, in the order they are processed.
+type Files []string
+
+// ChangeKind identifies the update that produced a Change or a Diagnostic.
+type ChangeKind int
+
+const (
+	TerraformVersionChange  ChangeKind = iota // required_version set in a file's terraform blocks
+	ProviderMigrationChange                   // required_providers entry moved to a new source
+	ProviderChange                            // provider version set in a file's required_providers blocks
+	ModuleMigrationChange                     // module blocks of a file moved to a new source
+	ModuleChange                              // module version set in a file's module blocks
+	ReferenceChange                           // local value or variable default behind a version updated
+	CustomChange                              // file edited by a registered updater
+)
+
+// Event is a Change, a Skip, or a Diagnostic.
+type Event interface {
+	event()
+}
+
+// Change is one update applied to a file, or that would be applied in a dry run. Each update of a
+// Config produces at most one Change per file, except provider migrations, which produce one per
+// migrated entry.
+type Change struct {
+	Kind       ChangeKind
+	File       string
+	Name       string   // Provider name, or the local.<name> or var.<name> reference that was followed
+	Source     string   // Module source of a ModuleChange
+	FromSource string   // Source replaced by a migration
+	ToSource   string   // Source written by a migration
+	Version    string   // Version written
+	From       []string // Version filter of a ModuleChange
+	Previous   []string // Distinct versions replaced by a ModuleChange or ProviderChange, sorted; "" is a missing version
+	Blocks     []string // Identifiers, unique within File, of the blocks whose values changed
+	Updater    string   // Registered name of the updater of a CustomChange
+}
+
+// Proposal is a version change about to be made to one block, passed to Options.Confirm.
+type Proposal struct {
+	Kind    ChangeKind // TerraformVersionChange, ProviderChange, or ModuleChange
+	File    string
+	Block   string // Label of a module block
+	Name    string // Module source or provider name
+	Current string // Version written now, or "" when there is none
+	Target  string // Version to write
+
+	// The attribute the change edits, with its values before and after the change: "version" or
+	// "required_version", or "source" for a Terragrunt source address that carries the version.
+	Attribute string
+	Before    string // "" when the attribute is added
+	After     string
+}
+
+// Skip is a matching block that was left alone. Filtered skips are blocks excluded by a filter of
+// the update, such as ignore_modules; the others are blocks the update could not be applied to.
+type Skip struct {
+	File     string
+	Message  string
+	Filtered bool
+}
+
+// Diagnostic is a file that an update could not be applied to.
+type Diagnostic struct {
+	Kind ChangeKind
+	File string
+	Err  error
+}
+
+func (Change) event()     {}
+func (Skip) event()       {}
+func (Diagnostic) event() {}
+
+// Result lists the events of a run in the order they happened.
+type Result struct {
+	Changes     []Change
+	Skips       []Skip
+	Diagnostics []Diagnostic
+}
+
+// add files an event under its type.
+func (result *Result) add(event Event) {
+	switch event := event.(type) {
+	case Change:
+		result.Changes = append(result.Changes, event)
+	case Skip:
+		result.Skips = append(result.Skips, event)
+	case Diagnostic:
+		result.Diagnostics = append(result.Diagnostics, event)
+	}
+}
+
+// eventSink receives the events of a run. A nil sink discards them.
+type eventSink func(Event)
+
+// emit passes an event to the sink.
+func (sink eventSink) emit(event Event) {
+	if sink != nil {
+		sink(event)
+	}
+}
+
+// skip reports a block that the update could not be applied to.
+func (sink eventSink) skip(format string, args ...any) {
+	sink.emit(Skip{Message: fmt.Sprintf(format, args...)})
+}
+
+// filtered reports a block excluded by a filter of the update.
+func (sink eventSink) filtered(format string, args ...any) {
+	sink.emit(Skip{Message: fmt.Sprintf(format, args...), Filtered: true})
+}
+
+// forFile returns a sink that sets the file of skips reported while processing filename.
+func (sink eventSink) forFile(filename string) eventSink {
+	return func(event Event) {
+		if skip, ok := event.(Skip); ok && skip.File == "" {
+			skip.File = filename
+			event = skip
+		}
+		sink.emit(event)
+	}
+}
+
+// Apply applies every update of config to every file: the Terraform version first, then provider
+// migrations, provider updates, module migrations, module updates, and custom updates, so that
+// updates see the sources that migrations write. Files that cannot be processed are reported as diagnostics rather
+// than stopping the run.
+//
+// Parameters:
+//   - ctx: Checked before each file; a cancelled run returns the events so far with ctx.Err()
+//   - config: The updates to apply, validated as a config file is
+//   - files: The files to update
+//
+// Returns:
+//   - Result: The changes, skips, and diagnostics of the run
+//   - error: An invalid config, a custom update whose updater is not registered, or the context's
+//     error when it is done before the run finishes
+func (e *Engine) Apply(ctx context.Context, config Config, files Files) (Result, error) {
+	config = config.clone()
+	if err := config.sanitize(); err != nil {
+		return Result{}, err
+	}
+
+	updaters := e.Updaters
+	if updaters == nil {
+		updaters = NewRegistry()
+	}
+	for index, update := range config.Custom {
+		if _, ok := updaters.Lookup(update.Updater); !ok {
+			return Result{}, fmt.Errorf("custom update at index %d names updater %q, which is not registered", index, update.Updater)
+		}
+	}
+
+	var result Result
+	r := &run{Options: e.Options, updaters: updaters, events: func(event Event) {
+		result.add(event)
+		if e.OnEvent != nil {
+			e.OnEvent(event)
+		}
+	}}
+	r.Prerelease = cmp.Or(config.Prerelease, r.Prerelease)
+
+	for _, step := range r.steps(&config) {
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			step(file)
+		}
+	}
+	return result, nil
+}
+
+// run is one Apply call.
+type run struct {
+	Options
+	updaters *Registry
+	events   eventSink
+}
+
+// steps returns the updates of a config in the order they are applied, each as a function that
+// applies the update to one file.
+func (r *run) steps(config *Config) []func(file string) {
+	var steps []func(string)
+	if config.TerraformVersion != "" {
+		steps = append(steps, func(file string) { r.update(file, TerraformVersionUpdater, config.TerraformVersion) })
+	}
+	if len(config.ProviderMigrations) > 0 {
+		steps = append(steps, func(file string) {
+			for index := range config.ProviderMigrations {
+				r.migrateProviders(file, &config.ProviderMigrations[index])
+			}
+		})
+	}
+	for index := range config.Providers {
+		steps = append(steps, func(file string) { r.update(file, ProviderUpdater, &config.Providers[index]) })
+	}
+	if len(config.Migrations) > 0 {
+		steps = append(steps, func(file string) {
+			for index := range config.Migrations {
+				r.migrateModules(file, &config.Migrations[index])
+			}
+		})
+	}
+	if len(config.Modules) > 0 {
+		steps = append(steps, func(file string) {
+			for index := range config.Modules {
+				r.update(file, ModuleUpdater, &config.Modules[index])
+			}
+		})
+	}
+	if len(config.Custom) > 0 {
+		steps = append(steps, func(file string) {
+			for index := range config.Custom {
+				r.update(file, config.Custom[index].Updater, &config.Custom[index])
+			}
+		})
+	}
+	return steps
+}
+
+// migrateProviders applies one provider migration to one file.
+func (r *run) migrateProviders(file string, migration *ProviderMigration) {
+	opts := r.providerMigrationOptions(migration)
+	opts.events = r.events.forFile(file)
+	migrated, err := migrateProviderSourceWithCount(file, opts, r.DryRun)
+	if err != nil {
+		r.events.emit(Diagnostic{Kind: ProviderMigrationChange, File: file, Err: err})
+		return
+	}
+	for _, entry := range migrated {
+		r.events.emit(Change{
+			Kind:       ProviderMigrationChange,
+			File:       file,
+			Name:       entry.name,
+			FromSource: migration.FromSource,
+			ToSource:   migration.ToSource,
+			Version:    migration.Version,
+			Blocks:     []string{entry.location},
+		})
+	}
+}
+
+// migrateModules applies one module migration to one file.
+func (r *run) migrateModules(file string, migration *ModuleMigration) {
+	opts := r.migrationOptions(migration)
+	opts.events = r.events.forFile(file)
+	changedBlocks, err := migrateModuleSourceWithCount(file, opts, r.DryRun)
+	if err != nil {
+		r.events.emit(Diagnostic{Kind: ModuleMigrationChange, File: file, Err: err})
+		return
+	}
+	if len(changedBlocks) > 0 {
+		r.events.emit(Change{
+			Kind:       ModuleMigrationChange,
+			File:       file,
+			FromSource: migration.FromSource,
+			ToSource:   migration.ToSource,
+			Version:    migration.Version,
+			Blocks:     blockIndexes(changedBlocks),
+		})
+	}
+}
+
+// change returns the Change for a file whose module blocks an update was applied to.
+func (update *ModuleUpdate) change(file string, changedBlocks []int, previous versionSet) Change {
+	return Change{
+		Kind:     ModuleChange,
+		File:     file,
+		Source:   update.Source,
+		Version:  update.Version,
+		From:     append([]string(nil), update.From...),
+		Previous: previous.sorted(),
+		Blocks:   blockIndexes(changedBlocks),
+	}
+}
+
+// moduleOptions combines a module update entry with the options of a run.
+func (options *Options) moduleOptions(update *ModuleUpdate) moduleUpdateOptions {
+	return moduleUpdateOptions{
+		moduleSource:     update.Source,
+		version:          update.Version,
+		fromVersions:     update.From,
+		ignoreVersions:   update.IgnoreVersions,
+		ignorePatterns:   update.IgnoreModules,
+		forceAdd:         options.ForceAdd,
+		preserveOperator: options.PreserveOperator || update.PreserveOperator,
+		allowDowngrade:   options.AllowDowngrade || update.AllowDowngrade,
+		prerelease:       cmp.Or(update.Prerelease, options.Prerelease),
+		terragrunt:       options.Terragrunt,
+		opentofu:         options.OpenTofu,
+		followReferences: options.FollowReferences,
+		outputFormat:     options.OutputFormat,
+		confirm:          options.Confirm,
+		beforeWrite:      options.BeforeWrite,
+		previous:         make(versionSet),
+	}
+}
+
+// providerOptions combines a provider update entry with the options of a run.
+func (options *Options) providerOptions(update *ProviderUpdate) *providerUpdateOptions {
+	opts := &providerUpdateOptions{
+		providerName:     update.Name,
+		version:          update.Version,
+		fromVersions:     update.from,
+		fromSource:       update.fromSource,
+		forceAdd:         options.ForceAdd,
+		preserveOperator: options.PreserveOperator || update.PreserveOperator,
+		allowDowngrade:   options.AllowDowngrade || update.AllowDowngrade,
+		prerelease:       cmp.Or(update.Prerelease, options.Prerelease),
+		terragrunt:       options.Terragrunt,
+		opentofu:         options.OpenTofu,
+		followReferences: options.FollowReferences,
+		outputFormat:     options.OutputFormat,
+		confirm:          options.Confirm,
+		beforeWrite:      options.BeforeWrite,
+		previous:         make(versionSet),
+	}
+	if update.AddMissing {
+		opts.addSource = update.Source
+	}
+	return opts
+}
+
+// terraformOptions combines a required_version target with the options of a run.
+func (options *Options) terraformOptions(version string) *terraformVersionOptions {
+	return &terraformVersionOptions{
+		version:        version,
+		allowDowngrade: options.AllowDowngrade,
+		prerelease:     options.Prerelease,
+		terragrunt:     options.Terragrunt,
+		opentofu:       options.OpenTofu,
+		outputFormat:   options.OutputFormat,
+		confirm:        options.Confirm,
+		beforeWrite:    options.BeforeWrite,
+	}
+}
+
+// blockIndexes converts module block indexes to block identifiers.
+func blockIndexes(indexes []int) []string {
+	blocks := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		blocks = append(blocks, strconv.Itoa(index))
+	}
+	return blocks
+}
+
+// versionSet collects the distinct versions an update replaced in one file. Options copied from
+// one another share the set; a nil set records nothing.
+type versionSet map[string]struct{}
+
+// replace records current as replaced when the update writes a different target over it.
+func (set versionSet) replace(current, target string) {
+	if set != nil && current != target {
+		set[current] = struct{}{}
+	}
+}
+
+// sorted returns the recorded versions in sorted order, or nil when none were recorded.
+func (set versionSet) sorted() []string {
+	if len(set) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(set))
+}
+
+// confirmChange asks confirm, when set, whether a proposed change may be made. A change that
+// leaves the version as it is needs no confirmation; a declined one is reported as filtered.
+func confirmChange(confirm func(Proposal) bool, events eventSink, subject string, proposal Proposal, outputFormat string) bool {
+	if confirm == nil || proposal.Current == proposal.Target || confirm(proposal) {
+		return true
+	}
+	events.filtered("%s: change to %s declined", subject, quote(proposal.Target, outputFormat))
+	return false
+}
+
+// quote formats a string with appropriate quoting based on the output format.
+// For "text" output, uses single quotes. For "md" (Markdown) output, uses backticks.
+//
+// Parameters:
+//   - s: The string to quote
+//   - format: Output format ("text" or "md")
+//
+// Returns:
+//   - string: The quoted string
+func quote(s, format string) string {
+	if format == "md" {
+		return "`" + s + "`"
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,295 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEngineApplyReturnsTypedResult(t *testing.T) {
+	dir := t.TempDir()
+	input := `terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "legacy_vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "3.0.0"
+}
+`
+	file := writeTestFile(t, dir, "main.tf", input)
+	broken := writeTestFile(t, dir, "broken.tf", "module \"vpc\" {\n")
+	config := Config{
+		TerraformVersion: ">= 1.9",
+		Providers:        []ProviderUpdate{{Name: "aws", Version: "~> 6.0"}},
+		Modules:          []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0", IgnoreModules: []string{"legacy_*"}}},
+	}
+
+	var events []Event
+	engine := &Engine{Options: Options{OutputFormat: "text"}, OnEvent: func(event Event) { events = append(events, event) }}
+	result, err := engine.Apply(context.Background(), config, Files{file, broken})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	wantChanges := []Change{
+		{Kind: TerraformVersionChange, File: file, Version: ">= 1.9"},
+		{Kind: ProviderChange, File: file, Name: "aws", Version: "~> 6.0", Previous: []string{"~> 5.0"}, Blocks: []string{"0/0/attribute/aws"}},
+		{Kind: ModuleChange, File: file, Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0", Previous: []string{"4.0.0"}, Blocks: []string{"1"}},
+	}
+	if !reflect.DeepEqual(result.Changes, wantChanges) {
+		t.Errorf("changes = %#v, want %#v", result.Changes, wantChanges)
+	}
+	if len(result.Skips) != 1 || !result.Skips[0].Filtered || result.Skips[0].File != file || !strings.Contains(result.Skips[0].Message, "'legacy_vpc'") {
+		t.Errorf("skips = %#v, want the ignored module", result.Skips)
+	}
+	if len(result.Diagnostics) != 3 {
+		t.Fatalf("diagnostics = %#v, want one per update of the broken file", result.Diagnostics)
+	}
+	for index, kind := range []ChangeKind{TerraformVersionChange, ProviderChange, ModuleChange} {
+		if diagnostic := result.Diagnostics[index]; diagnostic.Kind != kind || diagnostic.File != broken || diagnostic.Err == nil {
+			t.Errorf("diagnostic %d = %#v, want kind %d for %s", index, diagnostic, kind, broken)
+		}
+	}
+	if len(events) != len(result.Changes)+len(result.Skips)+len(result.Diagnostics) {
+		t.Errorf("OnEvent received %d events, want every event of the result", len(events))
+	}
+
+	want := strings.NewReplacer(`">= 1.5"`, `">= 1.9"`, `"~> 5.0"`, `"~> 6.0"`, `"4.0.0"`, `"5.0.0"`).Replace(input)
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestEngineApplyDryRunLeavesFilesUnchanged(t *testing.T) {
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	engine := &Engine{Options: Options{DryRun: true}}
+	result, err := engine.Apply(context.Background(), Config{Modules: []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}}}, Files{file})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Kind != ModuleChange {
+		t.Errorf("changes = %#v, want the module change reported", result.Changes)
+	}
+	if got := readTestFile(t, file); got != input {
+		t.Errorf("dry run modified main.tf:\n%s", got)
+	}
+}
+
+func TestEngineApplyReportsReplacedVersions(t *testing.T) {
+	input := `module "a" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.1.0"
+}
+
+module "b" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "c" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+
+module "d" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	engine := &Engine{Options: Options{DryRun: true, ForceAdd: true}}
+	result, err := engine.Apply(context.Background(), Config{Modules: []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}}}, Files{file})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(result.Changes) != 1 {
+		t.Fatalf("changes = %#v, want one module change", result.Changes)
+	}
+	if got, want := result.Changes[0].Previous, []string{"", "4.0.0", "4.1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Previous = %q, want %q", got, want)
+	}
+}
+
+func TestEngineApplyConfirmsEachChange(t *testing.T) {
+	input := `terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "vpc_prod" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+`
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	config := Config{
+		TerraformVersion: ">= 1.9",
+		Providers:        []ProviderUpdate{{Name: "aws", Version: "~> 6.0"}},
+		Modules:          []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}},
+	}
+
+	var proposals []Proposal
+	engine := &Engine{Options: Options{OutputFormat: "text", Confirm: func(proposal Proposal) bool {
+		proposals = append(proposals, proposal)
+		return proposal.Kind == ProviderChange || proposal.Block == "vpc_prod"
+	}}}
+	result, err := engine.Apply(context.Background(), config, Files{file})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	wantProposals := []Proposal{
+		{Kind: TerraformVersionChange, File: file, Current: ">= 1.5", Target: ">= 1.9", Attribute: "required_version", Before: ">= 1.5", After: ">= 1.9"},
+		{Kind: ProviderChange, File: file, Name: "aws", Current: "~> 5.0", Target: "~> 6.0", Attribute: "version", Before: "~> 5.0", After: "~> 6.0"},
+		{Kind: ModuleChange, File: file, Block: "vpc", Name: "terraform-aws-modules/vpc/aws", Current: "4.0.0", Target: "5.0.0", Attribute: "version", Before: "4.0.0", After: "5.0.0"},
+		{Kind: ModuleChange, File: file, Block: "vpc_prod", Name: "terraform-aws-modules/vpc/aws", Current: "4.0.0", Target: "5.0.0", Attribute: "version", Before: "4.0.0", After: "5.0.0"},
+	}
+	if !reflect.DeepEqual(proposals, wantProposals) {
+		t.Errorf("proposals = %#v, want %#v", proposals, wantProposals)
+	}
+	if len(result.Changes) != 2 || result.Changes[1].Kind != ModuleChange || !reflect.DeepEqual(result.Changes[1].Blocks, []string{"2"}) {
+		t.Errorf("changes = %#v, want the provider and vpc_prod", result.Changes)
+	}
+	if len(result.Skips) != 2 || !result.Skips[0].Filtered || !strings.Contains(result.Skips[1].Message, "Module 'vpc' in "+file+" (source: 'terraform-aws-modules/vpc/aws'): change to '5.0.0' declined") {
+		t.Errorf("skips = %#v, want the declined changes", result.Skips)
+	}
+	got := readTestFile(t, file)
+	for _, want := range []string{`required_version = ">= 1.5"`, `version = "~> 6.0"`, "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"", "version = \"5.0.0\""} {
+		if !strings.Contains(got, want) {
+			t.Errorf("main.tf missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestEngineApplyCallsBeforeWrite(t *testing.T) {
+	dir := t.TempDir()
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	written := writeTestFile(t, dir, "a.tf", input)
+	refused := writeTestFile(t, dir, "b.tf", input)
+	unchanged := writeTestFile(t, dir, "c.tf", "terraform {}\n")
+
+	var calls []string
+	engine := &Engine{Options: Options{BeforeWrite: func(filename string) error {
+		calls = append(calls, filename)
+		if filename == refused {
+			return errors.New("backup failed")
+		}
+		return nil
+	}}}
+	result, err := engine.Apply(context.Background(), Config{Modules: []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}}}, Files{written, refused, unchanged})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := []string{written, refused}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("BeforeWrite calls = %q, want %q", calls, want)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].File != written {
+		t.Errorf("changes = %#v, want only %s", result.Changes, written)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].File != refused || !strings.Contains(result.Diagnostics[0].Err.Error(), "backup failed") {
+		t.Errorf("diagnostics = %#v, want the refused write", result.Diagnostics)
+	}
+	if got := readTestFile(t, refused); got != input {
+		t.Errorf("b.tf written despite the BeforeWrite error:\n%s", got)
+	}
+}
+
+func TestEngineApplyRejectsInvalidConfig(t *testing.T) {
+	engine := &Engine{}
+	_, err := engine.Apply(context.Background(), Config{Modules: []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws"}}}, Files{"main.tf"})
+	if err == nil {
+		t.Fatal("Apply() error = nil, want the missing version reported")
+	}
+}
+
+func TestEngineApplyStopsWhenContextIsDone(t *testing.T) {
+	input := "terraform {\n  required_version = \">= 1.5\"\n}\n"
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine := &Engine{}
+	result, err := engine.Apply(ctx, Config{TerraformVersion: ">= 1.9"}, Files{file})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Apply() error = %v, want context.Canceled", err)
+	}
+	if len(result.Changes) != 0 || readTestFile(t, file) != input {
+		t.Errorf("cancelled run changed main.tf: %#v", result)
+	}
+}
+
+func TestEngineApplyReportsNonLiteralSourcesAndVersions(t *testing.T) {
+	dir := t.TempDir()
+	native := writeTestFile(t, dir, "main.tf", "module \"computed\" {\n  source  = \"${local.registry}/vpc/aws\"\n  version = \"4.0.0\"\n}\n")
+	jsonInput := `{
+  "terraform": {"required_version": 1, "required_providers": {"aws": {"source": "hashicorp/aws", "version": 6}}},
+  "module": {
+    "vpc": {"source": "terraform-aws-modules/vpc/aws", "version": 5},
+    "numbered": {"source": 5, "version": "4.0.0"},
+    "templated": {"source": "${local.registry}/vpc/aws", "version": "4.0.0"}
+  }
+}
+`
+	jsonFile := writeTestFile(t, dir, "main.tf.json", jsonInput)
+	config := Config{
+		TerraformVersion: ">= 1.9",
+		Providers:        []ProviderUpdate{{Name: "aws", Version: "~> 6.0"}},
+		Modules:          []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}},
+	}
+
+	engine := &Engine{Options: Options{OutputFormat: "text"}}
+	result, err := engine.Apply(context.Background(), config, Files{native, jsonFile})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("changes = %#v, want none", result.Changes)
+	}
+	wantSkips := []string{
+		"Terraform required_version in " + jsonFile + " has a non-literal version '1', skipping",
+		"Provider 'aws' in " + jsonFile + " has a non-literal version '6', skipping",
+		"Module 'computed' in " + native + " has a non-literal source '\"${local.registry}/vpc/aws\"', skipping",
+		"Module 'vpc' in " + jsonFile + " (source: 'terraform-aws-modules/vpc/aws') has a non-literal version '5', skipping",
+		"Module 'numbered' in " + jsonFile + " has a non-literal source '5', skipping",
+		"Module 'templated' in " + jsonFile + " has a non-literal source '\"${local.registry}/vpc/aws\"', skipping",
+	}
+	var skips []string
+	for _, skip := range result.Skips {
+		if skip.Filtered {
+			t.Errorf("skip %q is filtered, want it reported", skip.Message)
+		}
+		skips = append(skips, skip.Message)
+	}
+	if !reflect.DeepEqual(skips, wantSkips) {
+		t.Errorf("skips = %#v, want %#v", skips, wantSkips)
+	}
+	if got := readTestFile(t, jsonFile); got != jsonInput {
+		t.Errorf("main.tf.json changed:\n%s", got)
+	}
+}
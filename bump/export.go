@@ -0,0 +1,137 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// A starter config records the versions a repository uses today, so that a team can edit it to
+// describe the next rollout. Each module source, provider, and the Terraform required_version is
+// listed at its dominant version: the one most pins use, or the highest of those tied.
+
+// FindRequiredVersions reads a file and returns the literal required_version constraints of its
+// terraform blocks, without modifying it. In a Terragrunt configuration, these are the terraform
+// blocks its generate blocks write. In OpenTofu mode, .tofu and .tofu.json files have none, as
+// their required_version constrains OpenTofu rather than Terraform.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to read
+//   - opts: Terragrunt selects how .hcl files are read, and OpenTofu whether .tofu files count
+//
+// Returns:
+//   - []string: The constraints, in written order
+//   - error: Any error encountered while reading or parsing the file
+func FindRequiredVersions(filename string, opts Options) ([]string, error) {
+	if opts.OpenTofu && isOpenTofuFile(filename) {
+		return nil, nil
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var values []*pinValue
+	switch syntax := fileSyntax(filename, opts.Terragrunt); syntax {
+	case jsonSyntax:
+		root, err := parseJSONSyntax(src)
+		if err != nil {
+			return nil, err
+		}
+		_, bodies := jsonBlocks(root, "terraform", false)
+		for _, body := range bodies {
+			values = append(values, jsonPinValue(src, body.member("required_version")))
+		}
+	default:
+		file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+		}
+		body := file.Body.(*hclsyntax.Body)
+		if syntax == terragruntSyntax {
+			values = generatedRequiredVersions(filename, src, body)
+		} else {
+			values = requiredVersionValues(body)
+		}
+	}
+
+	var versions []string
+	for _, value := range values {
+		if _, ok := parseConstraint(literalText(value)); ok {
+			versions = append(versions, value.text)
+		}
+	}
+	return versions, nil
+}
+
+// requiredVersionValues returns the required_version values of the terraform blocks of a body.
+func requiredVersionValues(body *hclsyntax.Body) []*pinValue {
+	var values []*pinValue
+	for _, block := range body.Blocks {
+		if block.Type == "terraform" {
+			values = append(values, attributePinValue(block.Body.Attributes["required_version"]))
+		}
+	}
+	return values
+}
+
+// generatedRequiredVersions returns the required_version values of the configuration written by
+// the generate blocks of a Terragrunt configuration. Terragrunt's own terraform block has none.
+func generatedRequiredVersions(filename string, src []byte, body *hclsyntax.Body) []*pinValue {
+	var values []*pinValue
+	for _, block := range body.Blocks {
+		if block.Type != "generate" {
+			continue
+		}
+		if generated := generatedPinBody(filename, src, block.Body.Attributes["contents"]); generated != nil {
+			values = append(values, requiredVersionValues(generated)...)
+		}
+	}
+	return values
+}
+
+// StarterConfig returns a config that sets every module source and provider, and the Terraform
+// required_version, to its dominant version.
+//
+// Parameters:
+//   - pins: The module and provider pins of the files, from FindPinnedVersions
+//   - requiredVersions: The required_version constraints of the files, from FindRequiredVersions
+//
+// Returns:
+//   - Config: The modules and providers in the order each first appears
+func StarterConfig(pins []PinnedVersion, requiredVersions []string) Config {
+	config := Config{TerraformVersion: dominantVersion(requiredVersions)}
+	for _, group := range groupPins(pins, AlignOptions{}) {
+		versions := make([]string, len(group))
+		for index, pin := range group {
+			versions[index] = pin.Version
+		}
+		if group[0].Module {
+			config.Modules = append(config.Modules, ModuleUpdate{Source: group[0].Name, Version: dominantVersion(versions)})
+		} else {
+			config.Providers = append(config.Providers, ProviderUpdate{Name: group[0].Name, Version: dominantVersion(versions)})
+		}
+	}
+	return config
+}
+
+// dominantVersion returns the version listed most often, the highest of those tied, or an empty
+// string for no versions.
+func dominantVersion(versions []string) string {
+	counts := make(map[string]int)
+	dominant := ""
+	for _, version := range versions {
+		counts[version]++
+	}
+	for _, version := range versions {
+		switch {
+		case dominant == "" || counts[version] > counts[dominant]:
+			dominant = version
+		case counts[version] == counts[dominant] && compareConstraints(version, dominant) > 0:
+			dominant = version
+		}
+	}
+	return dominant
+}
@@ -0,0 +1,56 @@
+package bump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindRequiredVersions(t *testing.T) {
+	dir := t.TempDir()
+	native := writeTestFile(t, dir, "versions.tf", "terraform {\n  required_version = \">= 1.5\"\n}\n\nterraform {\n  required_version = var.terraform\n}\n")
+	json := writeTestFile(t, dir, "versions.tf.json", `{"terraform": [{"required_version": "~> 1.6.0"}, {"required_version": "${var.terraform}"}]}`)
+	terragrunt := writeTestFile(t, dir, "terragrunt.hcl", "terraform {\n  source = \"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0\"\n}\n\n"+
+		"generate \"versions\" {\n  path     = \"versions.tf\"\n  contents = <<EOF\nterraform {\n  required_version = \">= 1.7\"\n}\nEOF\n}\n")
+	tofu := writeTestFile(t, dir, "versions.tofu", "terraform {\n  required_version = \">= 1.8\"\n}\n")
+
+	for _, tt := range []struct {
+		filename string
+		opentofu bool
+		want     []string
+	}{
+		{filename: native, want: []string{">= 1.5"}},
+		{filename: json, want: []string{"~> 1.6.0"}},
+		{filename: terragrunt, want: []string{">= 1.7"}},
+		{filename: tofu, want: []string{">= 1.8"}},
+		{filename: tofu, opentofu: true},
+	} {
+		got, err := FindRequiredVersions(tt.filename, Options{Terragrunt: true, OpenTofu: tt.opentofu})
+		if err != nil {
+			t.Fatalf("FindRequiredVersions(%s) error = %v", tt.filename, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindRequiredVersions(%s) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestStarterConfig(t *testing.T) {
+	pins := []PinnedVersion{
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Version: "4.0.0"},
+		{Name: "aws", Version: "~> 5.0"},
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Version: "5.0.0"},
+		{Module: true, Name: "terraform-aws-modules/vpc/aws", Version: "4.0.0"},
+		{Name: "aws", Version: "~> 6.0"},
+	}
+	want := Config{
+		TerraformVersion: ">= 1.6",
+		Providers:        []ProviderUpdate{{Name: "aws", Version: "~> 6.0"}},
+		Modules:          []ModuleUpdate{{Source: "terraform-aws-modules/vpc/aws", Version: "4.0.0"}},
+	}
+	if got := StarterConfig(pins, []string{">= 1.5", ">= 1.6", ">= 1.6"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("StarterConfig() = %#v, want %#v", got, want)
+	}
+	if got := StarterConfig(nil, nil); !reflect.DeepEqual(got, Config{}) {
+		t.Errorf("StarterConfig() of no pins = %#v, want an empty config", got)
+	}
+}
@@ -0,0 +1,133 @@
+package bump
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UpdateGroup names a set of entries from a config's modules and providers lists so that they can
+// be applied, and reported, on their own; for example, one pull request per group.
+//
+// Example YAML:
+//
+//	groups:
+//	  - name: "aws-core"
+//	    modules:
+//	      - "terraform-aws-modules/vpc/aws"
+//	      - "terraform-aws-modules/eks/aws"
+//	    providers:
+//	      - "aws"
+type UpdateGroup struct {
+	Name      string   `yaml:"name"`      // Group name (e.g., "aws-core")
+	Modules   []string `yaml:"modules"`   // Optional: sources of module entries in the group
+	Providers []string `yaml:"providers"` // Optional: names of provider entries in the group
+}
+
+// Group returns the config that applies only the module and provider entries of the named group.
+// The default pre-release policy is kept; terraform_version, migrations, and custom updates are
+// left out, as no group lists them.
+//
+// Parameters:
+//   - name: The name of a group of the config
+//
+// Returns:
+//   - Config: The entries of the group
+//   - error: The config has no group with the name
+func (config *Config) Group(name string) (Config, error) {
+	index := slices.IndexFunc(config.Groups, func(group UpdateGroup) bool { return group.Name == name })
+	if index < 0 {
+		return Config{}, fmt.Errorf("config has no group %q", name)
+	}
+	group := config.Groups[index]
+	return Config{
+		Prerelease: config.Prerelease,
+		Providers:  filterEntries(config.Providers, func(provider ProviderUpdate) bool { return slices.Contains(group.Providers, provider.Name) }),
+		Modules:    filterEntries(config.Modules, func(module ModuleUpdate) bool { return slices.Contains(group.Modules, module.Source) }),
+	}, nil
+}
+
+// Ungrouped returns the config without the entries of its groups: terraform_version, migrations,
+// custom updates, and the module and provider entries that no group lists.
+func (config *Config) Ungrouped() Config {
+	ungrouped := config.clone()
+	ungrouped.Groups = nil
+	ungrouped.Providers = filterEntries(config.Providers, func(provider ProviderUpdate) bool { return config.groupOf(provider.Name, true) == "" })
+	ungrouped.Modules = filterEntries(config.Modules, func(module ModuleUpdate) bool { return config.groupOf(module.Source, false) == "" })
+	return ungrouped
+}
+
+// groupOf returns the name of the group listing a provider name or module source, or "" when none
+// does.
+func (config *Config) groupOf(entry string, provider bool) string {
+	for _, group := range config.Groups {
+		entries := group.Modules
+		if provider {
+			entries = group.Providers
+		}
+		if slices.Contains(entries, entry) {
+			return group.Name
+		}
+	}
+	return ""
+}
+
+// filterEntries returns the entries for which keep returns true, in their order.
+func filterEntries[E any](entries []E, keep func(E) bool) []E {
+	var kept []E
+	for _, entry := range entries {
+		if keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// sanitizeGroups trims the groups of a config and validates that each has a unique name and lists
+// entries of the modules and providers lists, each in one group only.
+func (config *Config) sanitizeGroups() error {
+	names := make(map[string]bool)
+	for i := range config.Groups {
+		group := &config.Groups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		group.Modules = trimNonEmptyStrings(group.Modules)
+		group.Providers = trimNonEmptyStrings(group.Providers)
+
+		if group.Name == "" {
+			return fmt.Errorf("group at index %d is missing 'name' field", i)
+		}
+		if names[group.Name] {
+			return fmt.Errorf("group at index %d repeats the name %q", i, group.Name)
+		}
+		names[group.Name] = true
+		if len(group.Modules) == 0 && len(group.Providers) == 0 {
+			return fmt.Errorf("group %q lists no modules or providers", group.Name)
+		}
+		if err := config.checkGroupEntries(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGroupEntries validates that a group lists only entries of the config, which no earlier
+// group lists.
+func (config *Config) checkGroupEntries(group *UpdateGroup) error {
+	for _, source := range group.Modules {
+		if !slices.ContainsFunc(config.Modules, func(module ModuleUpdate) bool { return module.Source == source }) {
+			return fmt.Errorf("group %q lists module %q, which is not in the modules list", group.Name, source)
+		}
+		if other := config.groupOf(source, false); other != group.Name {
+			return fmt.Errorf("group %q lists module %q, which is already in group %q", group.Name, source, other)
+		}
+	}
+	for _, name := range group.Providers {
+		if !slices.ContainsFunc(config.Providers, func(provider ProviderUpdate) bool { return provider.Name == name }) {
+			return fmt.Errorf("group %q lists provider %q, which is not in the providers list", group.Name, name)
+		}
+		if other := config.groupOf(name, true); other != group.Name {
+			return fmt.Errorf("group %q lists provider %q, which is already in group %q", group.Name, name, other)
+		}
+	}
+	return nil
+}
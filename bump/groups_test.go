@@ -0,0 +1,57 @@
+package bump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigGroupSelectsEntries(t *testing.T) {
+	config, err := LoadConfig(writeTestFile(t, t.TempDir(), "config.yml", `terraform_version: ">= 1.9"
+prerelease: exclude
+providers:
+  - name: aws
+    version: "~> 6.0"
+  - name: random
+    version: "~> 3.6"
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: "5.0.0"
+  - source: terraform-aws-modules/eks/aws
+    version: "20.0.0"
+  - source: terraform-aws-modules/vpc/aws
+    version: "4.9.0"
+    from: "4.0.0"
+groups:
+  - name: aws-core
+    modules: [" terraform-aws-modules/vpc/aws "]
+    providers: [aws]
+  - name: eks
+    modules: [terraform-aws-modules/eks/aws]
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	core, err := config.Group("aws-core")
+	if err != nil {
+		t.Fatalf("Group() error = %v", err)
+	}
+	want := Config{
+		Prerelease: "exclude",
+		Providers:  []ProviderUpdate{config.Providers[0]},
+		Modules:    []ModuleUpdate{config.Modules[0], config.Modules[2]},
+	}
+	if !reflect.DeepEqual(core, want) {
+		t.Errorf("Group(aws-core) = %#v, want %#v", core, want)
+	}
+
+	ungrouped := config.Ungrouped()
+	if ungrouped.TerraformVersion != ">= 1.9" || !reflect.DeepEqual(ungrouped.Providers, []ProviderUpdate{config.Providers[1]}) ||
+		len(ungrouped.Modules) != 0 || ungrouped.Groups != nil {
+		t.Errorf("Ungrouped() = %#v, want terraform_version and the random provider", ungrouped)
+	}
+
+	if _, err := config.Group("missing"); err == nil || err.Error() != `config has no group "missing"` {
+		t.Errorf("Group(missing) error = %v", err)
+	}
+}
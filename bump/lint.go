@@ -0,0 +1,210 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Lint mode reads the selected files without modifying them and reports pinning problems, such
+// as a registry module without a version or a provider constraint with no upper bound:
+//
+//	main.tf:1:1: error [module-missing-version] Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') has no version
+//	versions.tf:5:17: warning [open-ended-constraint] Provider 'aws' version '>= 5.0' has no upper bound
+//
+// Every rule has a default severity that -lint-rule can change, or turn off. Findings with error
+// severity make the command exit non-zero. Values that are not literal strings are not checked.
+
+// LintSeverity is how a lint rule's findings are reported.
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"   // Findings make the command exit non-zero
+	LintWarning LintSeverity = "warning" // Findings are reported only
+	LintOff     LintSeverity = "off"     // The rule is not run
+)
+
+const (
+	ruleModuleMissingVersion   = "module-missing-version"
+	ruleProviderMissingVersion = "provider-missing-version"
+	ruleOpenEndedConstraint    = "open-ended-constraint"
+	ruleGitMissingRef          = "git-missing-ref"
+)
+
+// lintRule is a check that lint mode can run, with the severity it has unless configured.
+type lintRule struct {
+	name     string
+	severity LintSeverity
+}
+
+// lintRules lists every rule in the order they are documented.
+var lintRules = []lintRule{
+	{name: ruleModuleMissingVersion, severity: LintError},
+	{name: ruleProviderMissingVersion, severity: LintError},
+	{name: ruleOpenEndedConstraint, severity: LintWarning},
+	{name: ruleGitMissingRef, severity: LintError},
+}
+
+// LintSeverities applies -lint-rule settings, written as <rule>=<severity>, to the default
+// severity of every rule. A later setting for the same rule replaces an earlier one.
+func LintSeverities(settings []string) (map[string]LintSeverity, error) {
+	severities := make(map[string]LintSeverity, len(lintRules))
+	names := make([]string, 0, len(lintRules))
+	for _, rule := range lintRules {
+		severities[rule.name] = rule.severity
+		names = append(names, rule.name)
+	}
+
+	for _, setting := range settings {
+		name, severity, ok := strings.Cut(setting, "=")
+		name, severity = strings.TrimSpace(name), strings.TrimSpace(severity)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("lint rule setting %q must be written as <rule>=<severity>", setting)
+		}
+		if _, known := severities[name]; !known {
+			return nil, fmt.Errorf("unknown lint rule %q (valid rules: %s)", name, strings.Join(names, ", "))
+		}
+		switch LintSeverity(severity) {
+		case LintError, LintWarning, LintOff:
+			severities[name] = LintSeverity(severity)
+		default:
+			return nil, fmt.Errorf("invalid severity %q for lint rule %q: must be 'error', 'warning', or 'off'", severity, name)
+		}
+	}
+	return severities, nil
+}
+
+// LintFinding is one problem reported by a lint rule.
+type LintFinding struct {
+	Pos      hcl.Pos
+	Rule     string
+	Severity LintSeverity
+	Message  string
+}
+
+// linter collects the findings for one file.
+type linter struct {
+	filename     string
+	severities   map[string]LintSeverity
+	terragrunt   bool
+	outputFormat string
+	findings     []LintFinding
+}
+
+// LintFile reads a file and checks its module blocks and required_providers entries against the
+// lint rules, without modifying it.
+//
+// Parameters:
+//   - filename: Path to the Terraform or Terragrunt file to check
+//   - severities: The severity of every rule, from LintSeverities, or nil for the defaults
+//   - opts: Terragrunt selects how .hcl files are read; OutputFormat the quoting of messages
+//
+// Returns:
+//   - []LintFinding: The findings of rules that are not turned off, in written order
+//   - error: Any error encountered while reading or parsing the file
+func LintFile(filename string, severities map[string]LintSeverity, opts Options) ([]LintFinding, error) {
+	if severities == nil {
+		severities, _ = LintSeverities(nil)
+	}
+	l := &linter{filename: filename, severities: severities, terragrunt: opts.Terragrunt, outputFormat: opts.OutputFormat}
+	if err := l.lintFile(); err != nil {
+		return nil, err
+	}
+	return l.findings, nil
+}
+
+// lintFile reads and checks the linter's file, sorting its findings by position.
+func (l *linter) lintFile() error {
+	src, err := os.ReadFile(l.filename)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := scanPins(l.filename, src, l.terragrunt, l); err != nil {
+		return err
+	}
+	slices.SortStableFunc(l.findings, func(a, b LintFinding) int {
+		return a.Pos.Byte - b.Pos.Byte
+	})
+	return nil
+}
+
+// visitModule applies the module rules to one module block.
+func (l *linter) visitModule(name string, pos hcl.Pos, source string, version *pinValue) {
+	subject := fmt.Sprintf("Module %s", quote(name, l.outputFormat))
+	l.checkGitRef(pos, subject, source)
+	if version == nil {
+		if isRegistryModule(source) {
+			l.add(pos, ruleModuleMissingVersion, fmt.Sprintf("%s (source: %s) has no version", subject, quote(source, l.outputFormat)))
+		}
+		return
+	}
+	l.checkConstraint(subject, version)
+}
+
+// visitProvider applies the provider rules to one required_providers entry.
+func (l *linter) visitProvider(name string, pos hcl.Pos, _ string, version *pinValue) {
+	subject := fmt.Sprintf("Provider %s", quote(name, l.outputFormat))
+	if version == nil {
+		l.add(pos, ruleProviderMissingVersion, subject+" has no version constraint")
+		return
+	}
+	l.checkConstraint(subject, version)
+}
+
+// visitTerragruntSource checks the terraform.source address of a Terragrunt configuration.
+func (l *linter) visitTerragruntSource(name string, source *pinValue) {
+	subject := fmt.Sprintf("Module %s", quote(name, l.outputFormat))
+	parsed := parseTerragruntSource(source.text)
+	if _, ok := parsed.version(); parsed.registry && !ok {
+		l.add(source.pos, ruleModuleMissingVersion, fmt.Sprintf("%s (source: %s) has no ?version=", subject, quote(source.text, l.outputFormat)))
+	}
+	l.checkGitRef(source.pos, subject, source.text)
+}
+
+// checkConstraint reports a literal version constraint that permits every later version.
+// Values that are not Terraform constraints are left to the update modes to report.
+func (l *linter) checkConstraint(subject string, version *pinValue) {
+	if !version.literal {
+		return
+	}
+	clauses, ok := parseConstraint(version.text)
+	if !ok || constraintRange(clauses).upper != nil {
+		return
+	}
+	l.add(version.pos, ruleOpenEndedConstraint, fmt.Sprintf("%s version %s has no upper bound", subject, quote(version.text, l.outputFormat)))
+}
+
+// checkGitRef reports a Git source without a ref parameter, which follows the default branch.
+func (l *linter) checkGitRef(pos hcl.Pos, subject, source string) {
+	if !isGitSource(source) {
+		return
+	}
+	if _, ok := parseTerragruntSource(source).version(); ok {
+		return
+	}
+	l.add(pos, ruleGitMissingRef, fmt.Sprintf("%s source %s has no ?ref=", subject, quote(source, l.outputFormat)))
+}
+
+// add records a finding unless its rule is turned off.
+func (l *linter) add(pos hcl.Pos, rule, message string) {
+	severity := l.severities[rule]
+	if severity == LintOff {
+		return
+	}
+	l.findings = append(l.findings, LintFinding{Pos: pos, Rule: rule, Severity: severity, Message: message})
+}
+
+// isGitSource reports whether a module source is fetched with Git: an explicit git:: source, or
+// one of the GitHub, Bitbucket, or SSH shorthands that Terraform fetches with Git.
+func isGitSource(source string) bool {
+	for _, prefix := range []string{"git::", "github.com/", "bitbucket.org/", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package bump
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLintFileHCL(t *testing.T) {
+	input := `module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+module "eks" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = ">= 19.0"
+}
+
+module "pinned" {
+  source  = "terraform-aws-modules/s3-bucket/aws"
+  version = "~> 4.1"
+}
+
+module "computed" {
+  source  = "terraform-aws-modules/rds/aws"
+  version = var.rds_version
+}
+
+module "git" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git//modules/subnets"
+}
+
+module "git_pinned" {
+  source = "github.com/example/terraform-aws-vpc?ref=v1.2.0"
+}
+
+module "local" {
+  source = "./modules/network"
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0, != 5.1.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+    null = "~> 3.2"
+  }
+}
+`
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	severities, err := LintSeverities(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := &linter{filename: file, severities: severities, outputFormat: "text"}
+	if err := l.lintFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"1:1 error module-missing-version Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') has no version",
+		"7:13 warning open-ended-constraint Module 'eks' version '>= 19.0' has no upper bound",
+		"20:1 error git-missing-ref Module 'git' source 'git::https://github.com/example/terraform-aws-vpc.git//modules/subnets' has no ?ref=",
+		"36:17 warning open-ended-constraint Provider 'aws' version '>= 5.0, != 5.1.0' has no upper bound",
+		"38:5 error provider-missing-version Provider 'random' has no version constraint",
+	}
+	var got []string
+	for _, finding := range l.findings {
+		got = append(got, fmt.Sprintf("%d:%d %s %s %s", finding.Pos.Line, finding.Pos.Column, finding.Severity, finding.Rule, finding.Message))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings mismatch:\n--- got ---\n%s\n--- want ---\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if readTestFile(t, file) != input {
+		t.Error("lint modified the file")
+	}
+}
+
+func TestLintFileJSONAndTerragrunt(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := writeTestFile(t, dir, "main.tf.json", `{
+  "module": {
+    "vpc": {"source": "terraform-aws-modules/vpc/aws"},
+    "templated": {"source": "terraform-aws-modules/eks/aws", "version": "${var.eks_version}"}
+  },
+  "terraform": {
+    "required_providers": {
+      "aws": {"source": "hashicorp/aws", "version": "> 5.0"}
+    }
+  }
+}
+`)
+	terragruntFile := writeTestFile(t, dir, "terragrunt.hcl", `terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws"
+}
+`)
+
+	severities, err := LintSeverities([]string{"open-ended-constraint=error", "module-missing-version=warning"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		file string
+		want []LintFinding
+	}{
+		{jsonFile, []LintFinding{
+			{Rule: ruleModuleMissingVersion, Severity: LintWarning, Message: "Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') has no version"},
+			{Rule: ruleOpenEndedConstraint, Severity: LintError, Message: "Provider 'aws' version '> 5.0' has no upper bound"},
+		}},
+		{terragruntFile, []LintFinding{
+			{Rule: ruleModuleMissingVersion, Severity: LintWarning, Message: "Module '" + terragruntModuleName(terragruntFile) + "' (source: 'tfr:///terraform-aws-modules/vpc/aws') has no ?version="},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := &linter{filename: tt.file, severities: severities, terragrunt: true, outputFormat: "text"}
+		if err := l.lintFile(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.file, err)
+		}
+		if len(l.findings) != len(tt.want) {
+			t.Fatalf("%s: findings = %#v, want %d", tt.file, l.findings, len(tt.want))
+		}
+		for index, finding := range l.findings {
+			finding.Pos.Line, finding.Pos.Column, finding.Pos.Byte = 0, 0, 0
+			if finding != tt.want[index] {
+				t.Errorf("%s: finding %d = %#v, want %#v", tt.file, index, finding, tt.want[index])
+			}
+		}
+	}
+}
+
+func TestLintFileTerragruntGenerateBlocks(t *testing.T) {
+	dir := t.TempDir()
+	content := `generate "provider" {
+  path     = "versions.tf"
+  contents = <<-EOF
+    terraform {
+      required_providers {
+        aws = {
+          source = "hashicorp/aws"
+        }
+        random = {
+          source  = "hashicorp/random"
+          version = "~> 3.6"
+        }
+      }
+    }
+  EOF
+}
+
+generate "templated" {
+  path     = "backend.tf"
+  contents = <<EOF
+terraform {
+  required_providers {
+    google = { source = "hashicorp/google", version = "${local.google_version}" }
+  }
+}
+EOF
+}
+`
+	file := writeTestFile(t, dir, "terragrunt.hcl", content)
+
+	severities, err := LintSeverities(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := &linter{filename: file, severities: severities, terragrunt: true, outputFormat: "text"}
+	if err := l.lintFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(l.findings) != 1 {
+		t.Fatalf("findings = %#v, want 1", l.findings)
+	}
+	finding := l.findings[0]
+	if finding.Pos.Line != 6 || finding.Pos.Column != 9 || !strings.HasPrefix(content[finding.Pos.Byte:], "aws = {") {
+		t.Errorf("position = %#v, want the aws entry at 6:9", finding.Pos)
+	}
+	finding.Pos.Line, finding.Pos.Column, finding.Pos.Byte = 0, 0, 0
+	want := LintFinding{Rule: ruleProviderMissingVersion, Severity: LintError, Message: "Provider 'aws' has no version constraint"}
+	if finding != want {
+		t.Errorf("finding = %#v, want %#v", finding, want)
+	}
+}
+
+func TestLintSeveritiesRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		setting string
+		want    string
+	}{
+		{"git-missing-ref", `lint rule setting "git-missing-ref" must be written as <rule>=<severity>`},
+		{"unpinned=error", `unknown lint rule "unpinned" (valid rules: module-missing-version, provider-missing-version, open-ended-constraint, git-missing-ref)`},
+		{"git-missing-ref=fatal", `invalid severity "fatal" for lint rule "git-missing-ref": must be 'error', 'warning', or 'off'`},
+	}
+	for _, tt := range tests {
+		if _, err := LintSeverities([]string{tt.setting}); err == nil || err.Error() != tt.want {
+			t.Errorf("LintSeverities(%q) error = %v, want %q", tt.setting, err, tt.want)
+		}
+	}
+}
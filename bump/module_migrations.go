@@ -0,0 +1,197 @@
+package bump
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// A module migration replaces a module's source and brings its version argument in line with the
+// new source. Terraform accepts version only for registry modules, so moving to a registry source
+// sets version, while moving to a Git or other source removes it:
+//
+//	module "vpc" {
+//	  source = "git::https://github.com/example/terraform-aws-vpc.git?ref=v4.2.0"
+//	}
+//
+// becomes, with to_source "app.terraform.io/example/vpc/aws" and version "5.0.0":
+//
+//	module "vpc" {
+//	  source  = "app.terraform.io/example/vpc/aws"
+//	  version = "5.0.0"
+//	}
+//
+// Terragrunt terraform.source addresses are not migrated.
+
+// migrationOptions holds one migration entry together with the options of the run.
+type migrationOptions struct {
+	filename       string
+	fromSource     string
+	toSource       string
+	version        string // empty when the new source is not a registry module
+	ignorePatterns []string
+	terragrunt     bool
+	opentofu       bool
+	outputFormat   string
+	events         eventSink
+	beforeWrite    func(string) error
+}
+
+// migrationOptions combines a migration entry with the options of the run.
+func (r *run) migrationOptions(migration *ModuleMigration) migrationOptions {
+	return migrationOptions{
+		fromSource:     migration.FromSource,
+		toSource:       migration.ToSource,
+		version:        migration.Version,
+		ignorePatterns: migration.IgnoreModules,
+		terragrunt:     r.Terragrunt,
+		opentofu:       r.OpenTofu,
+		outputFormat:   r.OutputFormat,
+		beforeWrite:    r.BeforeWrite,
+	}
+}
+
+// migrateModuleSourceWithCount migrates the module blocks of a file whose source matches the
+// migration, preserving the file's permissions when it is written.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to process
+//   - opts: The migration and behaviour switches to apply
+//   - dryRun: If true, report what would change without modifying the file
+//
+// Returns:
+//   - changedBlocks: indexes of the migrated module blocks
+//   - error: Any error encountered during file reading, parsing, or writing
+func migrateModuleSourceWithCount(filename string, opts migrationOptions, dryRun bool) (changedBlocks []int, err error) {
+	opts.filename = filename
+	_, err = rewriteFile(filename, dryRun, opts.beforeWrite, func(src []byte) (output []byte, updated bool, err error) {
+		switch fileSyntax(filename, opts.terragrunt) {
+		case jsonSyntax:
+			output, changedBlocks, err = migrateModuleSourceJSON(src, &opts)
+		case terragruntSyntax:
+			return nil, false, nil
+		default:
+			output, changedBlocks, err = migrateModuleSourceHCL(src, &opts)
+		}
+		return output, len(changedBlocks) > 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changedBlocks, nil
+}
+
+func migrateModuleSourceHCL(src []byte, opts *migrationOptions) (output []byte, changedBlocks []int, err error) {
+	file, diags := hclwrite.ParseConfig(src, opts.filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+
+	for blockIndex, block := range file.Body().Blocks() {
+		if block.Type() == "module" && migrateModuleBlock(block, opts) {
+			changedBlocks = append(changedBlocks, blockIndex)
+		}
+	}
+	return hclwrite.Format(file.Bytes()), changedBlocks, nil
+}
+
+// migrateModuleBlock rewrites one module block when its source matches, returning true if it did.
+func migrateModuleBlock(block *hclwrite.Block, opts *migrationOptions) bool {
+	body := block.Body()
+	sourceAttr := body.GetAttribute("source")
+	if sourceAttr == nil || !isLiteralString(sourceAttr) || !opts.matchesSource(attributeStringValue(sourceAttr)) {
+		return false
+	}
+	moduleName := moduleBlockName(block)
+	if !opts.permits(moduleName) {
+		return false
+	}
+	if versionAttr := body.GetAttribute("version"); versionAttr != nil && !isLiteralString(versionAttr) {
+		reportComputedVersion(opts.events, opts.subject(moduleName), strings.TrimSpace(string(versionAttr.Expr().BuildTokens(nil).Bytes())), "", opts.outputFormat)
+		return false
+	}
+
+	body.SetAttributeValue("source", cty.StringVal(opts.toSource))
+	if opts.version != "" {
+		body.SetAttributeValue("version", cty.StringVal(opts.version))
+	} else {
+		body.RemoveAttribute("version")
+	}
+	return true
+}
+
+// migrateModuleSourceJSON migrates matching module blocks in a JSON syntax file.
+func migrateModuleSourceJSON(src []byte, opts *migrationOptions) (output []byte, changedBlocks []int, err error) {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var edits []jsonEdit
+	labels, bodies := jsonBlocks(root, "module", true)
+	for blockIndex, body := range bodies {
+		source := body.member("source")
+		if source == nil || source.kind != jsonString || !opts.matchesSource(source.text) || !opts.permits(labels[blockIndex]) {
+			continue
+		}
+		version := body.member("version")
+		if version != nil && isJSONComputed(opts.events, opts.subject(labels[blockIndex]), src, version, opts.outputFormat) {
+			continue
+		}
+
+		edits = append(edits, replaceJSONString(source, opts.toSource))
+		switch {
+		case opts.version != "" && version != nil:
+			edits = append(edits, replaceJSONString(version, opts.version))
+		case opts.version != "":
+			edits = append(edits, insertJSONMember(src, body, "version", opts.version))
+		case version != nil:
+			edits = append(edits, removeJSONMember(body, "version"))
+		}
+		changedBlocks = append(changedBlocks, blockIndex)
+	}
+	return applyJSONEdits(src, edits), changedBlocks, nil
+}
+
+// matchesSource reports whether a module source is covered by the migration. A from_source
+// without a query string also matches the same Git or other non-registry source with any ref
+// parameter; sources with other query parameters must be named exactly.
+func (opts *migrationOptions) matchesSource(source string) bool {
+	if sameModuleSource(source, opts.fromSource, opts.opentofu) {
+		return true
+	}
+	if strings.Contains(opts.fromSource, "?") || isRegistryModule(opts.fromSource) {
+		return false
+	}
+	return parseTerragruntSource(source).withoutVersion() == opts.fromSource
+}
+
+// permits applies the migration's module-name exclusions.
+func (opts *migrationOptions) permits(moduleName string) bool {
+	if !shouldIgnoreModule(moduleName, opts.ignorePatterns) {
+		return true
+	}
+	opts.events.filtered("Skipped module %s in %s (matches ignore pattern)", quote(moduleName, opts.outputFormat), opts.filename)
+	return false
+}
+
+// subject describes a module block in the current file for skips.
+func (opts *migrationOptions) subject(moduleName string) string {
+	return fmt.Sprintf("Module %s in %s (source: %s)", quote(moduleName, opts.outputFormat), opts.filename, quote(opts.fromSource, opts.outputFormat))
+}
+
+// withoutVersion returns the source address with its version parameter removed.
+func (source terragruntSource) withoutVersion() string {
+	params := source.params
+	if source.versionIndex >= 0 {
+		params = slices.Delete(slices.Clone(params), source.versionIndex, source.versionIndex+1)
+	}
+	if len(params) == 0 {
+		return source.base
+	}
+	return source.base + "?" + strings.Join(params, "&")
+}
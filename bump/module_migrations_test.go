@@ -0,0 +1,152 @@
+package bump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateModuleSourceHCL(t *testing.T) {
+	input := `module "git_v4" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git?ref=v4.2.0"
+  name   = "main"
+}
+
+module "git_depth" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git?depth=1&ref=v4.3.0"
+}
+
+module "legacy" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git?ref=v3.0.0"
+}
+
+module "other_repo" {
+  source = "git::https://github.com/example/terraform-aws-eks.git?ref=v1.0.0"
+}
+`
+	want := `module "git_v4" {
+  source  = "app.terraform.io/example/vpc/aws"
+  name    = "main"
+  version = "5.0.0"
+}
+
+module "git_depth" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git?depth=1&ref=v4.3.0"
+}
+
+module "legacy" {
+  source = "git::https://github.com/example/terraform-aws-vpc.git?ref=v3.0.0"
+}
+
+module "other_repo" {
+  source = "git::https://github.com/example/terraform-aws-eks.git?ref=v1.0.0"
+}
+`
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	opts := migrationOptions{
+		fromSource:     "git::https://github.com/example/terraform-aws-vpc.git",
+		toSource:       "app.terraform.io/example/vpc/aws",
+		version:        "5.0.0",
+		ignorePatterns: []string{"legacy"},
+		outputFormat:   "text",
+	}
+
+	changed, err := migrateModuleSourceWithCount(file, opts, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []int{0}) {
+		t.Errorf("changed blocks = %v, want [0]", changed)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestMigrateModuleSourceRemovesVersion(t *testing.T) {
+	input := `module "eks" {
+  source  = "community/eks/aws"
+  version = "~> 19.0"
+}
+
+module "computed" {
+  source  = "registry.terraform.io/community/eks/aws"
+  version = var.eks_version
+}
+`
+	want := `module "eks" {
+  source = "git::https://github.com/example/terraform-aws-eks.git?ref=v20.1.0"
+}
+
+module "computed" {
+  source  = "registry.terraform.io/community/eks/aws"
+  version = var.eks_version
+}
+`
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	opts := migrationOptions{fromSource: "community/eks/aws", toSource: "git::https://github.com/example/terraform-aws-eks.git?ref=v20.1.0", outputFormat: "text", events: printEvents(false)}
+
+	var changed []int
+	stderr := captureStderr(t, func() {
+		var err error
+		if changed, err = migrateModuleSourceWithCount(file, opts, false); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	if !reflect.DeepEqual(changed, []int{0}) {
+		t.Errorf("changed blocks = %v, want [0]", changed)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	if !strings.Contains(stderr, "Module 'computed'") || !strings.Contains(stderr, "non-literal version 'var.eks_version'") {
+		t.Errorf("stderr = %q, want the computed version reported", stderr)
+	}
+}
+
+func TestMigrateModuleSourceJSON(t *testing.T) {
+	input := `{
+  "module": {
+    "eks": {
+      "version": "~> 19.0",
+      "source": "community/eks/aws"
+    },
+    "vpc": {
+      "source": "git::https://github.com/example/terraform-aws-vpc.git?ref=v4.2.0"
+    }
+  }
+}
+`
+	tests := []struct {
+		name string
+		opts migrationOptions
+		want string
+	}{
+		{
+			name: "remove version",
+			opts: migrationOptions{fromSource: "community/eks/aws", toSource: "git::https://github.com/example/terraform-aws-eks.git?ref=v20.1.0", events: printEvents(false)},
+			want: strings.Replace(input, `"version": "~> 19.0",
+      "source": "community/eks/aws"`, `"source": "git::https://github.com/example/terraform-aws-eks.git?ref=v20.1.0"`, 1),
+		},
+		{
+			name: "add version",
+			opts: migrationOptions{fromSource: "git::https://github.com/example/terraform-aws-vpc.git", toSource: "app.terraform.io/example/vpc/aws", version: "5.0.0", events: printEvents(false)},
+			want: strings.Replace(input, `"source": "git::https://github.com/example/terraform-aws-vpc.git?ref=v4.2.0"`, `"source": "app.terraform.io/example/vpc/aws",
+      "version": "5.0.0"`, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, t.TempDir(), "main.tf.json", input)
+			tt.opts.outputFormat = "text"
+			changed, err := migrateModuleSourceWithCount(file, tt.opts, false)
+			if err != nil || len(changed) != 1 {
+				t.Fatalf("changed=%v err=%v", changed, err)
+			}
+			if got := readTestFile(t, file); got != tt.want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package bump
 
 import (
 	"fmt"
@@ -308,3 +308,70 @@ func TestUpdateModuleVersionErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateModuleVersionPreservesConstraintOperator(t *testing.T) {
+	input := "module \"root\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 4.2\"\n}\n\nmodule \"shared\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \">= 4.0, < 5.0\"\n}\n\nmodule \"pinned\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.2.0\"\n}\n"
+	want := "module \"root\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 5.1\"\n}\n\nmodule \"shared\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \">= 5.1, < 6.0\"\n}\n\nmodule \"pinned\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.1.0\"\n}\n"
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.1.0", preserveOperator: true, outputFormat: "text", events: printEvents(false)}
+	updated, changedBlocks, err := updateModuleVersionWithCount(file, opts, false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if len(changedBlocks) != 3 {
+		t.Errorf("changed blocks = %v, want all three", changedBlocks)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateModuleVersionPreservedOperatorAlreadyCurrent(t *testing.T) {
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 5.1\"\n}\n"
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.1.0", preserveOperator: true, outputFormat: "text", events: printEvents(false)}
+	updated, changedBlocks, err := updateModuleVersionWithCount(file, opts, false)
+	if err != nil || !updated || len(changedBlocks) != 0 {
+		t.Fatalf("updated=%v changed=%v err=%v, want an unchanged applied update", updated, changedBlocks, err)
+	}
+	if got := readTestFile(t, file); got != input {
+		t.Errorf("content = %q, want %q", got, input)
+	}
+}
+
+func TestUpdateModuleVersionRefusesDowngrade(t *testing.T) {
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.2.0\"\n}\n"
+	want := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.0.0\"\n}\n"
+
+	t.Run("skipped by default", func(t *testing.T) {
+		file := writeTestFile(t, t.TempDir(), "main.tf", input)
+		var updated bool
+		var err error
+		stderr := captureStderr(t, func() {
+			updated, err = updateModuleVersion(file, "terraform-aws-modules/vpc/aws", "5.0.0", nil, nil, nil, false, false, false, "text")
+		})
+		if err != nil || updated {
+			t.Fatalf("updated=%v err=%v, want a skipped downgrade", updated, err)
+		}
+		if !strings.Contains(stderr, "Module 'vpc' in "+file+" (source: 'terraform-aws-modules/vpc/aws') is at version '5.2.0', which is newer than '5.0.0'; skipping downgrade") {
+			t.Errorf("stderr = %q", stderr)
+		}
+		if got := readTestFile(t, file); got != input {
+			t.Errorf("content = %q, want %q", got, input)
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		file := writeTestFile(t, t.TempDir(), "main.tf", input)
+		opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", allowDowngrade: true, outputFormat: "text", events: printEvents(false)}
+		updated, changedBlocks, err := updateModuleVersionWithCount(file, opts, false)
+		if err != nil || !updated || len(changedBlocks) != 1 {
+			t.Fatalf("updated=%v changed=%v err=%v", updated, changedBlocks, err)
+		}
+		if got := readTestFile(t, file); got != want {
+			t.Errorf("content = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,506 @@
+package bump
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// containsVersion checks if a version string is present in a slice of versions.
+// This helper function reduces code duplication when checking version filters.
+//
+// Parameters:
+//   - versions: List of version strings to search through
+//   - version: The version string to search for
+//
+// Returns:
+//   - bool: true if the version is found in the list, false otherwise
+func containsVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// containsVersion checks if a version is in a list of versions. The ref of a Terragrunt source is
+// compared without a leading "v", which git tags conventionally carry and configured versions may
+// omit.
+func (opts *moduleUpdateOptions) containsVersion(versions []string, version string) bool {
+	if opts.terragruntSource == nil {
+		return containsVersion(versions, version)
+	}
+	return slices.ContainsFunc(versions, func(v string) bool {
+		return strings.TrimPrefix(v, "v") == strings.TrimPrefix(version, "v")
+	})
+}
+
+// editModuleVersions updates modules with the specified source in a parsed file of any syntax and
+// counts changed blocks.
+//
+// The function retains comments and other HCL structures; the file is normalised with
+// hclwrite.Format when it is written.
+// If a matching module doesn't have a version attribute:
+//   - When forceAdd is false (default): the module is skipped
+//   - When forceAdd is true and the source is a registry module: a version attribute is added
+//   - When forceAdd is true and the source is not a registry module: the module is skipped
+//     because Terraform does not permit version constraints for those sources
+//
+// All modules with the same source attribute will be updated to the same version.
+// If fromVersions is specified, only modules with current version matching any in the list will be updated.
+// If ignoreVersions is specified, modules with current version matching any in the list will be skipped.
+// If ignorePatterns is specified, modules with names matching any pattern will be skipped.
+// If the target is older than every version the current constraint permits, the module is skipped
+// unless allowDowngrade is set. A pre-release target is skipped when the prerelease policy forbids
+// it. Skips are reported to opts.events.
+// If preserveOperator is set, the operator and precision of an existing constraint are kept and only
+// its version numbers move to the target (see preserveConstraintOperator).
+//
+// Parameters:
+//   - file: The parsed file to edit; followed references are added to its reference edits
+//   - opts: The module source, target version, filters, and behaviour switches to apply
+//
+// Returns:
+//   - updated: true if at least one module operation was applied (or would be applied in dry-run mode)
+//   - changedBlocks: indexes of module blocks whose version values differ from the target
+//   - error: Any error encountered while parsing a JSON syntax file
+func editModuleVersions(file *File, opts *moduleUpdateOptions) (updated bool, changedBlocks []int, err error) {
+	switch file.syntax {
+	case jsonSyntax:
+		file.json, updated, changedBlocks, err = updateModuleVersionJSON(file.json, opts)
+	case terragruntSyntax:
+		updated, changedBlocks = editModuleVersionTerragrunt(file.HCL, opts)
+	default:
+		updated, changedBlocks = editModuleVersion(file.HCL, opts)
+	}
+	file.referenceEdits = append(file.referenceEdits, opts.referenceEdits...)
+	return updated, changedBlocks, err
+}
+
+// configSyntax identifies how a selected file is parsed and edited.
+type configSyntax int
+
+const (
+	nativeSyntax configSyntax = iota
+	jsonSyntax
+	terragruntSyntax
+)
+
+// fileSyntax chooses the syntax for a selected file from its name. Files ending in .hcl are
+// Terragrunt configuration only when Terragrunt mode is enabled; otherwise they are parsed as
+// native Terraform syntax, as before.
+func fileSyntax(filename string, terragrunt bool) configSyntax {
+	switch {
+	case isJSONSyntax(filename):
+		return jsonSyntax
+	case terragrunt && strings.HasSuffix(filename, ".hcl"):
+		return terragruntSyntax
+	}
+	return nativeSyntax
+}
+
+// editModuleVersion updates matching module blocks in a parsed file.
+func editModuleVersion(file *hclwrite.File, opts *moduleUpdateOptions) (updated bool, changedBlocks []int) {
+	// Iterate through all blocks in the file
+	for blockIndex, block := range file.Body().Blocks() {
+		blockUpdated, blockChanged := updateModuleBlockResult(block, opts)
+		if blockUpdated {
+			updated = true
+			if blockChanged {
+				changedBlocks = append(changedBlocks, blockIndex)
+			}
+		}
+	}
+	return updated, changedBlocks
+}
+
+type moduleUpdateOptions struct {
+	filename         string
+	moduleSource     string
+	version          string
+	fromVersions     []string
+	ignoreVersions   []string
+	ignorePatterns   []string
+	forceAdd         bool
+	preserveOperator bool
+	allowDowngrade   bool
+	prerelease       string
+	terragrunt       bool
+	opentofu         bool
+	followReferences bool
+	referenceEdits   []referenceEdit
+	outputFormat     string
+	events           eventSink
+	confirm          func(Proposal) bool
+	beforeWrite      func(string) error
+	previous         versionSet
+	terragruntSource *terragruntSource // set while updating a Terragrunt source, whose version is a query parameter
+}
+
+// matchesSource reports whether a module's literal source is the one being updated. Sources are
+// compared exactly, except that OpenTofu mode also matches equivalent registry addresses.
+func (opts *moduleUpdateOptions) matchesSource(source string) bool {
+	if opts.opentofu {
+		return sameModuleSource(source, opts.moduleSource, true)
+	}
+	return source == opts.moduleSource
+}
+
+// targetVersion returns the value to write in place of the current version attribute.
+func (opts *moduleUpdateOptions) targetVersion(currentVersion string) string {
+	if opts.terragruntSource != nil {
+		return terragruntRefVersion(currentVersion, opts.version)
+	}
+	if opts.preserveOperator {
+		return preserveConstraintOperator(currentVersion, opts.version)
+	}
+	return opts.version
+}
+
+func updateModuleBlockResult(block *hclwrite.Block, opts *moduleUpdateOptions) (updated, changed bool) {
+	if block.Type() != "module" {
+		return false, false
+	}
+
+	moduleName := moduleBlockName(block)
+	sourceAttr := block.Body().GetAttribute("source")
+	if sourceAttr == nil {
+		return false, false
+	}
+	if !isLiteralString(sourceAttr) {
+		opts.reportComputedSource(moduleName, string(sourceAttr.Expr().BuildTokens(nil).Bytes()))
+		return false, false
+	}
+	sourceValue := attributeStringValue(sourceAttr)
+	if !opts.matchesSource(sourceValue) {
+		return false, false
+	}
+
+	versionAttr := block.Body().GetAttribute("version")
+	if versionAttr != nil && !isLiteralString(versionAttr) {
+		return opts.followVersion(moduleName, sourceValue, versionAttr.Expr().BuildTokens(nil).Bytes())
+	}
+	currentVersion := ""
+	if versionAttr != nil {
+		currentVersion = attributeStringValue(versionAttr)
+	}
+	newVersion, ok := opts.resolveVersion(moduleName, sourceValue, currentVersion, versionAttr != nil)
+	if !ok {
+		return false, false
+	}
+	block.Body().SetAttributeValue("version", cty.StringVal(newVersion))
+	return true, versionAttr == nil || currentVersion != newVersion
+}
+
+// resolveVersion decides the version to write into a module block whose source matches. The same
+// checks apply to native and JSON syntax: local sources, name patterns, missing versions, version
+// filters, downgrades, and the pre-release policy. Skips are reported before returning.
+//
+// Parameters:
+//   - moduleName: The module block label
+//   - sourceValue: The module's literal source
+//   - currentVersion: The literal version currently written, if any
+//   - hasVersion: false when the block has no version attribute
+//
+// Returns:
+//   - string: The version value to write
+//   - bool: false when the block must be left alone
+func (opts *moduleUpdateOptions) resolveVersion(moduleName, sourceValue, currentVersion string, hasVersion bool) (string, bool) {
+	if isLocalModule(sourceValue) {
+		opts.events.skip("Module %s in %s (source: %s) is a local module and cannot be version-bumped, skipping",
+			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
+		return "", false
+	}
+
+	if shouldIgnoreModule(moduleName, opts.ignorePatterns) {
+		opts.events.filtered("Skipped module %s in %s (matches ignore pattern)", quote(moduleName, opts.outputFormat), opts.filename)
+		return "", false
+	}
+
+	subject := opts.subject(moduleName)
+	if !hasVersion {
+		return opts.resolveMissingVersion(moduleName, sourceValue, subject)
+	}
+
+	if shouldSkipModuleVersion(moduleName, currentVersion, opts) {
+		return "", false
+	}
+	newVersion := opts.targetVersion(currentVersion)
+	if refuseVersionChange(opts.events, subject, currentVersion, opts.version, newVersion, opts.allowDowngrade, opts.prerelease, opts.outputFormat) ||
+		!opts.confirmChange(subject, moduleName, currentVersion, newVersion) {
+		return "", false
+	}
+	opts.previous.replace(currentVersion, newVersion)
+	return newVersion, true
+}
+
+// confirmChange asks for confirmation of a version change to a module block.
+func (opts *moduleUpdateOptions) confirmChange(subject, moduleName, currentVersion, newVersion string) bool {
+	proposal := Proposal{
+		Kind:      ModuleChange,
+		File:      opts.filename,
+		Block:     moduleName,
+		Name:      opts.moduleSource,
+		Current:   currentVersion,
+		Target:    newVersion,
+		Attribute: "version",
+		Before:    currentVersion,
+		After:     newVersion,
+	}
+	if source := opts.terragruntSource; source != nil {
+		proposal.Attribute, proposal.Before, proposal.After = "source", source.raw, source.withVersion(newVersion)
+	}
+	return confirmChange(opts.confirm, opts.events, subject, proposal, opts.outputFormat)
+}
+
+// reportComputedSource reports that a module is skipped because its source, which cannot be
+// matched, is not a literal string.
+func (opts *moduleUpdateOptions) reportComputedSource(moduleName, expr string) {
+	opts.events.skip("Module %s in %s has a non-literal source %s, skipping", quote(moduleName, opts.outputFormat), opts.filename,
+		quote(strings.TrimSpace(expr), opts.outputFormat))
+}
+
+// subject describes a module block in the current file for skips.
+func (opts *moduleUpdateOptions) subject(moduleName string) string {
+	return fmt.Sprintf("Module %s in %s (source: %s)", quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
+}
+
+// followVersion updates the definition behind a module version that references a local value or
+// variable, applying the same checks as a literal version.
+func (opts *moduleUpdateOptions) followVersion(moduleName, sourceValue string, expression []byte) (updated, changed bool) {
+	definition, ok := followVersionReference(opts.events, opts.subject(moduleName), expression, filepath.Dir(opts.filename), opts.followReferences, opts.outputFormat)
+	if !ok {
+		return false, false
+	}
+	newVersion, ok := opts.resolveVersion(moduleName, sourceValue, definition.value, true)
+	if !ok {
+		return false, false
+	}
+	opts.referenceEdits = append(opts.referenceEdits, referenceEdit{definition: definition, value: newVersion})
+	return true, definition.value != newVersion
+}
+
+// resolveMissingVersion decides whether a version may be added to a matching module that has none.
+func (opts *moduleUpdateOptions) resolveMissingVersion(moduleName, sourceValue, subject string) (string, bool) {
+	if !opts.forceAdd {
+		opts.events.skip("Module %s in %s (source: %s) has no version attribute, skipping",
+			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
+		return "", false
+	}
+	if !isRegistryModule(sourceValue) {
+		opts.events.skip("Module %s in %s (source: %s) is not a registry module and cannot use a version attribute, skipping",
+			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
+		return "", false
+	}
+	if refusePrerelease(opts.events, subject, "", opts.version, opts.prerelease, opts.outputFormat) ||
+		!opts.confirmChange(subject, moduleName, "", opts.version) {
+		return "", false
+	}
+	opts.previous.replace("", opts.version)
+	return opts.version, true
+}
+
+func moduleBlockName(block *hclwrite.Block) string {
+	if len(block.Labels()) == 0 {
+		return ""
+	}
+	return block.Labels()[0]
+}
+
+func attributeStringValue(attr *hclwrite.Attribute) string {
+	tokens := attr.Expr().BuildTokens(nil)
+	return trimQuotes(strings.TrimSpace(string(tokens.Bytes())))
+}
+
+func shouldSkipModuleVersion(moduleName, currentVersion string, opts *moduleUpdateOptions) bool {
+	if len(opts.ignoreVersions) > 0 && opts.containsVersion(opts.ignoreVersions, currentVersion) {
+		opts.events.filtered("Skipped module %s in %s (current version %s matches 'ignore-version' filter %v)", quote(moduleName, opts.outputFormat), opts.filename, quote(currentVersion, opts.outputFormat), opts.ignoreVersions)
+		return true
+	}
+
+	if len(opts.fromVersions) > 0 && !opts.containsVersion(opts.fromVersions, currentVersion) {
+		opts.events.filtered("Skipped module %s in %s (current version %s does not match any 'from' filter %v)", quote(moduleName, opts.outputFormat), opts.filename, quote(currentVersion, opts.outputFormat), opts.fromVersions)
+		return true
+	}
+
+	return false
+}
+
+// isLocalModule checks if a module source is a local path.
+// Local modules use relative or absolute paths instead of registry sources.
+//
+// Parameters:
+//   - source: The module source to check
+//
+// Returns:
+//   - bool: true if the source is a local path, false otherwise
+//
+// Examples:
+//   - `./modules/vpc` returns true
+//   - `../shared/modules` returns true
+//   - `/absolute/path/module` returns true
+//   - `terraform-aws-modules/vpc/aws` returns false
+func isLocalModule(source string) bool {
+	return strings.HasPrefix(source, "./") ||
+		strings.HasPrefix(source, "../") ||
+		strings.HasPrefix(source, "/")
+}
+
+func isRegistryModule(source string) bool {
+	_, err := tfaddr.ParseModuleSource(source)
+	return err == nil
+}
+
+// shouldIgnoreModule checks if a module name matches any of the ignore patterns.
+// Patterns support wildcard matching using '*' for zero or more characters.
+//
+// Parameters:
+//   - moduleName: The name of the module to check
+//   - patterns: List of patterns to match against (e.g., ["vpc", "legacy-*", "*-test"])
+//
+// Returns:
+//   - bool: true if the module name matches any pattern, false otherwise
+//
+// Examples:
+//   - shouldIgnoreModule("vpc", ["vpc"]) returns true (exact match)
+//   - shouldIgnoreModule("legacy-vpc", ["legacy-*"]) returns true (wildcard prefix)
+//   - shouldIgnoreModule("vpc-test", ["*-test"]) returns true (wildcard suffix)
+//   - shouldIgnoreModule("prod-vpc-test", ["*-vpc-*"]) returns true (wildcard both sides)
+//   - shouldIgnoreModule("vpc", ["s3"]) returns false (no match)
+func shouldIgnoreModule(moduleName string, patterns []string) bool {
+	// Defensive: According to HCL/Terraform syntax, module blocks must have labels ("module" "name"),
+	// so moduleName should never be empty in practice. This check handles malformed HCL or unexpected
+	// parsing results. If moduleName is empty, do not ignore the module.
+	if moduleName == "" {
+		return false
+	}
+
+	if len(patterns) == 0 {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if matchPattern(moduleName, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern performs wildcard pattern matching.
+// Supports '*' as a wildcard that matches zero or more characters.
+//
+// Matching behavior:
+//   - Uses greedy matching for middle parts (finds first occurrence of each part in order)
+//   - Consecutive wildcards (**, ***, etc.) are treated as a single wildcard
+//   - For patterns with multiple wildcards and repeated literal parts (e.g., "a*c*c"),
+//     the algorithm ensures all parts fit without overlapping by checking that middle
+//     parts don't extend past where the suffix begins
+//
+// Parameters:
+//   - name: The string to match
+//   - pattern: The pattern to match against (may contain '*' wildcards)
+//
+// Returns:
+//   - bool: true if the name matches the pattern, false otherwise
+//
+// Examples:
+//   - matchPattern("vpc", "vpc") returns true (exact match)
+//   - matchPattern("legacy-vpc", "legacy-*") returns true (wildcard suffix)
+//   - matchPattern("vpc-test", "*-test") returns true (wildcard prefix)
+//   - matchPattern("prod-vpc-test", "*-vpc-*") returns true (wildcard both sides)
+//   - matchPattern("abc", "a**c") returns true (consecutive wildcards)
+//   - matchPattern("acc", "a*c*c") returns true (repeated parts, wildcards match zero chars)
+//   - matchPattern("vpc", "s3") returns false (no match)
+func matchPattern(name, pattern string) bool {
+	// If pattern has no wildcards, do exact match
+	if !strings.Contains(pattern, "*") {
+		return name == pattern
+	}
+
+	// Split pattern by '*' to get the literal parts
+	parts := strings.Split(pattern, "*")
+
+	if !patternBoundsMatch(name, parts) {
+		return false
+	}
+
+	pos, ok := orderedMiddlePartsEnd(name, parts)
+	return ok && suffixDoesNotOverlap(name, parts, pos)
+}
+
+func patternBoundsMatch(name string, parts []string) bool {
+	first := parts[0]
+	last := parts[len(parts)-1]
+
+	if first != "" && !strings.HasPrefix(name, first) {
+		return false
+	}
+	if last != "" && !strings.HasSuffix(name, last) {
+		return false
+	}
+
+	return first == "" || last == "" || len(name) >= len(first)+len(last)
+}
+
+func orderedMiddlePartsEnd(name string, parts []string) (int, bool) {
+	pos := 0
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if i == 0 {
+			pos = len(part)
+			continue
+		}
+		if i == len(parts)-1 {
+			break
+		}
+
+		idx := strings.Index(name[pos:], part)
+		if idx == -1 {
+			return 0, false
+		}
+		pos += idx + len(part)
+	}
+
+	return pos, true
+}
+
+func suffixDoesNotOverlap(name string, parts []string, pos int) bool {
+	last := parts[len(parts)-1]
+	if last == "" {
+		return true
+	}
+
+	return pos <= len(name)-len(last)
+}
+
+// trimQuotes removes surrounding single or double quotes from a string.
+// If the string doesn't have matching quotes on both ends, it returns the original string.
+//
+// Parameters:
+//   - s: The string to trim quotes from
+//
+// Returns:
+//   - string: The string with quotes removed, or the original string if no matching quotes found
+//
+// Examples:
+//   - `"hello"` returns `hello`
+//   - `'hello'` returns `hello`
+//   - `hello` returns `hello`
+//   - `"hello'` returns `"hello'` (mismatched quotes)
+func trimQuotes(s string) string {
+	if len(s) >= 2 {
+		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package bump
+
+import (
+	"strings"
+
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+)
+
+// OpenTofu reads .tofu and .tofu.json files alongside .tf and .tf.json files. When both main.tf
+// and main.tofu exist in a directory, OpenTofu loads main.tofu and ignores main.tf, so a stack can
+// keep a Terraform variant and an OpenTofu variant of the same file side by side. OpenTofu's
+// registry, registry.opentofu.org, mirrors registry.terraform.io addresses.
+
+// openTofuRegistryHost is the default registry host used by OpenTofu.
+const openTofuRegistryHost = "registry.opentofu.org"
+
+// isOpenTofuFile reports whether a file is OpenTofu-only configuration.
+func isOpenTofuFile(filename string) bool {
+	return strings.HasSuffix(filename, ".tofu") || strings.HasSuffix(filename, ".tofu.json")
+}
+
+// sameModuleSource reports whether two module sources refer to the same module. Registry
+// addresses are compared after normalisation, so "ns/name/system" matches
+// "registry.terraform.io/ns/name/system"; other sources must match exactly. With openTofu set,
+// registry.opentofu.org addresses also match their registry.terraform.io equivalents.
+func sameModuleSource(a, b string, openTofu bool) bool {
+	if a == b {
+		return true
+	}
+	first, ok := registryModuleAddress(a, openTofu)
+	if !ok {
+		return false
+	}
+	second, ok := registryModuleAddress(b, openTofu)
+	return ok && first == second
+}
+
+// registryModuleAddress returns the normalised form of a registry module source.
+func registryModuleAddress(source string, openTofu bool) (string, bool) {
+	address, err := tfaddr.ParseModuleSource(source)
+	if err != nil {
+		return "", false
+	}
+	if openTofu && string(address.Package.Host) == openTofuRegistryHost {
+		address.Package.Host = tfaddr.DefaultModuleRegistryHost
+	}
+	return address.String(), true
+}
@@ -0,0 +1,107 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSameModuleSource(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		openTofu bool
+		want     bool
+	}{
+		{"terraform-aws-modules/vpc/aws", "terraform-aws-modules/vpc/aws", false, true},
+		{"terraform-aws-modules/vpc/aws", "registry.terraform.io/terraform-aws-modules/vpc/aws", false, true},
+		{"terraform-aws-modules/vpc/aws", "registry.opentofu.org/terraform-aws-modules/vpc/aws", false, false},
+		{"terraform-aws-modules/vpc/aws", "registry.opentofu.org/terraform-aws-modules/vpc/aws", true, true},
+		{"registry.terraform.io/terraform-aws-modules/vpc/aws//modules/vpc-endpoints", "registry.opentofu.org/terraform-aws-modules/vpc/aws//modules/vpc-endpoints", true, true},
+		{"terraform-aws-modules/vpc/aws", "registry.opentofu.org/terraform-aws-modules/vpc/google", true, false},
+		{"app.terraform.io/example/vpc/aws", "registry.opentofu.org/example/vpc/aws", true, false},
+		{"git::https://example.com/vpc.git", "git::https://example.com/vpc.git", true, true},
+		{"./modules/vpc", "modules/vpc", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := sameModuleSource(tt.a, tt.b, tt.openTofu); got != tt.want {
+			t.Errorf("sameModuleSource(%q, %q, %v) = %v, want %v", tt.a, tt.b, tt.openTofu, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateModuleVersionOpenTofuRegistry(t *testing.T) {
+	input := `terraform {
+  required_version = ">= 1.8"
+
+  encryption {
+    key_provider "pbkdf2" "main" {
+      passphrase = var.passphrase
+    }
+  }
+}
+
+provider "aws" {
+  alias    = "by_region"
+  for_each = var.regions
+  region   = each.value
+}
+
+module "tofu" {
+  source  = "registry.opentofu.org/terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "terraform" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+`
+
+	tests := []struct {
+		name     string
+		openTofu bool
+		want     string
+	}{
+		{"exact match without OpenTofu mode", false, strings.Replace(input, "source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"", "source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.0.0\"", 1)},
+		{"registry hosts are equivalent in OpenTofu mode", true, strings.ReplaceAll(input, `version = "4.0.0"`, `version = "5.0.0"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, t.TempDir(), "main.tofu", input)
+			opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", opentofu: tt.openTofu, outputFormat: "text", events: printEvents(false)}
+			if _, _, err := updateModuleVersionWithCount(file, opts, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := readTestFile(t, file); got != tt.want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTerraformVersionSkipsOpenTofuFiles(t *testing.T) {
+	dir := t.TempDir()
+	input := "terraform {\n  required_version = \">= 1.8\"\n}\n"
+	tofuFile := writeTestFile(t, dir, "versions.tofu", input)
+	tofuJSONFile := writeTestFile(t, dir, "versions.tofu.json", `{"terraform": {"required_version": ">= 1.8"}}`)
+	tfFile := writeTestFile(t, dir, "versions.tf", input)
+
+	opts := &terraformVersionOptions{version: ">= 1.9", opentofu: true, outputFormat: "text", events: printEvents(false)}
+	stderr := captureStderr(t, func() {
+		for _, file := range []string{tofuFile, tofuJSONFile} {
+			if updated, err := updateTerraformVersionWithOptions(file, opts, false); err != nil || updated {
+				t.Errorf("%s: updated=%v err=%v, want a skip", file, updated, err)
+			}
+		}
+	})
+	if strings.Count(stderr, "constrains OpenTofu rather than Terraform") != 2 {
+		t.Errorf("stderr = %q, want both files reported", stderr)
+	}
+	if updated, err := updateTerraformVersionWithOptions(tfFile, opts, false); err != nil || !updated {
+		t.Errorf(".tf file: updated=%v err=%v", updated, err)
+	}
+	if got := readTestFile(t, tofuFile); got != input {
+		t.Errorf(".tofu file changed:\n%s", got)
+	}
+}
@@ -0,0 +1,261 @@
+package bump
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// The read-only modes (lint and policy checks) scan a file for its version pins rather than
+// editing it through hclwrite, because they report the line and column of each pin. A pin is a
+// module block's source and version, a required_providers entry's version, or the
+// terraform.source address of a Terragrunt configuration. The required_providers entries a
+// Terragrunt generate block writes are scanned in place in the heredoc.
+
+// pinValue is a string argument found while scanning a file, with the byte range it occupies.
+type pinValue struct {
+	text    string
+	pos     hcl.Pos
+	end     int  // byte offset just past the value
+	json    bool // written in JSON syntax rather than native syntax
+	literal bool // false for expressions and templates, which are not checked
+}
+
+// encode returns s written as a string literal in the value's syntax, for replacing the value.
+func (v *pinValue) encode(s string) string {
+	if v.json {
+		return jsonStringLiteral(s)
+	}
+	return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+}
+
+// pinVisitor receives the pins of a file in written order. Module blocks are visited only when
+// their source is a literal string. A provider's source is empty when the entry does not set one
+// as a literal string.
+type pinVisitor interface {
+	visitModule(name string, pos hcl.Pos, source string, version *pinValue)
+	visitProvider(name string, pos hcl.Pos, source string, version *pinValue)
+	visitTerragruntSource(name string, source *pinValue)
+}
+
+// scanPins parses a file in the syntax chosen by its name and passes its pins to the visitor.
+//
+// Parameters:
+//   - filename: Path of the file, used for positions and to choose the syntax
+//   - src: The file contents
+//   - terragrunt: If true, .hcl files are scanned as Terragrunt configuration
+//   - visitor: Receives each pin
+//
+// Returns:
+//   - error: Any error encountered while parsing
+func scanPins(filename string, src []byte, terragrunt bool, visitor pinVisitor) error {
+	if fileSyntax(filename, terragrunt) == jsonSyntax {
+		return scanJSONPins(src, visitor)
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+	body := file.Body.(*hclsyntax.Body)
+	if fileSyntax(filename, terragrunt) == terragruntSyntax {
+		scanTerragruntPins(filename, src, body, visitor)
+		return nil
+	}
+
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "module":
+			source := attributePinValue(block.Body.Attributes["source"])
+			if source == nil || !source.literal || len(block.Labels) == 0 {
+				continue
+			}
+			visitor.visitModule(block.Labels[0], block.DefRange().Start, source.text, attributePinValue(block.Body.Attributes["version"]))
+		case "terraform":
+			scanTerraformBlock(block, visitor)
+		}
+	}
+	return nil
+}
+
+// scanTerraformBlock visits the required_providers entries of a terraform block.
+func scanTerraformBlock(block *hclsyntax.Block, visitor pinVisitor) {
+	for _, nested := range block.Body.Blocks {
+		if nested.Type == "required_providers" {
+			scanRequiredProviders(nested.Body, visitor)
+		}
+	}
+}
+
+// scanRequiredProviders visits each entry of a required_providers block, whether written as an
+// attribute or in nested block syntax.
+func scanRequiredProviders(body *hclsyntax.Body, visitor pinVisitor) {
+	attributes := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attributes = append(attributes, attr)
+	}
+	slices.SortFunc(attributes, func(a, b *hclsyntax.Attribute) int {
+		return a.SrcRange.Start.Byte - b.SrcRange.Start.Byte
+	})
+
+	for _, attr := range attributes {
+		switch expr := attr.Expr.(type) {
+		case *hclsyntax.ObjectConsExpr:
+			source := expressionPinValue(providerObjectItem(expr, "source"))
+			visitor.visitProvider(attr.Name, attr.SrcRange.Start, literalText(source), expressionPinValue(providerObjectVersion(expr)))
+		case *hclsyntax.TemplateExpr:
+			// The legacy form, aws = "~> 5.0", is a version constraint without a source.
+			visitor.visitProvider(attr.Name, attr.SrcRange.Start, "", expressionPinValue(expr))
+		}
+	}
+	for _, block := range body.Blocks {
+		source := attributePinValue(block.Body.Attributes["source"])
+		visitor.visitProvider(block.Type, block.DefRange().Start, literalText(source), attributePinValue(block.Body.Attributes["version"]))
+	}
+}
+
+// scanTerragruntPins visits the terraform.source address of a Terragrunt configuration and the
+// required_providers entries of the configuration its generate blocks write. The directory name
+// stands in for the module name.
+func scanTerragruntPins(filename string, src []byte, body *hclsyntax.Body, visitor pinVisitor) {
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "terraform":
+			if source := attributePinValue(block.Body.Attributes["source"]); source != nil && source.literal {
+				visitor.visitTerragruntSource(terragruntModuleName(filename), source)
+			}
+		case "generate":
+			generated := generatedPinBody(filename, src, block.Body.Attributes["contents"])
+			if generated == nil {
+				continue
+			}
+			for _, generatedBlock := range generated.Blocks {
+				if generatedBlock.Type == "terraform" {
+					scanTerraformBlock(generatedBlock, visitor)
+				}
+			}
+		}
+	}
+}
+
+// generatedPinBody parses the heredoc contents of a generate block in place, so that positions
+// and byte offsets refer to the Terragrunt file. It returns nil for contents that are not a
+// heredoc, that use template interpolation, or that are not valid HCL; the update path reports
+// those as skipped.
+func generatedPinBody(filename string, src []byte, contents *hclsyntax.Attribute) *hclsyntax.Body {
+	if contents == nil {
+		return nil
+	}
+	exprRange := contents.Expr.Range()
+	heredoc := string(src[exprRange.Start.Byte:exprRange.End.Byte])
+	if !strings.HasPrefix(heredoc, "<<") {
+		return nil
+	}
+	heredoc = strings.TrimSuffix(heredoc, "\n")
+	start, end := strings.Index(heredoc, "\n")+1, strings.LastIndex(heredoc, "\n")+1
+	if start == 0 || end <= start {
+		return nil
+	}
+	text := heredoc[start:end]
+	if strings.Contains(text, "${") || strings.Contains(text, "%{") {
+		return nil
+	}
+	pos := hcl.Pos{Line: exprRange.Start.Line + 1, Column: 1, Byte: exprRange.Start.Byte + start}
+	file, diags := hclsyntax.ParseConfig([]byte(text), filename, pos)
+	if diags.HasErrors() {
+		return nil
+	}
+	return file.Body.(*hclsyntax.Body)
+}
+
+// scanJSONPins visits the module blocks and required_providers entries of a JSON syntax file.
+func scanJSONPins(src []byte, visitor pinVisitor) error {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return err
+	}
+
+	labels, bodies := jsonBlocks(root, "module", true)
+	for blockIndex, body := range bodies {
+		source := jsonPinValue(src, body.member("source"))
+		if source == nil || !source.literal {
+			continue
+		}
+		visitor.visitModule(labels[blockIndex], jsonPosition(src, body.start), source.text, jsonPinValue(src, body.member("version")))
+	}
+
+	_, terraformBodies := jsonBlocks(root, "terraform", false)
+	for _, terraformBody := range terraformBodies {
+		requiredProviders := terraformBody.member("required_providers")
+		if requiredProviders == nil {
+			continue
+		}
+		for _, providers := range requiredProviders.bodies() {
+			for _, member := range providers.members {
+				if member.key == "//" {
+					continue
+				}
+				var source string
+				if member.value.kind == jsonObject {
+					source = literalText(jsonPinValue(src, member.value.member("source")))
+				}
+				visitor.visitProvider(member.key, jsonPosition(src, member.keyStart), source, jsonPinValue(src, jsonProviderVersion(member.value)))
+			}
+		}
+	}
+	return nil
+}
+
+// attributePinValue returns an attribute's value, or nil when the attribute is not set.
+func attributePinValue(attr *hclsyntax.Attribute) *pinValue {
+	if attr == nil {
+		return nil
+	}
+	return expressionPinValue(attr.Expr)
+}
+
+// expressionPinValue returns an expression's value, or nil for a nil expression.
+func expressionPinValue(expr hclsyntax.Expression) *pinValue {
+	if expr == nil {
+		return nil
+	}
+	value := &pinValue{pos: expr.Range().Start, end: expr.Range().End.Byte}
+	if isStringLiteral(expr) {
+		if literal, diags := expr.Value(nil); !diags.HasErrors() && literal.IsKnown() && !literal.IsNull() {
+			value.text = literal.AsString()
+			value.literal = true
+		}
+	}
+	return value
+}
+
+// literalText returns the text of a literal value, or an empty string when the value is not set
+// or not literal.
+func literalText(value *pinValue) string {
+	if value == nil || !value.literal {
+		return ""
+	}
+	return value.text
+}
+
+// jsonPinValue returns a JSON value, or nil when it is not set. Strings that contain template
+// sequences are not literal.
+func jsonPinValue(src []byte, value *jsonValue) *pinValue {
+	if value == nil {
+		return nil
+	}
+	literal := value.kind == jsonString && !strings.Contains(value.text, "${") && !strings.Contains(value.text, "%{")
+	return &pinValue{text: value.text, pos: jsonPosition(src, value.start), end: value.end, json: true, literal: literal}
+}
+
+// jsonPosition converts a byte offset in a JSON syntax file to a line and column.
+func jsonPosition(src []byte, offset int) hcl.Pos {
+	before := string(src[:offset])
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return hcl.Pos{Line: strings.Count(before, "\n") + 1, Column: offset - lineStart + 1, Byte: offset}
+}
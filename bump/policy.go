@@ -0,0 +1,225 @@
+package bump
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Policy mode checks the module blocks and required_providers entries that a policy file covers,
+// reporting every version constraint that permits a version outside the policy's bounds:
+//
+//	main.tf:3:13: Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '~> 5.0' permits versions below the minimum '5.1.0'
+//
+// With -fix, a constraint whose only problem is its floor is rewritten with its floor raised to
+// the minimum, keeping its operator and precision ("~> 5.0" becomes "~> 5.1"). Values are replaced
+// in place, so the rest of the file is not reformatted. Missing versions and constraints above the
+// maximum are left for review. Terragrunt terraform.source refs are not covered by policies.
+
+// versionBounds is the range of versions a policy permits. A nil bound is open.
+type versionBounds struct {
+	minimum    *semanticVersion
+	maximum    *semanticVersion
+	minVersion string
+	maxVersion string
+}
+
+// newVersionBounds returns the bounds of a policy entry whose versions have been validated.
+func newVersionBounds(minVersion, maxVersion string) versionBounds {
+	bounds := versionBounds{minVersion: minVersion, maxVersion: maxVersion}
+	if version, ok := parseSemanticVersion(minVersion); ok {
+		bounds.minimum = &version
+	}
+	if version, ok := parseSemanticVersion(maxVersion); ok {
+		bounds.maximum = &version
+	}
+	return bounds
+}
+
+// restrict narrows the bounds to the intersection with another entry's bounds, for pins that
+// several policy entries cover.
+func (bounds *versionBounds) restrict(other versionBounds) {
+	if other.minimum != nil && (bounds.minimum == nil || compareVersions(*other.minimum, *bounds.minimum) > 0) {
+		bounds.minimum, bounds.minVersion = other.minimum, other.minVersion
+	}
+	if other.maximum != nil && (bounds.maximum == nil || compareVersions(*other.maximum, *bounds.maximum) < 0) {
+		bounds.maximum, bounds.maxVersion = other.maximum, other.maxVersion
+	}
+}
+
+// problems describes how a constraint permits versions outside the bounds. A constraint permits
+// versions below the minimum unless its floor is at or above it, and versions above the maximum
+// unless its ceiling is at or below it; an exclusive ceiling equal to the maximum is accepted.
+//
+// Returns:
+//   - []string: One phrase per problem, or nil when the constraint is within the bounds
+func (bounds versionBounds) problems(constraint, outputFormat string) []string {
+	clauses, ok := parseConstraint(constraint)
+	if !ok {
+		return []string{"is not a version constraint"}
+	}
+
+	var problems []string
+	permitted := constraintRange(clauses)
+	if bounds.minimum != nil && (permitted.lower == nil || compareVersions(permitted.lower.version, *bounds.minimum) < 0) {
+		problems = append(problems, "permits versions below the minimum "+quote(bounds.minVersion, outputFormat))
+	}
+	if bounds.maximum != nil && (permitted.upper == nil || compareVersions(permitted.upper.version, *bounds.maximum) > 0) {
+		problems = append(problems, "permits versions above the maximum "+quote(bounds.maxVersion, outputFormat))
+	}
+	return problems
+}
+
+// raise returns the constraint with its floor raised to the minimum, keeping its operators and
+// precision. A constraint without a floor gains a ">=" clause.
+//
+// Returns:
+//   - string: The raised constraint
+//   - bool: false when the floor is not below the minimum, or the raised constraint would still
+//     be outside the bounds
+func (bounds versionBounds) raise(constraint string) (string, bool) {
+	clauses, ok := parseConstraint(constraint)
+	if !ok || bounds.minimum == nil {
+		return "", false
+	}
+
+	lower := constraintRange(clauses).lower
+	if lower != nil && compareVersions(lower.version, *bounds.minimum) >= 0 {
+		return "", false
+	}
+	raised := preserveConstraintOperator(constraint, bounds.minVersion)
+	if lower == nil {
+		raised = formatConstraint(append([]constraintClause{{operator: ">=", version: *bounds.minimum}}, clauses...))
+	}
+	return raised, len(bounds.problems(raised, "text")) == 0
+}
+
+// PolicyViolation is a pin that permits versions outside the policy.
+type PolicyViolation struct {
+	Pos     hcl.Pos
+	Subject string // The module or provider, as it starts Message
+	Message string
+	Value   string // The literal constraint, or empty when the pin has no version
+	Raised  string // The fixed constraint, or empty when the violation cannot be fixed
+	value   *pinValue
+}
+
+// policyChecker collects the policy violations of one file.
+type policyChecker struct {
+	policy       *Policy
+	opentofu     bool
+	outputFormat string
+	beforeWrite  func(string) error
+	violations   []PolicyViolation
+}
+
+// CheckPolicy reads a file and checks its module blocks and required_providers entries against a
+// policy. When fix is set, constraints whose floor is below a policy minimum are raised to it and
+// the file is written, unless opts.DryRun is set.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to check
+//   - policy: The policy, from LoadPolicy
+//   - fix: If true, raise the fixable violations
+//   - opts: DryRun, Terragrunt, OpenTofu, and BeforeWrite apply as for updates; OutputFormat selects the quoting
+//     of messages
+//
+// Returns:
+//   - []PolicyViolation: The violations in written order, with Raised set on those fix raises
+//   - error: Any error encountered while reading, parsing, or writing the file
+func CheckPolicy(filename string, policy *Policy, fix bool, opts Options) ([]PolicyViolation, error) {
+	checker := &policyChecker{policy: policy, opentofu: opts.OpenTofu, outputFormat: opts.OutputFormat, beforeWrite: opts.BeforeWrite}
+	if err := checker.checkFile(filename, fix, opts.DryRun, opts.Terragrunt); err != nil {
+		return nil, err
+	}
+	return checker.violations, nil
+}
+
+// checkFile collects a file's violations in written order and, when fix is set, writes the raised
+// constraints, preserving the file's permissions.
+func (c *policyChecker) checkFile(filename string, fix, dryRun, terragrunt bool) error {
+	_, err := rewriteFile(filename, dryRun, c.beforeWrite, func(src []byte) ([]byte, bool, error) {
+		if err := scanPins(filename, src, terragrunt, c); err != nil {
+			return nil, false, err
+		}
+		slices.SortStableFunc(c.violations, func(a, b PolicyViolation) int {
+			return a.Pos.Byte - b.Pos.Byte
+		})
+		if !fix {
+			return nil, false, nil
+		}
+
+		// Raised values replace the literals by byte range, as JSON edits do, in either syntax
+		var edits []jsonEdit
+		for _, violation := range c.violations {
+			if violation.Raised != "" {
+				edits = append(edits, jsonEdit{start: violation.value.pos.Byte, end: violation.value.end, text: violation.value.encode(violation.Raised)})
+			}
+		}
+		return applyJSONEdits(src, edits), len(edits) > 0, nil
+	})
+	return err
+}
+
+// visitModule checks a module block against the policies for its source.
+func (c *policyChecker) visitModule(name string, pos hcl.Pos, source string, version *pinValue) {
+	var bounds versionBounds
+	covered := false
+	for _, entry := range c.policy.Modules {
+		if sameModuleSource(source, entry.Source, c.opentofu) {
+			bounds.restrict(newVersionBounds(entry.MinVersion, entry.MaxVersion))
+			covered = true
+		}
+	}
+	if covered {
+		c.check(fmt.Sprintf("Module %s (source: %s)", quote(name, c.outputFormat), quote(source, c.outputFormat)), pos, version, bounds)
+	}
+}
+
+// visitProvider checks a required_providers entry against the policies for its name.
+func (c *policyChecker) visitProvider(name string, pos hcl.Pos, _ string, version *pinValue) {
+	var bounds versionBounds
+	covered := false
+	for _, entry := range c.policy.Providers {
+		if entry.Name == name {
+			bounds.restrict(newVersionBounds(entry.MinVersion, entry.MaxVersion))
+			covered = true
+		}
+	}
+	if covered {
+		c.check(fmt.Sprintf("Provider %s", quote(name, c.outputFormat)), pos, version, bounds)
+	}
+}
+
+// visitTerragruntSource ignores Terragrunt sources, whose refs are not version constraints.
+func (c *policyChecker) visitTerragruntSource(string, *pinValue) {}
+
+// check records a violation when a pin's version is missing or permits versions outside the
+// bounds. Versions that are not literal strings are not checked.
+func (c *policyChecker) check(subject string, pos hcl.Pos, version *pinValue, bounds versionBounds) {
+	if version == nil {
+		c.violations = append(c.violations, PolicyViolation{Pos: pos, Subject: subject, Message: subject + " has no version, so it permits every version"})
+		return
+	}
+	if !version.literal {
+		return
+	}
+	problems := bounds.problems(version.text, c.outputFormat)
+	if len(problems) == 0 {
+		return
+	}
+
+	violation := PolicyViolation{
+		Pos:     version.pos,
+		Subject: subject,
+		Message: fmt.Sprintf("%s version %s %s", subject, quote(version.text, c.outputFormat), strings.Join(problems, " and ")),
+		Value:   version.text,
+		value:   version,
+	}
+	if raised, ok := bounds.raise(version.text); ok {
+		violation.Raised = raised
+	}
+	c.violations = append(c.violations, violation)
+}
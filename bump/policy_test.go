@@ -0,0 +1,38 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersionBoundsProblems(t *testing.T) {
+	bounds := newVersionBounds("5.1.0", "6.0.0")
+	tests := []struct {
+		constraint string
+		want       string
+		raised     string
+	}{
+		{"5.1.0", "", ""},
+		{"~> 5.1", "", ""},
+		{"~> 5.0", "permits versions below the minimum '5.1.0'", "~> 5.1"},
+		{"5.0.2", "permits versions below the minimum '5.1.0'", "5.1.0"},
+		{">= 4.0, < 5.0", "permits versions below the minimum '5.1.0'", ">= 5.1, < 6.0"},
+		{"< 5.5", "permits versions below the minimum '5.1.0'", ">= 5.1.0, < 5.5"},
+		{">= 5.2", "permits versions above the maximum '6.0.0'", ""},
+		{">= 4.0", "permits versions below the minimum '5.1.0' and permits versions above the maximum '6.0.0'", ""},
+		{"~> 6.0", "permits versions above the maximum '6.0.0'", ""},
+		{"~> 5", "permits versions below the minimum '5.1.0'", ""},
+		{"latest", "is not a version constraint", ""},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(bounds.problems(tt.constraint, "text"), " and "); got != tt.want {
+			t.Errorf("problems(%q) = %q, want %q", tt.constraint, got, tt.want)
+		}
+		if raised, _ := bounds.raise(tt.constraint); tt.raised != "" && raised != tt.raised {
+			t.Errorf("raise(%q) = %q, want %q", tt.constraint, raised, tt.raised)
+		} else if _, ok := bounds.raise(tt.constraint); tt.raised == "" && ok {
+			t.Errorf("raise(%q) succeeded, want no fix", tt.constraint)
+		}
+	}
+}
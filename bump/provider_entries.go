@@ -0,0 +1,119 @@
+package bump
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Provider entries can be incomplete in two ways: an entry that names only its source, and a
+// required_providers block that does not declare the provider at all. With force-add, a missing
+// version is inserted into an existing entry; with an add source, a missing entry is created:
+//
+//	required_providers {
+//	  aws = {
+//	    source  = "hashicorp/aws"
+//	    version = "~> 6.0" # added by force-add
+//	  }
+//	  google = { # added with source hashicorp/google
+//	    source  = "hashicorp/google"
+//	    version = "~> 6.0"
+//	  }
+//	}
+
+// resolveMissingVersion decides whether a version may be added to a provider entry that has none.
+// Without force-add the entry is reported as skipped.
+func (opts *providerUpdateOptions) resolveMissingVersion() (string, bool) {
+	if !opts.forceAdd {
+		opts.events.skip("Provider %s in %s has no version constraint, skipping (use force-add to add one)",
+			quote(opts.providerName, opts.outputFormat), opts.filename)
+		return "", false
+	}
+	return opts.resolveVersion("")
+}
+
+// hasProviderEntry reports whether a required_providers block declares the provider, in either
+// attribute or block syntax.
+func hasProviderEntry(requiredProviders *hclwrite.Block, providerName string) bool {
+	if requiredProviders.Body().GetAttribute(providerName) != nil {
+		return true
+	}
+	for _, providerBlock := range requiredProviders.Body().Blocks() {
+		if providerBlock.Type() == providerName {
+			return true
+		}
+	}
+	return false
+}
+
+// addProviderEntry declares the provider in a required_providers block with the configured source
+// and the target version.
+//
+// Parameters:
+//   - requiredProviders: A required_providers block that does not declare the provider
+//   - opts: The provider name, source, target version, and behaviour switches to apply
+//
+// Returns:
+//   - bool: true if the entry was added; false when the pre-release policy refuses the target
+func addProviderEntry(requiredProviders *hclwrite.Block, opts *providerUpdateOptions) bool {
+	newVersion, ok := opts.resolveVersion("")
+	if !ok {
+		return false
+	}
+	requiredProviders.Body().SetAttributeRaw(opts.providerName, hclwrite.TokensForObject([]hclwrite.ObjectAttrTokens{
+		{Name: hclwrite.TokensForIdentifier("source"), Value: hclwrite.TokensForValue(cty.StringVal(opts.addSource))},
+		{Name: hclwrite.TokensForIdentifier("version"), Value: hclwrite.TokensForValue(cty.StringVal(newVersion))},
+	}))
+	return true
+}
+
+// providerObjectVersion returns the value of a provider object expression's version item, or nil.
+func providerObjectVersion(objExpr *hclsyntax.ObjectConsExpr) hclsyntax.Expression {
+	return providerObjectItem(objExpr, "version")
+}
+
+// providerObjectItem returns the value of a provider object expression's item with the given key,
+// or nil.
+func providerObjectItem(objExpr *hclsyntax.ObjectConsExpr, key string) hclsyntax.Expression {
+	for _, item := range objExpr.Items {
+		if keyName, ok := providerObjectItemKey(item); ok && keyName == key {
+			return item.ValueExpr
+		}
+	}
+	return nil
+}
+
+// insertProviderObjectVersion adds a version item after the last item of a provider object
+// expression. Objects written across several lines receive the item on its own line with the
+// indentation of the last item; single-line objects receive it after a comma.
+func insertProviderObjectVersion(objExpr *hclsyntax.ObjectConsExpr, expression []byte, version string) []byte {
+	item := "version = " + string(hclwrite.TokensForValue(cty.StringVal(version)).Bytes())
+	if len(objExpr.Items) == 0 {
+		return spliceBytes(expression, objExpr.OpenRange.End.Byte, " "+item+" ")
+	}
+
+	last := objExpr.Items[len(objExpr.Items)-1]
+	separator := ", "
+	object := expression[objExpr.SrcRange.Start.Byte:objExpr.SrcRange.End.Byte]
+	if bytes.IndexByte(object, '\n') >= 0 {
+		keyStart := last.KeyExpr.Range().Start.Byte
+		lineStart := bytes.LastIndexByte(expression[:keyStart], '\n') + 1
+		indent := string(expression[lineStart:keyStart])
+		if strings.TrimSpace(indent) != "" {
+			indent = ""
+		}
+		separator = "\n" + indent
+	}
+	return spliceBytes(expression, last.ValueExpr.Range().End.Byte, separator+item)
+}
+
+// spliceBytes returns a copy of src with text inserted at offset.
+func spliceBytes(src []byte, offset int, text string) []byte {
+	result := make([]byte, 0, len(src)+len(text))
+	result = append(result, src[:offset]...)
+	result = append(result, text...)
+	return append(result, src[offset:]...)
+}
@@ -0,0 +1,263 @@
+package bump
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// A provider migration moves required_providers entries to a new source address, such as from a
+// community namespace to the vendor's official one, and sets the version constraint for the new
+// source at the same time. Entries are matched by source, so the local name is kept:
+//
+//	required_providers {
+//	  widget = {
+//	    source  = "example-community/widget" # becomes "example/widget"
+//	    version = "~> 1.4"                   # becomes "~> 2.0"
+//	  }
+//	}
+
+// providerMigrationOptions holds one provider migration together with the options of the run.
+type providerMigrationOptions struct {
+	filename     string
+	fromSource   string
+	toSource     string
+	version      string
+	terragrunt   bool
+	opentofu     bool
+	outputFormat string
+	events       eventSink
+	beforeWrite  func(string) error
+}
+
+// migratedProvider is a required_providers entry moved by a provider migration.
+type migratedProvider struct {
+	location string // provider block location, in the form used for provider updates
+	name     string // local provider name
+}
+
+// providerMigrationOptions combines a provider migration with the options of the run.
+func (r *run) providerMigrationOptions(migration *ProviderMigration) providerMigrationOptions {
+	return providerMigrationOptions{
+		fromSource:   migration.FromSource,
+		toSource:     migration.ToSource,
+		version:      migration.Version,
+		terragrunt:   r.Terragrunt,
+		opentofu:     r.OpenTofu,
+		outputFormat: r.OutputFormat,
+		beforeWrite:  r.BeforeWrite,
+	}
+}
+
+// migrateProviderSourceWithCount migrates the required_providers entries of a file whose source
+// matches the migration, preserving the file's permissions when it is written.
+//
+// Parameters:
+//   - filename: Path to the Terraform file to process
+//   - opts: The migration and behaviour switches to apply
+//   - dryRun: If true, report what would change without modifying the file
+//
+// Returns:
+//   - []migratedProvider: The migrated entries, in written order
+//   - error: Any error encountered during file reading, parsing, or writing
+func migrateProviderSourceWithCount(filename string, opts providerMigrationOptions, dryRun bool) ([]migratedProvider, error) {
+	opts.filename = filename
+	var migrated []migratedProvider
+	_, err := rewriteFile(filename, dryRun, opts.beforeWrite, func(src []byte) (output []byte, updated bool, err error) {
+		switch fileSyntax(filename, opts.terragrunt) {
+		case jsonSyntax:
+			output, migrated, err = migrateProviderSourceJSON(src, &opts)
+		case terragruntSyntax:
+			output, migrated, err = migrateProviderSourceTerragrunt(src, &opts)
+		default:
+			output, migrated, err = migrateProviderSourceHCL(src, &opts)
+		}
+		return output, len(migrated) > 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+func migrateProviderSourceHCL(src []byte, opts *providerMigrationOptions) ([]byte, []migratedProvider, error) {
+	file, diags := hclwrite.ParseConfig(src, opts.filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+	migrated := migrateProviderEntries(file, opts)
+	return hclwrite.Format(file.Bytes()), migrated, nil
+}
+
+// migrateProviderSourceTerragrunt migrates entries in the configuration written by a Terragrunt
+// configuration's generate blocks.
+func migrateProviderSourceTerragrunt(src []byte, opts *providerMigrationOptions) ([]byte, []migratedProvider, error) {
+	file, diags := hclwrite.ParseConfig(src, opts.filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+
+	var migrated []migratedProvider
+	for blockIndex, block := range file.Body().Blocks() {
+		generated, ok := generatedConfig(opts.events, block, opts.filename, "required_providers", opts.outputFormat)
+		if !ok {
+			continue
+		}
+		blockMigrated := migrateProviderEntries(generated, opts)
+		if len(blockMigrated) == 0 {
+			continue
+		}
+		setGeneratedConfig(block, generated)
+		for _, entry := range blockMigrated {
+			migrated = append(migrated, migratedProvider{location: fmt.Sprintf("%d/generate/%s", blockIndex, entry.location), name: entry.name})
+		}
+	}
+	return hclwrite.Format(file.Bytes()), migrated, nil
+}
+
+// migrateProviderEntries migrates matching entries in the required_providers blocks of a parsed
+// file, in both block and attribute syntax.
+func migrateProviderEntries(file *hclwrite.File, opts *providerMigrationOptions) (migrated []migratedProvider) {
+	for blockIndex, block := range file.Body().Blocks() {
+		if block.Type() != "terraform" {
+			continue
+		}
+		for nestedIndex, nestedBlock := range block.Body().Blocks() {
+			if nestedBlock.Type() != "required_providers" {
+				continue
+			}
+			prefix := fmt.Sprintf("%d/%d/", blockIndex, nestedIndex)
+			for providerIndex, providerBlock := range nestedBlock.Body().Blocks() {
+				if opts.migrateProviderBlock(providerBlock) {
+					migrated = append(migrated, migratedProvider{location: fmt.Sprintf("%sblock/%d", prefix, providerIndex), name: providerBlock.Type()})
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(nestedBlock.Body().Attributes())) {
+				if opts.migrateProviderAttribute(nestedBlock, name) {
+					migrated = append(migrated, migratedProvider{location: prefix + "attribute/" + name, name: name})
+				}
+			}
+		}
+	}
+	return migrated
+}
+
+// migrateProviderBlock migrates a block-syntax provider entry, returning true if it matched.
+func (opts *providerMigrationOptions) migrateProviderBlock(providerBlock *hclwrite.Block) bool {
+	body := providerBlock.Body()
+	sourceAttr := body.GetAttribute("source")
+	if sourceAttr == nil || !isLiteralString(sourceAttr) || !sameProviderAddress(attributeStringValue(sourceAttr), opts.fromSource, opts.opentofu) {
+		return false
+	}
+	if versionAttr := body.GetAttribute("version"); versionAttr != nil && !isLiteralString(versionAttr) {
+		reportComputedVersion(opts.events, opts.subject(providerBlock.Type()), strings.TrimSpace(string(versionAttr.Expr().BuildTokens(nil).Bytes())), "", opts.outputFormat)
+		return false
+	}
+
+	body.SetAttributeValue("source", cty.StringVal(opts.toSource))
+	body.SetAttributeValue("version", cty.StringVal(opts.version))
+	return true
+}
+
+// migrateProviderAttribute migrates an attribute-syntax provider entry, returning true if it
+// matched. The version item is replaced or added first, then the source item is replaced.
+func (opts *providerMigrationOptions) migrateProviderAttribute(nestedBlock *hclwrite.Block, name string) bool {
+	objExpr, expression, ok := providerAttributeObject(nestedBlock, name)
+	if !ok {
+		return false
+	}
+	sourceExpr := providerObjectItem(objExpr, "source")
+	if sourceExpr == nil || !isStringLiteral(sourceExpr) || !sameProviderAddress(expressionText(expression, sourceExpr), opts.fromSource, opts.opentofu) {
+		return false
+	}
+
+	var updated []byte
+	versionExpr := providerObjectVersion(objExpr)
+	switch {
+	case versionExpr == nil:
+		updated = insertProviderObjectVersion(objExpr, expression, opts.version)
+	case !isStringLiteral(versionExpr):
+		valueRange := versionExpr.Range()
+		reportComputedVersion(opts.events, opts.subject(name), string(expression[valueRange.Start.Byte:valueRange.End.Byte]), "", opts.outputFormat)
+		return false
+	default:
+		updated, _, _ = replaceProviderObjectVersion(objExpr, expression, func(string) (string, bool) { return opts.version, true })
+	}
+
+	objExpr, ok = parseProviderObject(updated)
+	if !ok {
+		return false
+	}
+	sourceRange := providerObjectItem(objExpr, "source").Range()
+	updated = slices.Concat(updated[:sourceRange.Start.Byte], hclwrite.TokensForValue(cty.StringVal(opts.toSource)).Bytes(), updated[sourceRange.End.Byte:])
+	return setProviderObject(nestedBlock, name, updated)
+}
+
+// migrateProviderSourceJSON migrates matching required_providers entries in a JSON syntax file.
+func migrateProviderSourceJSON(src []byte, opts *providerMigrationOptions) ([]byte, []migratedProvider, error) {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var edits []jsonEdit
+	var migrated []migratedProvider
+	_, terraformBodies := jsonBlocks(root, "terraform", false)
+	for terraformIndex, terraformBody := range terraformBodies {
+		requiredProviders := terraformBody.member("required_providers")
+		if requiredProviders == nil {
+			continue
+		}
+		for providersIndex, providers := range requiredProviders.bodies() {
+			for _, member := range providers.members {
+				entryEdits, ok := opts.migrateJSONEntry(src, member)
+				if !ok {
+					continue
+				}
+				edits = append(edits, entryEdits...)
+				migrated = append(migrated, migratedProvider{location: fmt.Sprintf("%d/json/%d/%s", terraformIndex, providersIndex, member.key), name: member.key})
+			}
+		}
+	}
+	return applyJSONEdits(src, edits), migrated, nil
+}
+
+// migrateJSONEntry returns the edits that migrate one JSON required_providers entry, or false when
+// the entry does not match.
+func (opts *providerMigrationOptions) migrateJSONEntry(src []byte, member jsonMember) ([]jsonEdit, bool) {
+	entry := member.value
+	if entry.kind != jsonObject {
+		return nil, false
+	}
+	source := entry.member("source")
+	if source == nil || source.kind != jsonString || !sameProviderAddress(source.text, opts.fromSource, opts.opentofu) {
+		return nil, false
+	}
+	version := entry.member("version")
+	if version != nil && isJSONComputed(opts.events, opts.subject(member.key), src, version, opts.outputFormat) {
+		return nil, false
+	}
+
+	edits := []jsonEdit{replaceJSONString(source, opts.toSource)}
+	if version != nil {
+		return append(edits, replaceJSONString(version, opts.version)), true
+	}
+	return append(edits, insertJSONMember(src, entry, "version", opts.version)), true
+}
+
+// subject describes a provider entry in the current file for skips.
+func (opts *providerMigrationOptions) subject(name string) string {
+	return fmt.Sprintf("Provider %s in %s (source: %s)", quote(name, opts.outputFormat), opts.filename, quote(opts.fromSource, opts.outputFormat))
+}
+
+// expressionText returns the literal value of a string expression within its source text.
+func expressionText(expression []byte, expr hclsyntax.Expression) string {
+	valueRange := expr.Range()
+	return attributeExpressionStringValue(expression[valueRange.Start.Byte:valueRange.End.Byte])
+}
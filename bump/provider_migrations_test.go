@@ -0,0 +1,167 @@
+package bump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateProviderSourceHCL(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 6.0"
+    }
+    widget = {
+      source  = "registry.terraform.io/example-community/widget"
+      version = "~> 1.4" # pinned for the old namespace
+    }
+    gadget = { source = "example-community/widget" }
+  }
+}
+
+terraform {
+  required_providers {
+    widget {
+      source  = "example-community/widget"
+      version = "1.4.2"
+    }
+  }
+}
+`
+	want := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 6.0"
+    }
+    widget = {
+      source  = "example/widget"
+      version = "~> 2.0" # pinned for the old namespace
+    }
+    gadget = { source = "example/widget", version = "~> 2.0" }
+  }
+}
+
+terraform {
+  required_providers {
+    widget {
+      source  = "example/widget"
+      version = "~> 2.0"
+    }
+  }
+}
+`
+	file := writeTestFile(t, t.TempDir(), "versions.tf", input)
+	opts := providerMigrationOptions{fromSource: "example-community/widget", toSource: "example/widget", version: "~> 2.0", outputFormat: "text", events: printEvents(false)}
+
+	migrated, err := migrateProviderSourceWithCount(file, opts, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantMigrated := []migratedProvider{
+		{location: "0/0/attribute/gadget", name: "gadget"},
+		{location: "0/0/attribute/widget", name: "widget"},
+		{location: "1/0/block/0", name: "widget"},
+	}
+	if !reflect.DeepEqual(migrated, wantMigrated) {
+		t.Errorf("migrated = %#v, want %#v", migrated, wantMigrated)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestMigrateProviderSourceSkipsComputedVersions(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    widget = {
+      source  = "example-community/widget"
+      version = var.widget_version
+    }
+  }
+}
+`
+	file := writeTestFile(t, t.TempDir(), "versions.tf", input)
+	opts := providerMigrationOptions{fromSource: "example-community/widget", toSource: "example/widget", version: "~> 2.0", outputFormat: "text", events: printEvents(false)}
+
+	stderr := captureStderr(t, func() {
+		if migrated, err := migrateProviderSourceWithCount(file, opts, false); err != nil || len(migrated) != 0 {
+			t.Errorf("migrated=%v err=%v, want a skip", migrated, err)
+		}
+	})
+	if !strings.Contains(stderr, "non-literal version 'var.widget_version'") {
+		t.Errorf("stderr = %q, want the computed version reported", stderr)
+	}
+	if got := readTestFile(t, file); got != input {
+		t.Errorf("file changed:\n%s", got)
+	}
+}
+
+func TestMigrateProviderSourceJSON(t *testing.T) {
+	input := `{
+  "terraform": {
+    "required_providers": {
+      "widget": {"source": "example-community/widget", "version": "~> 1.4"},
+      "gadget": {"source": "example-community/widget"}
+    }
+  }
+}
+`
+	want := `{
+  "terraform": {
+    "required_providers": {
+      "widget": {"source": "example/widget", "version": "~> 2.0"},
+      "gadget": {"source": "example/widget", "version": "~> 2.0"}
+    }
+  }
+}
+`
+	file := writeTestFile(t, t.TempDir(), "versions.tf.json", input)
+	opts := providerMigrationOptions{fromSource: "example-community/widget", toSource: "example/widget", version: "~> 2.0", outputFormat: "text", events: printEvents(false)}
+
+	migrated, err := migrateProviderSourceWithCount(file, opts, false)
+	if err != nil || len(migrated) != 2 {
+		t.Fatalf("migrated=%v err=%v", migrated, err)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestMigrateProviderSourceOpenTofuRegistry(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    widget = {
+      source  = "registry.opentofu.org/example-community/widget"
+      version = "~> 1.4"
+    }
+  }
+}
+`
+	want := `terraform {
+  required_providers {
+    widget = {
+      source  = "example/widget"
+      version = "~> 2.0"
+    }
+  }
+}
+`
+	for _, opentofu := range []bool{false, true} {
+		file := writeTestFile(t, t.TempDir(), "versions.tf", input)
+		opts := providerMigrationOptions{fromSource: "example-community/widget", toSource: "example/widget", version: "~> 2.0", opentofu: opentofu, outputFormat: "text", events: printEvents(false)}
+
+		if _, err := migrateProviderSourceWithCount(file, opts, false); err != nil {
+			t.Fatalf("opentofu=%v: unexpected error: %v", opentofu, err)
+		}
+		wantContent := input
+		if opentofu {
+			wantContent = want
+		}
+		if got := readTestFile(t, file); got != wantContent {
+			t.Errorf("opentofu=%v: content mismatch:\n--- got ---\n%s--- want ---\n%s", opentofu, got, wantContent)
+		}
+	}
+}
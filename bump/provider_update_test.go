@@ -0,0 +1,519 @@
+package bump
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateProviderVersionContract(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		provider, version string
+		wantUpdated       bool
+	}{
+		{
+			name: "legacy provider block syntax", provider: "aws", version: "~> 5.0",
+			input: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+`, wantUpdated: true,
+		},
+		{
+			name: "attribute syntax preserves configuration aliases", provider: "aws", version: "~> 5.0",
+			input: `terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = "~> 4.0"
+      configuration_aliases = [aws.alternate]
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = "~> 5.0"
+      configuration_aliases = [aws.alternate]
+    }
+  }
+}
+`, wantUpdated: true,
+		},
+		{
+			name: "mixed providers only named provider changes", provider: "azurerm", version: "~> 3.5",
+			input: `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 3.0"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 3.5"
+    }
+  }
+}
+`, wantUpdated: true,
+		},
+		{
+			name: "mixed syntax across terraform blocks", provider: "aws", version: "~> 5.0",
+			input: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.1"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+`, wantUpdated: true,
+		},
+		{
+			name: "attribute provider without version is unchanged", provider: "aws", version: "~> 5.0",
+			input: `terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`, wantUpdated: false,
+		},
+		{
+			name: "block provider without version unchanged without force-add", provider: "aws", version: "~> 5.0",
+			input: `terraform {
+  required_providers {
+    aws {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws {
+      source = "hashicorp/aws"
+    }
+  }
+}
+`,
+		},
+		{
+			name: "missing provider unchanged", provider: "google", version: "~> 6.0",
+			input: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
+`,
+			want: `terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTestFile(t, t.TempDir(), "main.tf", tt.input)
+			updated, err := updateProviderVersion(filename, tt.provider, tt.version, false)
+			if err != nil {
+				t.Fatalf("updateProviderVersion returned error: %v", err)
+			}
+			if updated != tt.wantUpdated {
+				t.Fatalf("updated = %t, want %t", updated, tt.wantUpdated)
+			}
+			if got := readTestFile(t, filename); got != tt.want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateProviderVersionDryRunContract(t *testing.T) {
+	input := "terraform {\n  required_providers {\n    aws {\n      source = \"hashicorp/aws\"\n      version = \"~> 4.0\"\n    }\n  }\n}\n"
+	filename := writeTestFile(t, t.TempDir(), "main.tf", input)
+	updated, err := updateProviderVersion(filename, "aws", "~> 5.0", true)
+	if err != nil {
+		t.Fatalf("updateProviderVersion returned error: %v", err)
+	}
+	if !updated {
+		t.Fatal("updated = false, want true")
+	}
+	if got := readTestFile(t, filename); got != input {
+		t.Fatalf("dry run mutated file: got %q, want %q", got, input)
+	}
+}
+
+func TestUpdateProviderVersionErrors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		_, err := updateProviderVersion(t.TempDir()+"/missing.tf", "aws", "~> 5.0", false)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("error = %v, want os.ErrNotExist", err)
+		}
+	})
+	t.Run("directory read error", func(t *testing.T) {
+		updated, err := updateProviderVersion(t.TempDir(), "aws", "~> 5.0", false)
+		if updated || err == nil || !strings.Contains(err.Error(), "failed to read file:") {
+			t.Fatalf("updated=%v err=%v, want failed to read file", updated, err)
+		}
+	})
+	t.Run("malformed HCL", func(t *testing.T) {
+		filename := writeTestFile(t, t.TempDir(), "invalid.tf", "terraform {\n")
+		_, err := updateProviderVersion(filename, "aws", "~> 5.0", false)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse HCL:") {
+			t.Fatalf("error = %v, want failed to parse HCL", err)
+		}
+	})
+	t.Run("read-only file", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write read-only files")
+		}
+		filename := writeTestFile(t, t.TempDir(), "readonly.tf", "terraform {\n  required_providers {\n    aws {\n      source = \"hashicorp/aws\"\n      version = \"~> 4.0\"\n    }\n  }\n}\n")
+		if err := os.Chmod(filename, 0o400); err != nil {
+			t.Fatalf("chmod: %v", err)
+		}
+		_, err := updateProviderVersion(filename, "aws", "~> 5.0", false)
+		if err == nil || !strings.Contains(err.Error(), "failed to write file:") {
+			t.Fatalf("error = %v, want failed to write file", err)
+		}
+	})
+}
+
+func TestUpdateProviderVersionPreservesConstraintOperator(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 4.0, < 5.0"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 4.67"
+    }
+  }
+}
+`
+	want := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.30, < 6.0"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+  }
+}
+`
+	filename := writeTestFile(t, t.TempDir(), "main.tf", input)
+	updated, changedBlocks, err := updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: "5.30.0", preserveOperator: true, events: printEvents(false)}, false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if len(changedBlocks) != 2 {
+		t.Errorf("changed blocks = %v, want two", changedBlocks)
+	}
+	if got := readTestFile(t, filename); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateProviderVersionRefusesDowngrade(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.30"
+    }
+  }
+}
+
+terraform {
+  required_providers {
+    aws {
+      source  = "hashicorp/aws"
+      version = "5.31.0"
+    }
+  }
+}
+`
+	filename := writeTestFile(t, t.TempDir(), "main.tf", input)
+	var updated bool
+	var err error
+	stderr := captureStderr(t, func() {
+		updated, err = updateProviderVersion(filename, "aws", "~> 4.67", false)
+	})
+	if err != nil || updated {
+		t.Fatalf("updated=%v err=%v, want skipped downgrades", updated, err)
+	}
+	if got := strings.Count(stderr, "Provider 'aws' in "+filename+" is at version"); got != 2 {
+		t.Errorf("stderr = %q, want two downgrade warnings", stderr)
+	}
+	if got := readTestFile(t, filename); got != input {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, input)
+	}
+
+	updated, changedBlocks, err := updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: "~> 4.67", allowDowngrade: true, events: printEvents(false)}, false)
+	if err != nil || !updated || len(changedBlocks) != 2 {
+		t.Fatalf("updated=%v changed=%v err=%v, want two allowed downgrades", updated, changedBlocks, err)
+	}
+}
+
+func TestUpdateProviderVersionPrereleasePolicy(t *testing.T) {
+	input := "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.30\"\n    }\n  }\n}\n"
+	filename := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	// Preserving the operator of "~> 5.30" would write "~> 6.0", which drops the pre-release, so
+	// the policy is checked against the configured target.
+	for _, preserveOperator := range []bool{false, true} {
+		var updated bool
+		var err error
+		stderr := captureStderr(t, func() {
+			updated, _, err = updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: "6.0.0-beta1", preserveOperator: preserveOperator, prerelease: prereleaseExclude, outputFormat: "text", events: printEvents(false)}, false)
+		})
+		if err != nil || updated {
+			t.Fatalf("preserveOperator=%v: updated=%v err=%v, want the pre-release skipped", preserveOperator, updated, err)
+		}
+		if want := "Warning: Provider 'aws' in " + filename + " would be set to pre-release '6.0.0-beta1', skipping (prerelease policy 'exclude')\n"; stderr != want {
+			t.Errorf("preserveOperator=%v: stderr = %q, want %q", preserveOperator, stderr, want)
+		}
+		if got := readTestFile(t, filename); got != input {
+			t.Errorf("preserveOperator=%v: content = %q, want unchanged", preserveOperator, got)
+		}
+	}
+}
+
+func TestUpdateProviderVersionForceAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		want     string
+	}{
+		{
+			name: "multi-line object", filename: "main.tf",
+			input: "terraform {\n  required_providers {\n    aws = {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+			want:  "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 6.0\"\n    }\n  }\n}\n",
+		},
+		{
+			name: "single-line object", filename: "main.tf",
+			input: "terraform {\n  required_providers {\n    aws = { source = \"hashicorp/aws\" }\n  }\n}\n",
+			want:  "terraform {\n  required_providers {\n    aws = { source = \"hashicorp/aws\", version = \"~> 6.0\" }\n  }\n}\n",
+		},
+		{
+			name: "object with trailing comma", filename: "main.tf",
+			input: "terraform {\n  required_providers {\n    aws = {\n      source                = \"hashicorp/aws\",\n      configuration_aliases = [aws.east],\n    }\n  }\n}\n",
+			want:  "terraform {\n  required_providers {\n    aws = {\n      source                = \"hashicorp/aws\",\n      configuration_aliases = [aws.east]\n      version               = \"~> 6.0\",\n    }\n  }\n}\n",
+		},
+		{
+			name: "block syntax", filename: "main.tf",
+			input: "terraform {\n  required_providers {\n    aws {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+			want:  "terraform {\n  required_providers {\n    aws {\n      source  = \"hashicorp/aws\"\n      version = \"~> 6.0\"\n    }\n  }\n}\n",
+		},
+		{
+			name: "JSON object", filename: "main.tf.json",
+			input: "{\"terraform\": {\"required_providers\": {\"aws\": {\"source\": \"hashicorp/aws\"}}}}\n",
+			want:  "{\"terraform\": {\"required_providers\": {\"aws\": {\"source\": \"hashicorp/aws\", \"version\": \"~> 6.0\"}}}}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTestFile(t, t.TempDir(), tt.filename, tt.input)
+			updated, changedBlocks, err := updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: "~> 6.0", forceAdd: true, outputFormat: "text", events: printEvents(false)}, false)
+			if err != nil || !updated || len(changedBlocks) != 1 {
+				t.Fatalf("updated=%v changed=%v err=%v", updated, changedBlocks, err)
+			}
+			if got := readTestFile(t, filename); got != tt.want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateProviderVersionWarnsAboutMissingVersion(t *testing.T) {
+	inputs := map[string]string{
+		"object.tf":    "terraform {\n  required_providers {\n    aws = { source = \"hashicorp/aws\" }\n  }\n}\n",
+		"block.tf":     "terraform {\n  required_providers {\n    aws {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+		"main.tf.json": `{"terraform": {"required_providers": {"aws": {"source": "hashicorp/aws"}}}}`,
+	}
+	for name, content := range inputs {
+		filename := writeTestFile(t, t.TempDir(), name, content)
+
+		var updated bool
+		var err error
+		stderr := captureStderr(t, func() {
+			updated, _, err = updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: "~> 6.0", outputFormat: "text", events: printEvents(false)}, false)
+		})
+		if err != nil || updated {
+			t.Fatalf("%s: updated=%v err=%v, want a skip", name, updated, err)
+		}
+		if want := "Warning: Provider 'aws' in " + filename + " has no version constraint, skipping (use force-add to add one)\n"; stderr != want {
+			t.Errorf("%s: stderr = %q, want %q", name, stderr, want)
+		}
+	}
+}
+
+func TestUpdateProviderVersionAddsMissingEntry(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+terraform {
+  required_version = ">= 1.5"
+}
+
+terraform {
+  required_providers {
+    random {
+      source  = "hashicorp/random"
+      version = "~> 3.5"
+    }
+  }
+}
+`
+	want := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.6"
+    }
+  }
+}
+
+terraform {
+  required_version = ">= 1.5"
+}
+
+terraform {
+  required_providers {
+    random {
+      source  = "hashicorp/random"
+      version = "~> 3.6"
+    }
+  }
+}
+`
+	filename := writeTestFile(t, t.TempDir(), "versions.tf", input)
+
+	opts := &providerUpdateOptions{providerName: "random", version: "~> 3.6", addSource: "hashicorp/random", outputFormat: "text", events: printEvents(false)}
+	updated, changedBlocks, err := updateProviderVersionWithCount(filename, opts, false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if want := []string{"0/0/attribute/random", "2/0/block/0"}; !reflect.DeepEqual(changedBlocks, want) {
+		t.Errorf("changed blocks = %v, want %v", changedBlocks, want)
+	}
+	if got := readTestFile(t, filename); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateProviderVersionAddsMissingJSONEntry(t *testing.T) {
+	input := "{\n  \"terraform\": {\n    \"required_providers\": {\n      \"aws\": {\"source\": \"hashicorp/aws\", \"version\": \"~> 5.0\"}\n    }\n  }\n}\n"
+	want := "{\n  \"terraform\": {\n    \"required_providers\": {\n      \"aws\": {\"source\": \"hashicorp/aws\", \"version\": \"~> 5.0\"},\n      \"random\": {\"source\": \"hashicorp/random\", \"version\": \"~> 3.6\"}\n    }\n  }\n}\n"
+	filename := writeTestFile(t, t.TempDir(), "versions.tf.json", input)
+
+	opts := &providerUpdateOptions{providerName: "random", version: "~> 3.6", addSource: "hashicorp/random", outputFormat: "text", events: printEvents(false)}
+	if updated, _, err := updateProviderVersionWithCount(filename, opts, false); err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if got := readTestFile(t, filename); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
@@ -0,0 +1,425 @@
+package bump
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// editProviderVersions updates a provider version in a parsed file of any syntax and counts
+// blocks whose values changed.
+//
+// This implementation supports both provider syntax styles:
+//
+// Block-based syntax:
+//
+//	required_providers { aws { source = "..." version = "..." } }
+//
+// Attribute-based syntax:
+//
+//	required_providers { aws = { source = "..." version = "..." } }
+//
+// Parameters:
+//   - file: The parsed file to edit; followed references are added to its reference edits
+//   - opts: The provider name, target version, and behaviour switches to apply
+//
+// Returns:
+//   - updated: true if a provider operation was applied (or would be applied in dry-run mode)
+//   - changedBlocks: locations of provider blocks whose version values differ from the target
+//   - error: Any error encountered while parsing a JSON syntax file
+func editProviderVersions(file *File, opts *providerUpdateOptions) (updated bool, changedBlocks []string, err error) {
+	switch file.syntax {
+	case jsonSyntax:
+		file.json, updated, changedBlocks, err = updateProviderVersionJSON(file.json, opts)
+	case terragruntSyntax:
+		updated, changedBlocks = editProviderVersionTerragrunt(file.HCL, opts)
+	default:
+		updated, changedBlocks = editProviderVersion(file.HCL, opts)
+	}
+	file.referenceEdits = append(file.referenceEdits, opts.referenceEdits...)
+	return updated, changedBlocks, err
+}
+
+// editProviderVersion updates a provider in the required_providers blocks of a parsed file.
+func editProviderVersion(file *hclwrite.File, opts *providerUpdateOptions) (updated bool, changedBlocks []string) {
+	// Iterate through all blocks in the file
+	for blockIndex, block := range file.Body().Blocks() {
+		blockUpdated, blockChanges := updateProviderTerraformBlockResult(block, opts)
+		updated = updated || blockUpdated
+		for _, blockChange := range blockChanges {
+			changedBlocks = append(changedBlocks, fmt.Sprintf("%d/%s", blockIndex, blockChange))
+		}
+	}
+	return updated, changedBlocks
+}
+
+// providerUpdateOptions describes one provider version update within required_providers blocks.
+// When addSource is set, required_providers blocks that do not declare the provider receive an
+// entry with that source.
+type providerUpdateOptions struct {
+	filename         string
+	providerName     string
+	version          string
+	fromVersions     []string // when set, only entries whose current version is listed are updated
+	fromSource       string   // when set, only entries with this source address are updated
+	forceAdd         bool
+	addSource        string
+	preserveOperator bool
+	allowDowngrade   bool
+	prerelease       string
+	terragrunt       bool
+	opentofu         bool
+	followReferences bool
+	referenceEdits   []referenceEdit
+	outputFormat     string
+	events           eventSink
+	confirm          func(Proposal) bool
+	beforeWrite      func(string) error
+	previous         versionSet
+}
+
+// targetVersion returns the value to write in place of the current version constraint.
+func (opts *providerUpdateOptions) targetVersion(currentVersion string) string {
+	if opts.preserveOperator {
+		return preserveConstraintOperator(currentVersion, opts.version)
+	}
+	return opts.version
+}
+
+// matchesSource reports whether an entry with the given source passes the fromSource filter. An
+// entry without a source, given as "", uses the provider of that name in the hashicorp namespace.
+func (opts *providerUpdateOptions) matchesSource(source string) bool {
+	if opts.fromSource == "" {
+		return true
+	}
+	if source == "" {
+		source = "hashicorp/" + opts.providerName
+	}
+	return sameProviderAddress(source, opts.fromSource, opts.opentofu)
+}
+
+// subject describes the provider in the current file for skips.
+func (opts *providerUpdateOptions) subject() string {
+	return fmt.Sprintf("Provider %s in %s", quote(opts.providerName, opts.outputFormat), opts.filename)
+}
+
+// followVersion updates the definition behind a provider version that references a local value
+// or variable, applying the same checks as a literal version.
+func (opts *providerUpdateOptions) followVersion(expression []byte) (updated, changed bool) {
+	definition, ok := followVersionReference(opts.events, opts.subject(), expression, filepath.Dir(opts.filename), opts.followReferences, opts.outputFormat)
+	if !ok {
+		return false, false
+	}
+	newVersion, ok := opts.resolveVersion(definition.value)
+	if !ok {
+		return false, false
+	}
+	opts.referenceEdits = append(opts.referenceEdits, referenceEdit{definition: definition, value: newVersion})
+	return true, definition.value != newVersion
+}
+
+// resolveVersion returns the value to write in place of the current version constraint, or false
+// when the provider entry must be left alone because the update would be a downgrade or a
+// pre-release that the policy forbids, or because it does not match the from filter. An empty
+// currentVersion means no version is written yet.
+func (opts *providerUpdateOptions) resolveVersion(currentVersion string) (string, bool) {
+	if len(opts.fromVersions) > 0 && !containsVersion(opts.fromVersions, currentVersion) {
+		return "", false
+	}
+	newVersion := opts.targetVersion(currentVersion)
+	if refuseVersionChange(opts.events, opts.subject(), currentVersion, opts.version, newVersion, opts.allowDowngrade, opts.prerelease, opts.outputFormat) {
+		return "", false
+	}
+	proposal := Proposal{Kind: ProviderChange, File: opts.filename, Name: opts.providerName, Current: currentVersion, Target: newVersion,
+		Attribute: "version", Before: currentVersion, After: newVersion}
+	if !confirmChange(opts.confirm, opts.events, opts.subject(), proposal, opts.outputFormat) {
+		return "", false
+	}
+	opts.previous.replace(currentVersion, newVersion)
+	return newVersion, true
+}
+
+// refuseVersionChange reports whether writing newVersion over currentVersion must be skipped,
+// either as a refused downgrade or as a pre-release that the policy forbids. The policy is checked
+// against the configured target rather than newVersion, because preserving a lower-precision
+// operator drops the target's pre-release. The skip reason is reported to events.
+func refuseVersionChange(events eventSink, subject, currentVersion, target, newVersion string, allowDowngrade bool, prereleasePolicy, outputFormat string) bool {
+	return refuseDowngrade(events, subject, currentVersion, newVersion, allowDowngrade, outputFormat) ||
+		refusePrerelease(events, subject, currentVersion, target, prereleasePolicy, outputFormat)
+}
+
+// refusePrerelease reports whether an update must be skipped because newVersion names a
+// pre-release that the policy forbids (see prereleasePermitted), reporting the skip reason.
+func refusePrerelease(events eventSink, subject, currentVersion, newVersion, policy, outputFormat string) bool {
+	if prereleasePermitted(policy, currentVersion, newVersion) {
+		return false
+	}
+	events.skip("%s would be set to pre-release %s, skipping (prerelease policy %s)",
+		subject, quote(newVersion, outputFormat), quote(policy, outputFormat))
+	return true
+}
+
+// refuseDowngrade reports whether an update must be skipped because newVersion would move the
+// constraint below the versions currentVersion permits (see isDowngrade). The skip reason is
+// reported to events.
+//
+// Parameters:
+//   - events: Receives the skip reason
+//   - subject: Description of the block being updated, used at the start of the skip message
+//   - currentVersion: The constraint currently written in the file
+//   - newVersion: The constraint that would be written
+//   - allowDowngrade: If true, downgrades are applied and nothing is refused
+//   - outputFormat: Output format ("text" or "md")
+//
+// Returns:
+//   - bool: true if the update is a downgrade that must be skipped
+func refuseDowngrade(events eventSink, subject, currentVersion, newVersion string, allowDowngrade bool, outputFormat string) bool {
+	if allowDowngrade || !isDowngrade(currentVersion, newVersion) {
+		return false
+	}
+	events.skip("%s is at version %s, which is newer than %s; skipping downgrade (use allow_downgrade to override)",
+		subject, quote(currentVersion, outputFormat), quote(newVersion, outputFormat))
+	return true
+}
+
+func updateProviderTerraformBlockResult(block *hclwrite.Block, opts *providerUpdateOptions) (updated bool, changedBlocks []string) {
+	if block.Type() != "terraform" {
+		return false, nil
+	}
+
+	updated = false
+	changedBlocks = nil
+	for nestedIndex, nestedBlock := range block.Body().Blocks() {
+		if nestedBlock.Type() != "required_providers" {
+			continue
+		}
+		if !hasProviderEntry(nestedBlock, opts.providerName) {
+			if opts.addSource != "" && addProviderEntry(nestedBlock, opts) {
+				updated = true
+				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/attribute/%s", nestedIndex, opts.providerName))
+			}
+			continue
+		}
+		blockSyntaxUpdated, blockSyntaxChanges := updateProviderBlockSyntaxResult(nestedBlock, opts)
+		if blockSyntaxUpdated {
+			updated = true
+			for _, blockSyntaxIndex := range blockSyntaxChanges {
+				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/block/%d", nestedIndex, blockSyntaxIndex))
+			}
+			continue
+		}
+		attributeUpdated, attributeChanged := updateProviderAttributeVersionResult(nestedBlock, opts)
+		if attributeUpdated {
+			updated = true
+			if attributeChanged {
+				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/attribute/%s", nestedIndex, opts.providerName))
+			}
+		}
+	}
+
+	return updated, changedBlocks
+}
+
+func updateProviderBlockSyntaxResult(nestedBlock *hclwrite.Block, opts *providerUpdateOptions) (updated bool, changedBlocks []int) {
+	updated = false
+	changedBlocks = nil
+	for providerIndex, providerBlock := range nestedBlock.Body().Blocks() {
+		if providerBlock.Type() != opts.providerName {
+			continue
+		}
+		if !opts.matchesSource(providerBlockSource(providerBlock)) {
+			continue
+		}
+		currentVersion := ""
+		versionAttribute := providerBlock.Body().GetAttribute("version")
+		if versionAttribute != nil && !isLiteralString(versionAttribute) {
+			if referenceUpdated, referenceChanged := opts.followVersion(versionAttribute.Expr().BuildTokens(nil).Bytes()); referenceUpdated {
+				updated = true
+				if referenceChanged {
+					changedBlocks = append(changedBlocks, providerIndex)
+				}
+			}
+			continue
+		}
+		var newVersion string
+		var ok bool
+		if versionAttribute == nil {
+			newVersion, ok = opts.resolveMissingVersion()
+		} else {
+			currentVersion = attributeStringValue(versionAttribute)
+			newVersion, ok = opts.resolveVersion(currentVersion)
+		}
+		if !ok {
+			continue
+		}
+		if versionAttribute == nil || currentVersion != newVersion {
+			changedBlocks = append(changedBlocks, providerIndex)
+		}
+		providerBlock.Body().SetAttributeValue("version", cty.StringVal(newVersion))
+		updated = true
+	}
+
+	return updated, changedBlocks
+}
+
+// providerBlockSource returns the source of a provider entry in block syntax, or "" when it has
+// none.
+func providerBlockSource(providerBlock *hclwrite.Block) string {
+	if sourceAttribute := providerBlock.Body().GetAttribute("source"); sourceAttribute != nil {
+		return attributeStringValue(sourceAttribute)
+	}
+	return ""
+}
+
+// updateProviderAttributeVersion updates the version value within a provider attribute's object expression
+// This handles the attribute-based syntax: aws = { source = "..." version = "..." }
+func updateProviderAttributeVersionResult(nestedBlock *hclwrite.Block, opts *providerUpdateOptions) (updated, changed bool) {
+	providerName := opts.providerName
+	objExpr, expression, ok := providerAttributeObject(nestedBlock, providerName)
+	if !ok || !opts.matchesSource(providerObjectSource(objExpr, expression)) {
+		return false, false
+	}
+
+	var updatedExpression []byte
+	var applied bool
+	versionExpr := providerObjectVersion(objExpr)
+	switch {
+	case versionExpr == nil:
+		if newVersion, ok := opts.resolveMissingVersion(); ok {
+			updatedExpression, applied, changed = insertProviderObjectVersion(objExpr, expression, newVersion), true, true
+		}
+	case !isStringLiteral(versionExpr):
+		valueRange := versionExpr.Range()
+		return opts.followVersion(expression[valueRange.Start.Byte:valueRange.End.Byte])
+	default:
+		updatedExpression, applied, changed = replaceProviderObjectVersion(objExpr, expression, opts.resolveVersion)
+	}
+	if !applied {
+		return false, false
+	}
+
+	if !setProviderObject(nestedBlock, providerName, updatedExpression) {
+		return false, false
+	}
+	return true, changed
+}
+
+// providerObjectSource returns the source of a provider entry in attribute syntax, or "" when it
+// has none.
+func providerObjectSource(objExpr *hclsyntax.ObjectConsExpr, expression []byte) string {
+	if sourceExpr := providerObjectItem(objExpr, "source"); sourceExpr != nil {
+		return expressionText(expression, sourceExpr)
+	}
+	return ""
+}
+
+// setProviderObject replaces a provider attribute's expression with the given source text.
+func setProviderObject(nestedBlock *hclwrite.Block, providerName string, expression []byte) bool {
+	newAttribute := append([]byte(providerName+" = "), expression...)
+	newExpr, diags := hclwrite.ParseConfig(newAttribute, "inline", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false
+	}
+
+	for _, newAttr := range newExpr.Body().Attributes() {
+		nestedBlock.Body().SetAttributeRaw(providerName, newAttr.Expr().BuildTokens(nil))
+		return true
+	}
+
+	return false
+}
+
+func providerAttributeObject(nestedBlock *hclwrite.Block, providerName string) (*hclsyntax.ObjectConsExpr, []byte, bool) {
+	attr, exists := nestedBlock.Body().Attributes()[providerName]
+	if !exists {
+		return nil, nil, false
+	}
+
+	expression := attr.Expr().BuildTokens(nil).Bytes()
+	objExpr, ok := parseProviderObject(expression)
+	return objExpr, expression, ok
+}
+
+// parseProviderObject parses the source text of a provider attribute's object expression.
+func parseProviderObject(expression []byte) (*hclsyntax.ObjectConsExpr, bool) {
+	expr, diags := hclsyntax.ParseExpression(expression, "inline", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, false
+	}
+
+	objExpr, ok := expr.(*hclsyntax.ObjectConsExpr)
+	return objExpr, ok
+}
+
+// replaceProviderObjectVersion splices new version values into a provider object expression.
+// resolveVersion maps each current value to its replacement, or returns false to leave it alone.
+//
+// Returns:
+//   - updated: The rewritten expression
+//   - applied: true if at least one version value was accepted by resolveVersion
+//   - changed: true if any version value differs from its replacement
+func replaceProviderObjectVersion(objExpr *hclsyntax.ObjectConsExpr, expression []byte, resolveVersion func(string) (string, bool)) (updated []byte, applied, changed bool) {
+	updated = append([]byte(nil), expression...)
+	applied = false
+	changed = false
+
+	for index := len(objExpr.Items) - 1; index >= 0; index-- {
+		item := objExpr.Items[index]
+		keyName, ok := providerObjectItemKey(item)
+		if !ok || keyName != "version" {
+			continue
+		}
+
+		valueRange := item.ValueExpr.Range()
+		if valueRange.Start.Byte < 0 || valueRange.End.Byte > len(expression) || valueRange.Start.Byte > valueRange.End.Byte {
+			return nil, false, false
+		}
+		currentVersion := attributeExpressionStringValue(expression[valueRange.Start.Byte:valueRange.End.Byte])
+		newVersion, ok := resolveVersion(currentVersion)
+		if !ok {
+			continue
+		}
+		applied = true
+		if currentVersion == newVersion {
+			continue
+		}
+		newValue := hclwrite.TokensForValue(cty.StringVal(newVersion)).Bytes()
+
+		next := make([]byte, 0, len(updated)-valueRange.End.Byte+valueRange.Start.Byte+len(newValue))
+		next = append(next, updated[:valueRange.Start.Byte]...)
+		next = append(next, newValue...)
+		next = append(next, updated[valueRange.End.Byte:]...)
+		updated = next
+		changed = true
+	}
+
+	return updated, applied, changed
+}
+
+func attributeExpressionStringValue(expression []byte) string {
+	return trimQuotes(strings.TrimSpace(string(expression)))
+}
+
+func providerObjectItemKey(item hclsyntax.ObjectConsItem) (string, bool) {
+	keyExpr, ok := item.KeyExpr.(*hclsyntax.ObjectConsKeyExpr)
+	if !ok {
+		return "", false
+	}
+
+	traversal, ok := keyExpr.Wrapped.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(traversal.Traversal) == 0 {
+		return "", false
+	}
+
+	rootName, ok := traversal.Traversal[0].(hcl.TraverseRoot)
+	if !ok {
+		return "", false
+	}
+
+	return rootName.Name, true
+}
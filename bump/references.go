@@ -0,0 +1,236 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Version and source arguments are usually quoted literals, but a configuration can also compute
+// them:
+//
+//	locals {
+//	  vpc_version = "5.0.0"
+//	}
+//
+//	module "vpc" {
+//	  source  = "terraform-aws-modules/vpc/aws"
+//	  version = local.vpc_version
+//	}
+//
+// Such expressions are never compared or overwritten as text; they are skipped with a reason.
+// When references are followed, an expression that is exactly local.<name> or var.<name> is
+// resolved to the literal locals entry or variable default in the same directory, and that
+// definition is updated instead.
+
+// expressionKind classifies the expression assigned to a version argument.
+type expressionKind int
+
+const (
+	literalExpression expressionKind = iota
+	referenceExpression
+	computedExpression
+)
+
+// versionReference names a local value or an input variable.
+type versionReference struct {
+	kind string // "local" or "var"
+	name string
+}
+
+func (ref versionReference) String() string {
+	return ref.kind + "." + ref.name
+}
+
+// classifyExpression classifies an expression's source text, returning the reference it names
+// when it is exactly local.<name> or var.<name>.
+func classifyExpression(expression []byte) (expressionKind, versionReference) {
+	expr, diags := hclsyntax.ParseExpression(expression, "inline", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return computedExpression, versionReference{}
+	}
+	if isStringLiteral(expr) {
+		return literalExpression, versionReference{}
+	}
+	if traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr); ok {
+		if ref, ok := traversalReference(traversal.Traversal); ok {
+			return referenceExpression, ref
+		}
+	}
+	return computedExpression, versionReference{}
+}
+
+// isStringLiteral reports whether an expression is a quoted string without interpolation.
+func isStringLiteral(expr hclsyntax.Expression) bool {
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	return ok && template.IsStringLiteral()
+}
+
+func traversalReference(traversal hcl.Traversal) (versionReference, bool) {
+	if len(traversal) != 2 {
+		return versionReference{}, false
+	}
+	root := traversal.RootName()
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok || (root != "local" && root != "var") {
+		return versionReference{}, false
+	}
+	return versionReference{kind: root, name: attr.Name}, true
+}
+
+// reportComputedVersion reports that a version expression cannot be updated.
+func reportComputedVersion(events eventSink, subject, expression, hint, outputFormat string) {
+	events.skip("%s has a non-literal version %s, skipping%s", subject, quote(expression, outputFormat), hint)
+}
+
+// followVersionReference resolves a non-literal version expression to the definition it names
+// when references are followed, reporting why the expression is skipped otherwise.
+//
+// Parameters:
+//   - events: Receives the reason an expression is skipped
+//   - subject: Description of the block being updated, used at the start of skip messages
+//   - expression: Source text of the version expression
+//   - dir: Directory of the module that contains the expression
+//   - follow: If true, local.<name> and var.<name> references are resolved
+//   - outputFormat: Output format ("text" or "md")
+//
+// Returns:
+//   - referenceDefinition: The literal definition the expression names
+//   - bool: false when the expression must be skipped
+func followVersionReference(events eventSink, subject string, expression []byte, dir string, follow bool, outputFormat string) (referenceDefinition, bool) {
+	text := strings.TrimSpace(string(expression))
+	kind, ref := classifyExpression(expression)
+	if kind != referenceExpression || !follow {
+		hint := ""
+		if kind == referenceExpression {
+			hint = " (use -follow-references to update its definition)"
+		}
+		reportComputedVersion(events, subject, text, hint, outputFormat)
+		return referenceDefinition{}, false
+	}
+
+	definition, err := resolveReference(dir, ref)
+	if err != nil {
+		events.skip("%s has version %s that cannot be followed: %v, skipping", subject, quote(text, outputFormat), err)
+		return referenceDefinition{}, false
+	}
+	return definition, true
+}
+
+// referenceDefinition is the literal value that a reference resolves to.
+type referenceDefinition struct {
+	ref      versionReference
+	filename string
+	value    string
+}
+
+// referenceEdit sets a followed definition to a new value.
+type referenceEdit struct {
+	definition referenceDefinition
+	value      string
+}
+
+// resolveReference finds the literal locals entry or variable default that a reference names
+// among the .tf and .tofu files of a directory. Values from .tfvars files and -var options are
+// not considered.
+func resolveReference(dir string, ref versionReference) (referenceDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return referenceDefinition{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".tf") && !strings.HasSuffix(name, ".tofu")) {
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return referenceDefinition{}, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		body, attrName := definitionBody(file.Body(), ref)
+		if body == nil {
+			continue
+		}
+		attr := body.GetAttribute(attrName)
+		if attr == nil || !isLiteralString(attr) {
+			return referenceDefinition{}, fmt.Errorf("%s in %s is not defined as a literal string", ref, filename)
+		}
+		return referenceDefinition{ref: ref, filename: filename, value: attributeStringValue(attr)}, nil
+	}
+	return referenceDefinition{}, fmt.Errorf("%s is not defined in %s", ref, dir)
+}
+
+// definitionBody returns the body and attribute name that define a reference: the locals block
+// containing the local value, or the variable block and its default.
+func definitionBody(body *hclwrite.Body, ref versionReference) (*hclwrite.Body, string) {
+	for _, block := range body.Blocks() {
+		switch {
+		case ref.kind == "local" && block.Type() == "locals" && block.Body().GetAttribute(ref.name) != nil:
+			return block.Body(), ref.name
+		case ref.kind == "var" && block.Type() == "variable" && moduleBlockName(block) == ref.name:
+			return block.Body(), "default"
+		}
+	}
+	return nil, ""
+}
+
+// applyReferenceEdits writes followed definitions once the file that referenced them has been
+// processed, and reports each definition that changes. A definition referenced by several blocks
+// is written once.
+//
+// Parameters:
+//   - events: Receives a ReferenceChange for each definition that changes
+//   - edits: Definitions to set, in the order they were resolved
+//   - dryRun: If true, report the changes without writing files
+//   - beforeWrite: When set, called before each definition file is written
+//
+// Returns:
+//   - error: Any error encountered while reading, parsing, or writing a definition file
+func applyReferenceEdits(events eventSink, edits []referenceEdit, dryRun bool, beforeWrite func(string) error) error {
+	applied := make(map[referenceEdit]bool)
+	for _, edit := range edits {
+		if edit.definition.value == edit.value || applied[edit] {
+			continue
+		}
+		applied[edit] = true
+		if !dryRun {
+			if err := writeReferenceEdit(edit, beforeWrite); err != nil {
+				return err
+			}
+		}
+		events.emit(Change{Kind: ReferenceChange, File: edit.definition.filename, Name: edit.definition.ref.String(), Version: edit.value})
+	}
+	return nil
+}
+
+func writeReferenceEdit(edit referenceEdit, beforeWrite func(string) error) error {
+	filename := edit.definition.filename
+	_, err := rewriteFile(filename, false, beforeWrite, func(src []byte) ([]byte, bool, error) {
+		file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, false, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+		}
+		body, attrName := definitionBody(file.Body(), edit.definition.ref)
+		if body == nil {
+			return nil, false, fmt.Errorf("%s is no longer defined", edit.definition.ref)
+		}
+		body.SetAttributeValue(attrName, cty.StringVal(edit.value))
+		return hclwrite.Format(file.Bytes()), true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package bump
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantKind   expressionKind
+		wantRef    string
+	}{
+		{`"5.0.0"`, literalExpression, ""},
+		{`local.vpc_version`, referenceExpression, "local.vpc_version"},
+		{` var.aws_version`, referenceExpression, "var.aws_version"},
+		{`"${local.major}.0.0"`, computedExpression, ""},
+		{`var.versions["vpc"]`, computedExpression, ""},
+		{`local.versions.vpc`, computedExpression, ""},
+		{`module.shared.version`, computedExpression, ""},
+		{`coalesce(var.version, "5.0.0")`, computedExpression, ""},
+	}
+
+	for _, tt := range tests {
+		kind, ref := classifyExpression([]byte(tt.expression))
+		gotRef := ""
+		if kind == referenceExpression {
+			gotRef = ref.String()
+		}
+		if kind != tt.wantKind || gotRef != tt.wantRef {
+			t.Errorf("classifyExpression(%q) = %v, %q, want %v, %q", tt.expression, kind, gotRef, tt.wantKind, tt.wantRef)
+		}
+	}
+}
+
+func TestUpdateModuleVersionSkipsNonLiteralExpressions(t *testing.T) {
+	input := `module "referenced" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = local.vpc_version
+}
+
+module "computed" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "${local.major}.0.0"
+}
+
+module "computed_source" {
+  source  = "${local.registry}/vpc/aws"
+  version = "4.0.0"
+}
+
+module "literal" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+`
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "main.tf", input)
+	writeTestFile(t, dir, "locals.tf", "locals {\n  vpc_version = \"4.0.0\"\n}\n")
+
+	streams := captureStdoutAndStderr(t, func() {
+		opts := moduleUpdateOptions{filename: file, moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", events: printEvents(true), outputFormat: "text"}
+		if _, _, err := updateModuleVersionWithCount(file, opts, false); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	want := strings.Replace(input, "version = \"4.0.0\"\n}\n", "version = \"5.0.0\"\n}\n", 2)
+	want = strings.Replace(want, "source  = \"${local.registry}/vpc/aws\"\n  version = \"5.0.0\"", "source  = \"${local.registry}/vpc/aws\"\n  version = \"4.0.0\"", 1)
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "locals.tf")); !strings.Contains(got, `"4.0.0"`) {
+		t.Errorf("locals.tf changed without -follow-references:\n%s", got)
+	}
+	for _, message := range []string{
+		"non-literal version 'local.vpc_version', skipping (use -follow-references to update its definition)",
+		"non-literal version '\"${local.major}.0.0\"', skipping\n",
+		"Module 'computed_source' in " + file + " has a non-literal source '\"${local.registry}/vpc/aws\"', skipping\n",
+	} {
+		if !strings.Contains(streams.stderr, message) {
+			t.Errorf("stderr = %q, want %q", streams.stderr, message)
+		}
+	}
+}
+
+func TestUpdateModuleVersionFollowsReferences(t *testing.T) {
+	input := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = local.vpc_version
+}
+
+module "vpc_v2" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = var.vpc_version
+}
+`
+	locals := `locals {
+  region      = "eu-west-1"
+  vpc_version = "4.0.0" # pinned
+}
+`
+	variables := `variable "vpc_version" {
+  type    = string
+  default = "~> 4.0"
+}
+`
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		wantLocals    string
+		wantVariables string
+	}{
+		{"write", false, strings.Replace(locals, `"4.0.0"`, `"5.0.0"`, 1), strings.Replace(variables, `"~> 4.0"`, `"5.0.0"`, 1)},
+		{"dry run", true, locals, variables},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := writeTestFile(t, dir, "main.tf", input)
+			localsFile := writeTestFile(t, dir, "locals.tf", locals)
+			variablesFile := writeTestFile(t, dir, "variables.tf", variables)
+
+			var updated bool
+			var changed []int
+			stdout := captureStdout(t, func() {
+				opts := moduleUpdateOptions{filename: file, moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", followReferences: true, outputFormat: "text", events: printEvents(false)}
+				var err error
+				updated, changed, err = updateModuleVersionWithCount(file, opts, tt.dryRun)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+
+			if !updated || len(changed) != 2 {
+				t.Errorf("updated=%v changed=%v, want both modules reported", updated, changed)
+			}
+			if got := readTestFile(t, file); got != input {
+				t.Errorf("main.tf changed:\n%s", got)
+			}
+			if got := readTestFile(t, localsFile); got != tt.wantLocals {
+				t.Errorf("locals.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.wantLocals)
+			}
+			if got := readTestFile(t, variablesFile); got != tt.wantVariables {
+				t.Errorf("variables.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.wantVariables)
+			}
+			if !strings.Contains(stdout, "Updated local.vpc_version to '5.0.0' in "+localsFile) {
+				t.Errorf("stdout = %q, want the local value reported", stdout)
+			}
+		})
+	}
+}
+
+func TestFollowReferencesReportsUnresolvableDefinitions(t *testing.T) {
+	input := `module "missing" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = var.undefined
+}
+
+module "computed" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = local.computed
+}
+`
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "main.tf", input)
+	writeTestFile(t, dir, "locals.tf", "locals {\n  computed = format(\"%s.0.0\", var.major)\n}\n")
+
+	stderr := captureStderr(t, func() {
+		opts := moduleUpdateOptions{filename: file, moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", followReferences: true, outputFormat: "text", events: printEvents(false)}
+		if updated, _, err := updateModuleVersionWithCount(file, opts, false); err != nil || updated {
+			t.Errorf("updated=%v err=%v, want both modules skipped", updated, err)
+		}
+	})
+
+	for _, message := range []string{
+		"var.undefined is not defined in " + dir,
+		"local.computed in " + filepath.Join(dir, "locals.tf") + " is not defined as a literal string",
+	} {
+		if !strings.Contains(stderr, message) {
+			t.Errorf("stderr = %q, want %q", stderr, message)
+		}
+	}
+}
+
+func TestUpdateProviderVersionFollowsReferences(t *testing.T) {
+	input := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = var.aws_version
+    }
+  }
+}
+`
+	variables := "variable \"aws_version\" {\n  default = \"~> 5.0\"\n}\n"
+
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "versions.tf", input)
+	variablesFile := writeTestFile(t, dir, "variables.tf", variables)
+
+	stderr := captureStderr(t, func() {
+		opts := &providerUpdateOptions{filename: file, providerName: "aws", version: "~> 6.0", outputFormat: "text", events: printEvents(false)}
+		if updated, _, err := updateProviderVersionWithCount(file, opts, false); err != nil || updated {
+			t.Errorf("updated=%v err=%v, want a skip without -follow-references", updated, err)
+		}
+	})
+	if !strings.Contains(stderr, "non-literal version 'var.aws_version'") {
+		t.Errorf("stderr = %q, want the reference reported", stderr)
+	}
+
+	captureStdout(t, func() {
+		opts := &providerUpdateOptions{filename: file, providerName: "aws", version: "~> 6.0", followReferences: true, outputFormat: "text", events: printEvents(false)}
+		if updated, changed, err := updateProviderVersionWithCount(file, opts, false); err != nil || !updated || len(changed) != 1 {
+			t.Errorf("updated=%v changed=%v err=%v", updated, changed, err)
+		}
+	})
+	if got := readTestFile(t, file); got != input {
+		t.Errorf("versions.tf changed:\n%s", got)
+	}
+	if got, want := readTestFile(t, variablesFile), strings.Replace(variables, "~> 5.0", "~> 6.0", 1); got != want {
+		t.Errorf("variables.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestNonLiteralVersionsAreSkippedInOtherSyntaxes(t *testing.T) {
+	dir := t.TempDir()
+	hclFile := writeTestFile(t, dir, "versions.tf", "terraform {\n  required_version = local.terraform_version\n}\n")
+	jsonFile := writeTestFile(t, dir, "versions.tf.json", `{"terraform": {"required_version": "${local.terraform_version}"}, "module": {"vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "${var.vpc_version}"}}}`)
+
+	stderr := captureStderr(t, func() {
+		opts := &terraformVersionOptions{version: ">= 1.9", outputFormat: "text", events: printEvents(false)}
+		for _, file := range []string{hclFile, jsonFile} {
+			if updated, err := updateTerraformVersionWithOptions(file, opts, false); err != nil || updated {
+				t.Errorf("%s: updated=%v err=%v, want a skip", file, updated, err)
+			}
+		}
+		moduleOpts := moduleUpdateOptions{filename: jsonFile, moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", followReferences: true, outputFormat: "text", events: printEvents(false)}
+		if updated, _, err := updateModuleVersionWithCount(jsonFile, moduleOpts, false); err != nil || updated {
+			t.Errorf("JSON module: updated=%v err=%v, want a skip", updated, err)
+		}
+	})
+
+	if got := strings.Count(stderr, "has a non-literal version"); got != 3 {
+		t.Errorf("stderr = %q, want 3 non-literal versions reported", stderr)
+	}
+}
@@ -0,0 +1,512 @@
+package bump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Terraform's JSON syntax (.tf.json files) expresses each block type as an object property. Block
+// labels become nested object properties, and any level may instead hold an array of objects when
+// the same block or label appears more than once:
+//
+//	{"module": {"vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "5.0.0"}}}
+//	{"terraform": [{"required_version": ">= 1.5", "required_providers": {"aws": {...}}}]}
+//
+// Files are edited by splicing new string literals into the original bytes, so key order,
+// indentation, and every untouched value are kept exactly as written.
+
+// jsonValueKind identifies the JSON type of a parsed value.
+type jsonValueKind int
+
+const (
+	jsonOther jsonValueKind = iota
+	jsonObject
+	jsonArray
+	jsonString
+)
+
+// jsonValue is a parsed JSON value together with the byte range it occupies in the source.
+type jsonValue struct {
+	kind    jsonValueKind
+	start   int
+	end     int
+	members []jsonMember // object members in written order
+	items   []*jsonValue // array items in written order
+	text    string       // decoded string value
+}
+
+// jsonMember is one property of a JSON object.
+type jsonMember struct {
+	key      string
+	keyStart int
+	keyEnd   int
+	value    *jsonValue
+}
+
+// jsonEdit replaces src[start:end] with text.
+type jsonEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// isJSONSyntax reports whether a file uses Terraform's JSON configuration syntax, including
+// OpenTofu's .tofu.json files.
+func isJSONSyntax(filename string) bool {
+	return strings.HasSuffix(filename, ".tf.json") || strings.HasSuffix(filename, ".tofu.json")
+}
+
+// parseJSONSyntax parses a JSON document, recording the byte range of every value.
+func parseJSONSyntax(src []byte) (*jsonValue, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(src, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	parser := jsonParser{src: src}
+	value, err := parser.value()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return value, nil
+}
+
+// jsonParser walks a document that encoding/json has already validated, so it only needs to
+// locate values rather than diagnose malformed input.
+type jsonParser struct {
+	src []byte
+	pos int
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *jsonParser) value() (*jsonValue, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("unexpected end of input")
+	}
+	switch p.src[p.pos] {
+	case '{':
+		return p.object()
+	case '[':
+		return p.array()
+	case '"':
+		return p.string()
+	}
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(",]} \t\r\n", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return &jsonValue{kind: jsonOther, start: start, end: p.pos}, nil
+}
+
+func (p *jsonParser) object() (*jsonValue, error) {
+	value := &jsonValue{kind: jsonObject, start: p.pos}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.src[p.pos] == '}' {
+			p.pos++
+			value.end = p.pos
+			return value, nil
+		}
+		if p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		p.pos++ // the colon
+		member, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		value.members = append(value.members, jsonMember{key: key.text, keyStart: key.start, keyEnd: key.end, value: member})
+	}
+}
+
+func (p *jsonParser) array() (*jsonValue, error) {
+	value := &jsonValue{kind: jsonArray, start: p.pos}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.src[p.pos] == ']' {
+			p.pos++
+			value.end = p.pos
+			return value, nil
+		}
+		if p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		value.items = append(value.items, item)
+	}
+}
+
+func (p *jsonParser) string() (*jsonValue, error) {
+	start := p.pos
+	p.pos++
+	for p.src[p.pos] != '"' {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	p.pos++
+	var text string
+	if err := json.Unmarshal(p.src[start:p.pos], &text); err != nil {
+		return nil, err
+	}
+	return &jsonValue{kind: jsonString, start: start, end: p.pos, text: text}, nil
+}
+
+// member returns the value of the first property with the given key, or nil.
+func (v *jsonValue) member(key string) *jsonValue {
+	for _, member := range v.members {
+		if member.key == key {
+			return member.value
+		}
+	}
+	return nil
+}
+
+// bodies returns the objects a value holds: the value itself when it is an object, or the object
+// items of an array.
+func (v *jsonValue) bodies() []*jsonValue {
+	switch v.kind {
+	case jsonObject:
+		return []*jsonValue{v}
+	case jsonArray:
+		var bodies []*jsonValue
+		for _, item := range v.items {
+			if item.kind == jsonObject {
+				bodies = append(bodies, item)
+			}
+		}
+		return bodies
+	}
+	return nil
+}
+
+// jsonBlocks returns the bodies of every block of the given type at the top level of a document,
+// in written order. The "//" comment property is never treated as a label.
+func jsonBlocks(root *jsonValue, blockType string, labelled bool) (labels []string, bodies []*jsonValue) {
+	for _, container := range root.bodies() {
+		for _, member := range container.members {
+			if member.key != blockType {
+				continue
+			}
+			for _, typeBody := range member.value.bodies() {
+				if !labelled {
+					for _, body := range typeBody.bodies() {
+						labels = append(labels, "")
+						bodies = append(bodies, body)
+					}
+					continue
+				}
+				for _, labelMember := range typeBody.members {
+					if labelMember.key == "//" {
+						continue
+					}
+					for _, body := range labelMember.value.bodies() {
+						labels = append(labels, labelMember.key)
+						bodies = append(bodies, body)
+					}
+				}
+			}
+		}
+	}
+	return labels, bodies
+}
+
+// jsonStringLiteral encodes a string as a JSON literal without HTML escaping, so constraint
+// operators such as "<" stay readable.
+func jsonStringLiteral(s string) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// replaceJSONString returns an edit that replaces a value with a string literal.
+func replaceJSONString(value *jsonValue, s string) jsonEdit {
+	return jsonEdit{start: value.start, end: value.end, text: jsonStringLiteral(s)}
+}
+
+// insertJSONMember returns an edit that appends a string property to an object, copying the
+// spacing already used between the object's properties and around their colons.
+func insertJSONMember(src []byte, object *jsonValue, key, s string) jsonEdit {
+	return insertJSONValue(src, object, key, jsonStringLiteral(s))
+}
+
+// insertJSONValue is insertJSONMember for a property whose value is already encoded as JSON.
+func insertJSONValue(src []byte, object *jsonValue, key, value string) jsonEdit {
+	if len(object.members) == 0 {
+		return jsonEdit{start: object.start + 1, end: object.start + 1, text: jsonStringLiteral(key) + ": " + value}
+	}
+
+	last := object.members[len(object.members)-1]
+	colon := string(src[last.keyEnd:last.value.start])
+	separator := string(src[object.start+1 : object.members[0].keyStart])
+	if len(object.members) > 1 {
+		previous := object.members[len(object.members)-2]
+		separator = strings.Replace(string(src[previous.value.end:last.keyStart]), ",", "", 1)
+	} else if separator == "" {
+		separator = " "
+	}
+	property := jsonStringLiteral(key) + colon + value
+	return jsonEdit{start: last.value.end, end: last.value.end, text: "," + separator + property}
+}
+
+// removeJSONMember returns an edit that deletes a property from an object together with the comma
+// that separates it from its neighbours.
+func removeJSONMember(object *jsonValue, key string) jsonEdit {
+	index := slices.IndexFunc(object.members, func(member jsonMember) bool { return member.key == key })
+	member := object.members[index]
+	switch {
+	case index > 0:
+		return jsonEdit{start: object.members[index-1].value.end, end: member.value.end}
+	case len(object.members) > 1:
+		return jsonEdit{start: member.keyStart, end: object.members[1].keyStart}
+	default:
+		return jsonEdit{start: member.keyStart, end: member.value.end}
+	}
+}
+
+// applyJSONEdits applies non-overlapping edits to src.
+func applyJSONEdits(src []byte, edits []jsonEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	output := append([]byte(nil), src...)
+	for _, edit := range edits {
+		next := make([]byte, 0, len(output)+len(edit.text))
+		next = append(next, output[:edit.start]...)
+		next = append(next, edit.text...)
+		next = append(next, output[edit.end:]...)
+		output = next
+	}
+	return output
+}
+
+// updateModuleVersionJSON updates matching module blocks in a JSON syntax file. Module blocks are
+// numbered in written order for the update report.
+func updateModuleVersionJSON(src []byte, opts *moduleUpdateOptions) (output []byte, updated bool, changedBlocks []int, err error) {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	var edits []jsonEdit
+	labels, bodies := jsonBlocks(root, "module", true)
+	for blockIndex, body := range bodies {
+		source := body.member("source")
+		if source == nil {
+			continue
+		}
+		if source.kind != jsonString || strings.Contains(source.text, "${") || strings.Contains(source.text, "%{") {
+			opts.reportComputedSource(labels[blockIndex], string(src[source.start:source.end]))
+			continue
+		}
+		if !opts.matchesSource(source.text) {
+			continue
+		}
+
+		version := body.member("version")
+		if version != nil && isJSONComputed(opts.events, opts.subject(labels[blockIndex]), src, version, opts.outputFormat) {
+			continue
+		}
+		currentVersion := ""
+		if version != nil {
+			currentVersion = version.text
+		}
+		newVersion, ok := opts.resolveVersion(labels[blockIndex], source.text, currentVersion, version != nil)
+		if !ok {
+			continue
+		}
+		updated = true
+		switch {
+		case version == nil:
+			edits = append(edits, insertJSONMember(src, body, "version", newVersion))
+		case currentVersion != newVersion:
+			edits = append(edits, replaceJSONString(version, newVersion))
+		default:
+			continue
+		}
+		changedBlocks = append(changedBlocks, blockIndex)
+	}
+
+	return applyJSONEdits(src, edits), updated, changedBlocks, nil
+}
+
+// updateProviderVersionJSON updates a provider in the required_providers blocks of a JSON syntax
+// file. Entries may be objects with a version property or a bare version string. As with native
+// syntax, force-add inserts a missing version property and an add source declares a missing entry.
+func updateProviderVersionJSON(src []byte, opts *providerUpdateOptions) (output []byte, updated bool, changedBlocks []string, err error) {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	var edits []jsonEdit
+	_, terraformBodies := jsonBlocks(root, "terraform", false)
+	for terraformIndex, terraformBody := range terraformBodies {
+		requiredProviders := terraformBody.member("required_providers")
+		if requiredProviders == nil {
+			continue
+		}
+		for providersIndex, providers := range requiredProviders.bodies() {
+			edit, applied, changed := jsonProviderEdit(src, providers, opts)
+			if !applied {
+				continue
+			}
+			updated = true
+			if changed {
+				edits = append(edits, edit)
+				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/json/%d/%s", terraformIndex, providersIndex, opts.providerName))
+			}
+		}
+	}
+
+	return applyJSONEdits(src, edits), updated, changedBlocks, nil
+}
+
+// jsonProviderEdit decides the edit for the provider in one required_providers object: replacing
+// the entry's version, adding a missing version property, or adding a missing entry.
+//
+// Returns:
+//   - edit: The edit to apply when changed is true
+//   - applied: true if the entry was accepted for the update
+//   - changed: true if the file must change
+func jsonProviderEdit(src []byte, providers *jsonValue, opts *providerUpdateOptions) (edit jsonEdit, applied, changed bool) {
+	entry := providers.member(opts.providerName)
+	if entry != nil && !opts.matchesSource(jsonProviderSource(entry)) {
+		return jsonEdit{}, false, false
+	}
+	switch {
+	case entry == nil:
+		if opts.addSource == "" {
+			return jsonEdit{}, false, false
+		}
+		newVersion, ok := opts.resolveVersion("")
+		if !ok {
+			return jsonEdit{}, false, false
+		}
+		value := fmt.Sprintf("{%s: %s, %s: %s}", jsonStringLiteral("source"), jsonStringLiteral(opts.addSource), jsonStringLiteral("version"), jsonStringLiteral(newVersion))
+		return insertJSONValue(src, providers, opts.providerName, value), true, true
+	case entry.kind == jsonObject && entry.member("version") == nil:
+		newVersion, ok := opts.resolveMissingVersion()
+		if !ok {
+			return jsonEdit{}, false, false
+		}
+		return insertJSONMember(src, entry, "version", newVersion), true, true
+	}
+
+	version := entry
+	if entry.kind == jsonObject {
+		version = entry.member("version")
+	}
+	if isJSONComputed(opts.events, opts.subject(), src, version, opts.outputFormat) {
+		return jsonEdit{}, false, false
+	}
+	newVersion, ok := opts.resolveVersion(version.text)
+	if !ok {
+		return jsonEdit{}, false, false
+	}
+	if version.text == newVersion {
+		return jsonEdit{}, true, false
+	}
+	return replaceJSONString(version, newVersion), true, true
+}
+
+// isJSONComputed reports whether a JSON version value is not a literal, reporting that it is
+// skipped: a value of another JSON type, such as the number 5, or an HCL template, as JSON syntax
+// evaluates "${...}" and "%{...}" sequences in strings.
+func isJSONComputed(events eventSink, subject string, src []byte, value *jsonValue, outputFormat string) bool {
+	switch {
+	case value.kind != jsonString:
+		reportComputedVersion(events, subject, string(src[value.start:value.end]), "", outputFormat)
+	case strings.Contains(value.text, "${") || strings.Contains(value.text, "%{"):
+		reportComputedVersion(events, subject, value.text, "", outputFormat)
+	default:
+		return false
+	}
+	return true
+}
+
+// jsonProviderSource returns the source string of a required_providers entry, or "" when it has
+// none.
+func jsonProviderSource(entry *jsonValue) string {
+	if entry.kind != jsonObject {
+		return ""
+	}
+	if source := entry.member("source"); source != nil && source.kind == jsonString {
+		return source.text
+	}
+	return ""
+}
+
+// jsonProviderVersion returns the version string of a required_providers entry, or nil.
+func jsonProviderVersion(entry *jsonValue) *jsonValue {
+	if entry == nil {
+		return nil
+	}
+	if entry.kind == jsonString {
+		return entry
+	}
+	if entry.kind != jsonObject {
+		return nil
+	}
+	version := entry.member("version")
+	if version == nil || version.kind != jsonString {
+		return nil
+	}
+	return version
+}
+
+// updateTerraformVersionJSON sets required_version in the terraform blocks of a JSON syntax file,
+// adding the property where it is missing.
+func updateTerraformVersionJSON(src []byte, filename string, opts *terraformVersionOptions) (output []byte, updated bool, err error) {
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var edits []jsonEdit
+	_, terraformBodies := jsonBlocks(root, "terraform", false)
+	for _, body := range terraformBodies {
+		version := body.member("required_version")
+		if version != nil && isJSONComputed(opts.events, "Terraform required_version in "+filename, src, version, opts.outputFormat) {
+			continue
+		}
+		currentVersion := ""
+		if version != nil {
+			currentVersion = version.text
+		}
+		if !opts.permits(filename, currentVersion) {
+			continue
+		}
+		updated = true
+		switch {
+		case version == nil:
+			edits = append(edits, insertJSONMember(src, body, "required_version", opts.version))
+		case currentVersion != opts.version:
+			edits = append(edits, replaceJSONString(version, opts.version))
+		}
+	}
+
+	return applyJSONEdits(src, edits), updated, nil
+}
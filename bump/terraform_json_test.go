@@ -0,0 +1,165 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateModuleVersionJSONPreservesLayout(t *testing.T) {
+	input := `{
+    "//": "Generated by cdktf",
+    "module": {
+        "vpc": {
+            "version": "4.0.0",
+            "source": "terraform-aws-modules/vpc/aws",
+            "cidr": "10.0.0.0/16"
+        },
+        "legacy": [
+            {"source": "terraform-aws-modules/vpc/aws", "version": "3.0.0"}
+        ],
+        "other": {
+            "source": "terraform-aws-modules/s3-bucket/aws",
+            "version": "1.0.0"
+        }
+    }
+}
+`
+	want := strings.Replace(strings.Replace(input, `"version": "4.0.0"`, `"version": "5.0.0"`, 1), `"version": "3.0.0"`, `"version": "5.0.0"`, 1)
+	file := writeTestFile(t, t.TempDir(), "main.tf.json", input)
+
+	opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.0.0", outputFormat: "text", events: printEvents(false)}
+	updated, changedBlocks, err := updateModuleVersionWithCount(file, opts, false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if len(changedBlocks) != 2 {
+		t.Errorf("changed blocks = %v, want two", changedBlocks)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateModuleVersionJSONAppliesModuleFilters(t *testing.T) {
+	input := `{"module": [
+	{"vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "4.0.0"}},
+	{"test-vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "4.0.0"}},
+	{"newer": {"source": "terraform-aws-modules/vpc/aws", "version": "6.0.0"}},
+	{"unpinned": {"source": "terraform-aws-modules/vpc/aws"}}
+]}
+`
+	want := `{"module": [
+	{"vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "~> 5.0"}},
+	{"test-vpc": {"source": "terraform-aws-modules/vpc/aws", "version": "4.0.0"}},
+	{"newer": {"source": "terraform-aws-modules/vpc/aws", "version": "6.0.0"}},
+	{"unpinned": {"source": "terraform-aws-modules/vpc/aws", "version": "~> 5.0"}}
+]}
+`
+	file := writeTestFile(t, t.TempDir(), "modules.tf.json", input)
+
+	opts := moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "~> 5.0", ignorePatterns: []string{"test-*"}, forceAdd: true, outputFormat: "text", events: printEvents(false)}
+	var updated bool
+	var err error
+	stderr := captureStderr(t, func() {
+		updated, _, err = updateModuleVersionWithCount(file, opts, false)
+	})
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if !strings.Contains(stderr, "Module 'newer'") || !strings.Contains(stderr, "skipping downgrade") {
+		t.Errorf("stderr = %q, want the downgrade reported", stderr)
+	}
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateProviderVersionJSON(t *testing.T) {
+	input := `{
+  "terraform": {
+    "required_providers": {
+      "aws": {
+        "source": "hashicorp/aws",
+        "version": ">= 4.0, < 5.0"
+      },
+      "google": "~> 5.0"
+    }
+  },
+  "provider": {"aws": {"region": "us-east-1"}}
+}
+`
+	filename := writeTestFile(t, t.TempDir(), "versions.tf.json", input)
+
+	updated, changedBlocks, err := updateProviderVersionWithCount(filename, &providerUpdateOptions{providerName: "aws", version: ">= 5.0, < 6.0", events: printEvents(false)}, false)
+	if err != nil || !updated || len(changedBlocks) != 1 {
+		t.Fatalf("updated=%v changed=%v err=%v", updated, changedBlocks, err)
+	}
+	updated, err = updateProviderVersion(filename, "google", "~> 6.0", false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+
+	want := strings.Replace(strings.Replace(input, `">= 4.0, < 5.0"`, `">= 5.0, < 6.0"`, 1), `"~> 5.0"`, `"~> 6.0"`, 1)
+	if got := readTestFile(t, filename); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateTerraformVersionJSON(t *testing.T) {
+	input := `{
+	"terraform": [
+		{"required_version": ">= 1.0"},
+		{
+			"backend": {"s3": {}}
+		}
+	]
+}
+`
+	want := `{
+	"terraform": [
+		{"required_version": ">= 1.5"},
+		{
+			"backend": {"s3": {}},
+			"required_version": ">= 1.5"
+		}
+	]
+}
+`
+	filename := writeTestFile(t, t.TempDir(), "main.tf.json", input)
+
+	updated, err := updateTerraformVersion(filename, ">= 1.5", true)
+	if err != nil || !updated {
+		t.Fatalf("dry run updated=%v err=%v", updated, err)
+	}
+	if got := readTestFile(t, filename); got != input {
+		t.Fatalf("dry run mutated file: %q", got)
+	}
+
+	updated, err = updateTerraformVersion(filename, ">= 1.5", false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if got := readTestFile(t, filename); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestUpdateJSONSyntaxErrors(t *testing.T) {
+	filename := writeTestFile(t, t.TempDir(), "broken.tf.json", `{"module": {`)
+	_, err := updateModuleVersion(filename, "terraform-aws-modules/vpc/aws", "5.0.0", nil, nil, nil, false, false, false, "text")
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to parse JSON:") {
+		t.Fatalf("error = %v, want failed to parse JSON", err)
+	}
+}
+
+func TestInsertJSONMemberEmptyObject(t *testing.T) {
+	src := []byte(`{"terraform": {}}`)
+	root, err := parseJSONSyntax(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := insertJSONMember(src, root.member("terraform"), "required_version", "< 2.0")
+	if got := string(applyJSONEdits(src, []jsonEdit{edit})); got != `{"terraform": {"required_version": "< 2.0"}}` {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,103 @@
+package bump
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// terraformVersionOptions describes one required_version update within terraform blocks.
+type terraformVersionOptions struct {
+	version        string
+	allowDowngrade bool
+	prerelease     string
+	terragrunt     bool
+	opentofu       bool
+	outputFormat   string
+	events         eventSink
+	confirm        func(Proposal) bool
+	beforeWrite    func(string) error
+}
+
+// editTerraformVersions updates the required_version attribute in the terraform blocks of a
+// parsed file of any syntax. Blocks whose current constraint is newer than the target are skipped
+// unless allowDowngrade is set, as are blocks where the prerelease policy forbids a pre-release
+// target. In OpenTofu mode, .tofu and .tofu.json files are skipped. Skips are reported to
+// opts.events.
+//
+// Parameters:
+//   - file: The parsed file to edit
+//   - opts: The target Terraform version and behaviour switches to apply
+//
+// Returns:
+//   - bool: true if a terraform block was updated (or would be updated in dry-run mode)
+//   - error: Any error encountered while parsing a JSON syntax file
+func editTerraformVersions(file *File, opts *terraformVersionOptions) (updated bool, err error) {
+	if opts.skipsFile(file.Name) {
+		return false, nil
+	}
+
+	switch file.syntax {
+	case jsonSyntax:
+		file.json, updated, err = updateTerraformVersionJSON(file.json, file.Name, opts)
+	case terragruntSyntax:
+		updated = editTerraformVersionTerragrunt(file.HCL, file.Name, opts)
+	default:
+		updated = editTerraformVersion(file.HCL, file.Name, opts)
+	}
+	return updated, err
+}
+
+// skipsFile reports whether a file is left alone because its required_version does not constrain
+// Terraform. In OpenTofu mode, required_version in a .tofu file constrains OpenTofu itself, which
+// is versioned independently of Terraform. The skip is reported to opts.events.
+func (opts *terraformVersionOptions) skipsFile(filename string) bool {
+	if opts.opentofu && isOpenTofuFile(filename) {
+		opts.events.skip("%s is OpenTofu configuration; its required_version constrains OpenTofu rather than Terraform, skipping", filename)
+		return true
+	}
+	return false
+}
+
+// editTerraformVersion sets required_version in the terraform blocks of a parsed file.
+func editTerraformVersion(file *hclwrite.File, filename string, opts *terraformVersionOptions) bool {
+	updated := false
+
+	// Iterate through all blocks in the file
+	for _, block := range file.Body().Blocks() {
+		// Look for terraform blocks
+		if block.Type() != "terraform" {
+			continue
+		}
+		currentVersion := ""
+		if attr := block.Body().GetAttribute("required_version"); attr != nil {
+			if !isLiteralString(attr) {
+				expression := strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes()))
+				reportComputedVersion(opts.events, "Terraform required_version in "+filename, expression, "", opts.outputFormat)
+				continue
+			}
+			currentVersion = attributeStringValue(attr)
+		}
+		if !opts.permits(filename, currentVersion) {
+			continue
+		}
+		// Update or add the required_version attribute
+		block.Body().SetAttributeValue("required_version", cty.StringVal(opts.version))
+		updated = true
+	}
+
+	return updated
+}
+
+// permits reports whether the target may replace the current required_version, which is empty
+// when the block has none. Refusals are reported as skips.
+func (opts *terraformVersionOptions) permits(filename, currentVersion string) bool {
+	subject := "Terraform required_version in " + filename
+	if refuseVersionChange(opts.events, subject, currentVersion, opts.version, opts.version, opts.allowDowngrade, opts.prerelease, opts.outputFormat) {
+		return false
+	}
+	proposal := Proposal{Kind: TerraformVersionChange, File: filename, Current: currentVersion, Target: opts.version,
+		Attribute: "required_version", Before: currentVersion, After: opts.version}
+	return confirmChange(opts.confirm, opts.events, subject, proposal, opts.outputFormat)
+}
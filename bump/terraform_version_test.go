@@ -1,4 +1,4 @@
-package main
+package bump
 
 import (
 	"errors"
@@ -162,3 +162,28 @@ func TestUpdateTerraformVersionErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateTerraformVersionRefusesDowngrade(t *testing.T) {
+	input := "terraform {\n  required_version = \">= 1.9\"\n}\n"
+	filename := writeTestFile(t, t.TempDir(), "main.tf", input)
+
+	var updated bool
+	var err error
+	stderr := captureStderr(t, func() {
+		updated, err = updateTerraformVersion(filename, "~> 1.5.0", false)
+	})
+	if err != nil || updated {
+		t.Fatalf("updated=%v err=%v, want a skipped downgrade", updated, err)
+	}
+	if !strings.Contains(stderr, "Terraform required_version in "+filename+" is at version '>= 1.9'") {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	updated, err = updateTerraformVersionWithOptions(filename, &terraformVersionOptions{version: "~> 1.5.0", allowDowngrade: true, events: printEvents(false)}, false)
+	if err != nil || !updated {
+		t.Fatalf("updated=%v err=%v", updated, err)
+	}
+	if got, want := readTestFile(t, filename), "terraform {\n  required_version = \"~> 1.5.0\"\n}\n"; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,309 @@
+package bump
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Terragrunt keeps module versions in the terraform block's source address instead of a version
+// attribute, and often writes required_providers into generated files:
+//
+//	terraform {
+//	  source = "git::https://github.com/example/modules.git//vpc?ref=v1.2.3"
+//	  # or: source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+//	}
+//
+//	generate "versions" {
+//	  path     = "versions.tf"
+//	  contents = <<EOF
+//	terraform {
+//	  required_providers { ... }
+//	}
+//	EOF
+//	}
+//
+// A Terragrunt configuration deploys one module per directory, so the directory name stands in for
+// the module block label when module-name filters are applied.
+
+// terragruntSource is a terraform.source address split into the module it identifies and the
+// query parameter that selects the module's version.
+type terragruntSource struct {
+	raw          string   // the address as written
+	address      string   // module identity, compared with the configured module source
+	base         string   // the source before its query string
+	params       []string // raw query parameters in written order
+	versionIndex int      // index of the version parameter in params, or -1
+	registry     bool     // tfr:// sources select versions with ?version= rather than ?ref=
+}
+
+// parseTerragruntSource splits a Terragrunt source address. Registry addresses written as
+// "tfr:///namespace/name/system" use the default registry host.
+func parseTerragruntSource(raw string) terragruntSource {
+	source := terragruntSource{raw: raw, versionIndex: -1}
+	base, query, hasQuery := strings.Cut(raw, "?")
+	source.base = base
+	source.address = base
+	if registryAddress, ok := strings.CutPrefix(base, "tfr://"); ok {
+		source.registry = true
+		source.address = strings.TrimPrefix(registryAddress, "/")
+	}
+	if !hasQuery {
+		return source
+	}
+
+	source.params = strings.Split(query, "&")
+	for index, param := range source.params {
+		if key, _, _ := strings.Cut(param, "="); key == source.versionKey() {
+			source.versionIndex = index
+			break
+		}
+	}
+	return source
+}
+
+func (source terragruntSource) versionKey() string {
+	if source.registry {
+		return "version"
+	}
+	return "ref"
+}
+
+// version returns the value of the version parameter, if present.
+func (source terragruntSource) version() (string, bool) {
+	if source.versionIndex < 0 {
+		return "", false
+	}
+	_, value, _ := strings.Cut(source.params[source.versionIndex], "=")
+	return value, true
+}
+
+// withVersion returns the source address with its version parameter set, keeping every other
+// parameter in place.
+func (source terragruntSource) withVersion(version string) string {
+	params := append([]string(nil), source.params...)
+	param := source.versionKey() + "=" + version
+	if source.versionIndex >= 0 {
+		params[source.versionIndex] = param
+	} else {
+		params = append(params, param)
+	}
+	return source.base + "?" + strings.Join(params, "&")
+}
+
+// terragruntModuleName returns the name used for module-name filters: the configuration's
+// directory name.
+func terragruntModuleName(filename string) string {
+	if absolute, err := filepath.Abs(filename); err == nil {
+		filename = absolute
+	}
+	return filepath.Base(filepath.Dir(filename))
+}
+
+// editModuleVersionTerragrunt updates the terraform.source version of a Terragrunt
+// configuration whose module matches, applying the same filters and checks as module blocks.
+func editModuleVersionTerragrunt(file *hclwrite.File, opts *moduleUpdateOptions) (updated bool, changedBlocks []int) {
+	moduleName := terragruntModuleName(opts.filename)
+	for blockIndex, block := range file.Body().Blocks() {
+		if block.Type() != "terraform" {
+			continue
+		}
+		blockUpdated, blockChanged := updateTerragruntSourceResult(block, moduleName, opts)
+		if blockUpdated {
+			updated = true
+			if blockChanged {
+				changedBlocks = append(changedBlocks, blockIndex)
+			}
+		}
+	}
+	return updated, changedBlocks
+}
+
+func updateTerragruntSourceResult(block *hclwrite.Block, moduleName string, opts *moduleUpdateOptions) (updated, changed bool) {
+	sourceAttr := block.Body().GetAttribute("source")
+	if sourceAttr == nil {
+		return false, false
+	}
+	if !isLiteralString(sourceAttr) {
+		opts.reportComputedSource(moduleName, string(sourceAttr.Expr().BuildTokens(nil).Bytes()))
+		return false, false
+	}
+	source := parseTerragruntSource(attributeStringValue(sourceAttr))
+	if !sameModuleSource(source.address, opts.moduleSource, opts.opentofu) {
+		return false, false
+	}
+
+	currentVersion, hasVersion := source.version()
+	if !hasVersion && !source.registry {
+		opts.events.skip("Module %s in %s (source: %s) has no ref query parameter, skipping",
+			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
+		return false, false
+	}
+	if _, ok := parseSemanticVersion(opts.version); !ok {
+		opts.events.skip("Module %s in %s (source: %s) selects an exact version in its source address, so %s cannot be applied, skipping",
+			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat), quote(opts.version, opts.outputFormat))
+		return false, false
+	}
+
+	sourceOpts := *opts
+	sourceOpts.terragruntSource = &source
+	newVersion, ok := sourceOpts.resolveVersion(moduleName, source.address, currentVersion, hasVersion)
+	if !ok {
+		return false, false
+	}
+	block.Body().SetAttributeValue("source", cty.StringVal(source.withVersion(newVersion)))
+	return true, !hasVersion || currentVersion != newVersion
+}
+
+// terragruntRefVersion returns the version written over the ref currentVersion. Git tags
+// conventionally carry a "v" prefix that the configured version may omit, so a prefixed ref keeps
+// it.
+func terragruntRefVersion(currentVersion, version string) string {
+	if strings.HasPrefix(currentVersion, "v") && !strings.HasPrefix(version, "v") {
+		return "v" + version
+	}
+	return version
+}
+
+// isLiteralString reports whether an attribute's expression is a quoted string without
+// interpolation.
+func isLiteralString(attr *hclwrite.Attribute) bool {
+	tokens := attr.Expr().BuildTokens(nil)
+	if len(tokens) < 2 || tokens[0].Type != hclsyntax.TokenOQuote || tokens[len(tokens)-1].Type != hclsyntax.TokenCQuote {
+		return false
+	}
+	for _, token := range tokens[1 : len(tokens)-1] {
+		if token.Type != hclsyntax.TokenQuotedLit {
+			return false
+		}
+	}
+	return true
+}
+
+// editProviderVersionTerragrunt updates a provider in the required_providers blocks written by
+// a Terragrunt configuration's generate blocks.
+func editProviderVersionTerragrunt(file *hclwrite.File, opts *providerUpdateOptions) (updated bool, changedBlocks []string) {
+	for blockIndex, block := range file.Body().Blocks() {
+		generated, ok := generatedConfig(opts.events, block, opts.filename, "required_providers", opts.outputFormat)
+		if !ok {
+			continue
+		}
+		blockUpdated, blockChanges := editProviderVersion(generated, opts)
+		if !blockUpdated {
+			continue
+		}
+		setGeneratedConfig(block, generated)
+		updated = true
+		for _, blockChange := range blockChanges {
+			changedBlocks = append(changedBlocks, fmt.Sprintf("%d/generate/%s", blockIndex, blockChange))
+		}
+	}
+	return updated, changedBlocks
+}
+
+// editTerraformVersionTerragrunt sets required_version in the terraform blocks written by a
+// Terragrunt configuration's generate blocks. Terragrunt's own terraform block is not a Terraform
+// settings block and is left alone.
+func editTerraformVersionTerragrunt(file *hclwrite.File, filename string, opts *terraformVersionOptions) (updated bool) {
+	for _, block := range file.Body().Blocks() {
+		generated, ok := generatedConfig(opts.events, block, filename, "terraform", opts.outputFormat)
+		if !ok || !editTerraformVersion(generated, filename, opts) {
+			continue
+		}
+		setGeneratedConfig(block, generated)
+		updated = true
+	}
+	return updated
+}
+
+// generatedConfig parses the Terraform configuration held in a generate block's heredoc contents
+// when the contents mention keyword. Contents that use template interpolation or directives
+// cannot be edited safely and are reported as skipped.
+func generatedConfig(events eventSink, block *hclwrite.Block, filename, keyword, outputFormat string) (*hclwrite.File, bool) {
+	if block.Type() != "generate" {
+		return nil, false
+	}
+	contents, ok := generatedContents(block)
+	if !ok || !strings.Contains(contents, keyword) {
+		return nil, false
+	}
+
+	name := moduleBlockName(block)
+	if strings.Contains(contents, "${") || strings.Contains(contents, "%{") {
+		events.skip("generate block %s in %s uses template interpolation in its contents, skipping", quote(name, outputFormat), filename)
+		return nil, false
+	}
+	generated, diags := hclwrite.ParseConfig([]byte(contents), filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		events.skip("generate block %s in %s does not contain valid HCL, skipping", quote(name, outputFormat), filename)
+		return nil, false
+	}
+	return generated, true
+}
+
+// generatedContents returns the text of a generate block's heredoc contents attribute.
+func generatedContents(block *hclwrite.Block) (string, bool) {
+	attr := block.Body().GetAttribute("contents")
+	if attr == nil {
+		return "", false
+	}
+	tokens := attr.Expr().BuildTokens(nil)
+	if len(tokens) < 2 || tokens[0].Type != hclsyntax.TokenOHeredoc || tokens[len(tokens)-1].Type != hclsyntax.TokenCHeredoc {
+		return "", false
+	}
+	var contents strings.Builder
+	for _, token := range tokens[1 : len(tokens)-1] {
+		contents.Write(token.Bytes)
+	}
+	return contents.String(), true
+}
+
+// setGeneratedConfig writes an edited configuration back between the original heredoc markers.
+// Indented heredocs ("<<-EOT") keep the indentation their contents were written with.
+func setGeneratedConfig(block *hclwrite.Block, generated *hclwrite.File) {
+	tokens := block.Body().GetAttribute("contents").Expr().BuildTokens(nil)
+	contents := string(generated.Bytes())
+	if strings.HasPrefix(string(tokens[0].Bytes), "<<-") {
+		original, _ := generatedContents(block)
+		contents = indentLines(contents, heredocIndent(original))
+	}
+	block.Body().SetAttributeRaw("contents", hclwrite.Tokens{
+		tokens[0],
+		{Type: hclsyntax.TokenStringLit, Bytes: []byte(contents)},
+		tokens[len(tokens)-1],
+	})
+}
+
+// heredocIndent returns the leading whitespace shared by every non-blank line of text.
+func heredocIndent(text string) string {
+	indent, found := "", false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found || len(lineIndent) < len(indent) {
+			indent, found = lineIndent, true
+		}
+	}
+	return indent
+}
+
+// indentLines prefixes every non-blank line of text with indent.
+func indentLines(text, indent string) string {
+	if indent == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
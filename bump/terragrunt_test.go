@@ -0,0 +1,262 @@
+package bump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateModuleVersionTerragruntSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		module  string
+		version string
+		want    string
+	}{
+		{"git ref keeps v prefix and other parameters", "git::https://github.com/example/modules.git//vpc?ref=v1.2.3&depth=1", "git::https://github.com/example/modules.git//vpc", "1.3.0", "git::https://github.com/example/modules.git//vpc?ref=v1.3.0&depth=1"},
+		{"git ref without prefix", "git::https://github.com/example/modules.git//vpc?ref=1.2.3", "git::https://github.com/example/modules.git//vpc", "1.3.0", "git::https://github.com/example/modules.git//vpc?ref=1.3.0"},
+		{"registry with default host", "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0", "terraform-aws-modules/vpc/aws", "5.1.0", "tfr:///terraform-aws-modules/vpc/aws?version=5.1.0"},
+		{"registry with explicit host", "tfr://registry.terraform.io/terraform-aws-modules/vpc/aws?version=5.0.0", "terraform-aws-modules/vpc/aws", "5.1.0", "tfr://registry.terraform.io/terraform-aws-modules/vpc/aws?version=5.1.0"},
+		{"different module", "tfr:///terraform-aws-modules/s3-bucket/aws?version=4.0.0", "terraform-aws-modules/vpc/aws", "5.1.0", "tfr:///terraform-aws-modules/s3-bucket/aws?version=4.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "include \"root\" {\n  path = find_in_parent_folders()\n}\n\nterraform {\n  source = \"" + tt.source + "\"\n}\n"
+			want := strings.Replace(input, tt.source, tt.want, 1)
+			file := writeTestFile(t, terragruntTestDir(t, t.TempDir(), "vpc"), "terragrunt.hcl", input)
+
+			opts := moduleUpdateOptions{moduleSource: tt.module, version: tt.version, terragrunt: true, outputFormat: "text", events: printEvents(false)}
+			if _, _, err := updateModuleVersionWithCount(file, opts, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := readTestFile(t, file); got != want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestUpdateModuleVersionTerragruntVPrefixedFilters(t *testing.T) {
+	const module = "git::https://github.com/example/modules.git//vpc"
+	tests := []struct {
+		name string
+		ref  string
+		opts moduleUpdateOptions
+		want string
+	}{
+		{"from without prefix matches prefixed ref", "v1.2.3", moduleUpdateOptions{version: "1.3.0", fromVersions: []string{"1.2.3"}}, "v1.3.0"},
+		{"from with prefix matches plain ref", "1.2.3", moduleUpdateOptions{version: "1.3.0", fromVersions: []string{"v1.2.3"}}, "1.3.0"},
+		{"from not matching", "v1.2.3", moduleUpdateOptions{version: "1.3.0", fromVersions: []string{"1.0.0"}}, "v1.2.3"},
+		{"ignore without prefix matches prefixed ref", "v1.2.3", moduleUpdateOptions{version: "1.3.0", ignoreVersions: []string{"1.2.3"}}, "v1.2.3"},
+		{"ignore with prefix matches plain ref", "1.2.3", moduleUpdateOptions{version: "1.3.0", ignoreVersions: []string{"v1.2.3"}}, "1.2.3"},
+		{"prefixed target over prefixed ref", "v1.2.3", moduleUpdateOptions{version: "v1.3.0"}, "v1.3.0"},
+		{"downgrade of prefixed ref", "v1.2.3", moduleUpdateOptions{version: "1.1.0"}, "v1.2.3"},
+		{"pre-release of prefixed ref", "v1.2.3", moduleUpdateOptions{version: "v1.3.0-rc.1", prerelease: prereleaseExclude}, "v1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "terraform {\n  source = \"" + module + "?ref=" + tt.ref + "\"\n}\n"
+			want := strings.Replace(input, "ref="+tt.ref, "ref="+tt.want, 1)
+			file := writeTestFile(t, terragruntTestDir(t, t.TempDir(), "vpc"), "terragrunt.hcl", input)
+
+			opts := tt.opts
+			opts.moduleSource = module
+			opts.terragrunt = true
+			opts.outputFormat = "text"
+			opts.events = printEvents(false)
+			captureStderr(t, func() {
+				if _, _, err := updateModuleVersionWithCount(file, opts, false); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+			if got := readTestFile(t, file); got != want {
+				t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestUpdateModuleVersionTerragruntConfirmsSource(t *testing.T) {
+	const module = "git::https://github.com/example/modules.git//vpc"
+	input := "terraform {\n  source = \"" + module + "?ref=v1.2.3&depth=1\"\n}\n"
+	dir := terragruntTestDir(t, t.TempDir(), "vpc")
+	file := writeTestFile(t, dir, "terragrunt.hcl", input)
+
+	var proposals []Proposal
+	opts := moduleUpdateOptions{moduleSource: module, version: "1.3.0", terragrunt: true, outputFormat: "text", events: printEvents(false),
+		confirm: func(proposal Proposal) bool {
+			proposals = append(proposals, proposal)
+			return false
+		}}
+	if _, _, err := updateModuleVersionWithCount(file, opts, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Proposal{{Kind: ModuleChange, File: file, Block: "vpc", Name: module, Current: "v1.2.3", Target: "v1.3.0",
+		Attribute: "source", Before: module + "?ref=v1.2.3&depth=1", After: module + "?ref=v1.3.0&depth=1"}}
+	if !reflect.DeepEqual(proposals, want) {
+		t.Errorf("proposals = %#v, want %#v", proposals, want)
+	}
+	if got := readTestFile(t, file); got != input {
+		t.Errorf("declined change written:\n%s", got)
+	}
+}
+
+func TestUpdateModuleVersionTerragruntSkips(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		opts       moduleUpdateOptions
+		wantStderr string
+	}{
+		{"git source without ref", `"git::https://github.com/example/modules.git//vpc"`, moduleUpdateOptions{moduleSource: "git::https://github.com/example/modules.git//vpc", version: "1.3.0", events: printEvents(false)}, "has no ref query parameter"},
+		{"constraint target", `"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"`, moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "~> 5.1", events: printEvents(false)}, "selects an exact version"},
+		{"downgrade", `"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"`, moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "4.0.0", events: printEvents(false)}, "skipping downgrade"},
+		{"ignored directory name", `"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"`, moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.1.0", ignorePatterns: []string{"v*"}, events: printEvents(false)}, ""},
+		{"interpolated source", `"${local.base}//vpc?ref=v1.2.3"`, moduleUpdateOptions{moduleSource: "${local.base}//vpc", version: "1.3.0", events: printEvents(false)}, "has a non-literal source '\"${local.base}//vpc?ref=v1.2.3\"'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "terraform {\n  source = " + tt.source + "\n}\n"
+			file := writeTestFile(t, terragruntTestDir(t, t.TempDir(), "vpc"), "terragrunt.hcl", input)
+
+			opts := tt.opts
+			opts.terragrunt = true
+			opts.outputFormat = "text"
+			var updated bool
+			var err error
+			stderr := captureStderr(t, func() {
+				updated, _, err = updateModuleVersionWithCount(file, opts, false)
+			})
+			if err != nil || updated {
+				t.Fatalf("updated=%v err=%v, want a skip", updated, err)
+			}
+			if tt.wantStderr != "" && !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+			if got := readTestFile(t, file); got != input {
+				t.Errorf("file changed:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestUpdateTerragruntGenerateBlocks(t *testing.T) {
+	input := `terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
+
+generate "versions" {
+  path      = "versions.tf"
+  if_exists = "overwrite"
+  contents  = <<EOF
+terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+EOF
+}
+
+generate "indented" {
+  path     = "providers.tf"
+  contents = <<-EOT
+    terraform {
+      required_providers {
+        aws = {
+          source  = "hashicorp/aws"
+          version = "~> 5.0"
+        }
+      }
+    }
+  EOT
+}
+
+generate "provider" {
+  path     = "provider.tf"
+  contents = <<EOF
+provider "aws" {
+  region = "${local.region}"
+}
+terraform {
+  required_providers {
+    aws = { source = "hashicorp/aws", version = "~> 5.0" }
+  }
+}
+EOF
+}
+`
+	file := writeTestFile(t, terragruntTestDir(t, t.TempDir(), "vpc"), "terragrunt.hcl", input)
+
+	var providerUpdated bool
+	var changedBlocks []string
+	var err error
+	stderr := captureStderr(t, func() {
+		providerUpdated, changedBlocks, err = updateProviderVersionWithCount(file, &providerUpdateOptions{providerName: "aws", version: "~> 6.0", terragrunt: true, outputFormat: "text", events: printEvents(false)}, false)
+	})
+	if err != nil || !providerUpdated {
+		t.Fatalf("provider updated=%v err=%v", providerUpdated, err)
+	}
+	if want := []string{"1/generate/0/0/attribute/aws", "2/generate/0/0/attribute/aws"}; strings.Join(changedBlocks, ",") != strings.Join(want, ",") {
+		t.Errorf("changed blocks = %v, want %v", changedBlocks, want)
+	}
+	if !strings.Contains(stderr, "generate block 'provider'") || !strings.Contains(stderr, "template interpolation") {
+		t.Errorf("stderr = %q, want the templated block reported", stderr)
+	}
+
+	captureStderr(t, func() {
+		_, err = updateTerraformVersionWithOptions(file, &terraformVersionOptions{version: ">= 1.9", terragrunt: true, outputFormat: "text", events: printEvents(false)}, false)
+	})
+	if err != nil {
+		t.Fatalf("terraform version: %v", err)
+	}
+
+	got := readTestFile(t, file)
+	for _, want := range []string{
+		"source = \"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0\"\n}",
+		"  required_version = \">= 1.9\"\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 6.0\"",
+		"    terraform {\n      required_providers {\n        aws = {\n          source  = \"hashicorp/aws\"\n          version = \"~> 6.0\"\n        }\n      }\n      required_version = \">= 1.9\"\n    }\n  EOT",
+		"aws = { source = \"hashicorp/aws\", version = \"~> 5.0\" }",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("content missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "required_version") != 2 {
+		t.Errorf("required_version must not be added to the Terragrunt terraform block:\n%s", got)
+	}
+}
+
+func TestTerragruntModeOnlyAppliesToHCLFiles(t *testing.T) {
+	input := "terraform {\n  source = \"tfr:///terraform-aws-modules/vpc/aws?version=5.0.0\"\n}\n"
+	dir := t.TempDir()
+	withoutFlag := writeTestFile(t, terragruntTestDir(t, dir, "plain"), "terragrunt.hcl", input)
+	tfFile := writeTestFile(t, terragruntTestDir(t, dir, "tf"), "main.tf", input)
+
+	if updated, _, err := updateModuleVersionWithCount(withoutFlag, moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.1.0", outputFormat: "text", events: printEvents(false)}, false); err != nil || updated {
+		t.Errorf(".hcl without terragrunt mode: updated=%v err=%v", updated, err)
+	}
+	if updated, _, err := updateModuleVersionWithCount(tfFile, moduleUpdateOptions{moduleSource: "terraform-aws-modules/vpc/aws", version: "5.1.0", terragrunt: true, outputFormat: "text", events: printEvents(false)}, false); err != nil || updated {
+		t.Errorf(".tf in terragrunt mode: updated=%v err=%v", updated, err)
+	}
+}
+
+// terragruntTestDir creates a named directory, since Terragrunt module names come from the
+// directory holding the configuration.
+func terragruntTestDir(t *testing.T, parent, name string) string {
+	t.Helper()
+	dir := filepath.Join(parent, name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", dir, err)
+	}
+	return dir
+}
@@ -0,0 +1,294 @@
+package bump
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Names of the built-in updaters, which apply the terraform_version, providers, and modules
+// entries of a config.
+const (
+	TerraformVersionUpdater = "terraform_version"
+	ProviderUpdater         = "providers"
+	ModuleUpdater           = "modules"
+)
+
+// Updater applies one kind of config entry to a parsed file.
+//
+// The built-in updaters take a string for TerraformVersionUpdater, a *ProviderUpdate for
+// ProviderUpdater, and a *ModuleUpdate for ModuleUpdater. Registered updaters take a
+// *CustomUpdate for each CustomUpdate entry of a config that names them.
+type Updater interface {
+	// Update edits the parsed file for one entry and returns a Change for each edit, or nil when
+	// the file has nothing to update. The file is written back when any Change is returned.
+	Update(file *File, entry Entry) ([]Change, error)
+}
+
+// Entry is a config entry passed to an Updater.
+type Entry any
+
+// File is a parsed file passed to an Updater. Registered updaters are passed native syntax files
+// only; the built-in updaters also edit Terraform JSON syntax files and, in Terragrunt mode,
+// Terragrunt configuration.
+type File struct {
+	Name    string         // Path of the file
+	HCL     *hclwrite.File // Parsed contents, edited in place; nil for a JSON syntax file
+	Options Options        // Options of the run
+
+	syntax         configSyntax
+	json           []byte // Contents of a JSON syntax file, replaced when edited
+	events         eventSink
+	referenceEdits []referenceEdit
+}
+
+// parse sets the contents of the file from src in the file's syntax.
+func (file *File) parse(src []byte) error {
+	if file.syntax == jsonSyntax {
+		file.json = src
+		return nil
+	}
+	parsed, diags := hclwrite.ParseConfig(src, file.Name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+	file.HCL = parsed
+	return nil
+}
+
+// bytes returns the edited contents of the file. HCL is normalised by hclwrite.Format; JSON syntax
+// is returned as edited, so that its layout is unchanged.
+func (file *File) bytes() []byte {
+	if file.syntax == jsonSyntax {
+		return file.json
+	}
+	return hclwrite.Format(file.HCL.Bytes())
+}
+
+// rewrite reads and parses the file, passes it to edit, and, unless dryRun is set, writes it back
+// when edit reports an update. The local values and variable defaults that edit followed are then
+// updated in their own files.
+func (file *File) rewrite(dryRun bool, beforeWrite func(string) error, edit func() (bool, error)) error {
+	_, err := rewriteFile(file.Name, dryRun, beforeWrite, func(src []byte) ([]byte, bool, error) {
+		if err := file.parse(src); err != nil {
+			return nil, false, err
+		}
+		updated, err := edit()
+		if err != nil {
+			return nil, false, err
+		}
+		return file.bytes(), updated, nil
+	})
+	if err != nil {
+		return err
+	}
+	return applyReferenceEdits(file.events, file.referenceEdits, dryRun, beforeWrite)
+}
+
+// Skip reports a block of the file that the update could not be applied to.
+func (file *File) Skip(format string, args ...any) {
+	file.events.skip(format, args...)
+}
+
+// Filtered reports a block of the file that a filter of the update excluded.
+func (file *File) Filtered(format string, args ...any) {
+	file.events.filtered(format, args...)
+}
+
+// CustomUpdate is a config entry applied by a registered updater.
+type CustomUpdate struct {
+	Updater  string            // Registered name of the updater (e.g., "platform-locals")
+	Name     string            // Optional: what the entry updates (e.g., "platform_version")
+	Version  string            // Target version (e.g., "2.4.0")
+	Settings map[string]string // Optional: further values the updater understands
+}
+
+// Registry maps names to the updaters an Engine applies. NewRegistry returns a registry with the
+// built-in updaters.
+type Registry struct {
+	updaters map[string]Updater
+}
+
+// NewRegistry returns a registry holding the built-in updaters.
+func NewRegistry() *Registry {
+	return &Registry{updaters: map[string]Updater{
+		TerraformVersionUpdater: terraformVersionUpdater{},
+		ProviderUpdater:         providerUpdater{},
+		ModuleUpdater:           moduleUpdater{},
+	}}
+}
+
+// Register adds an updater under a name that CustomUpdate entries refer to.
+//
+// Parameters:
+//   - name: The name of the updater; it must not be empty or already registered
+//   - updater: The updater to apply to entries with this name
+//
+// Returns:
+//   - error: An empty or duplicate name, or a nil updater
+func (registry *Registry) Register(name string, updater Updater) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("updater name must not be empty")
+	case updater == nil:
+		return fmt.Errorf("updater %q is nil", name)
+	case registry.updaters[name] != nil:
+		return fmt.Errorf("updater %q is already registered", name)
+	}
+	registry.updaters[name] = updater
+	return nil
+}
+
+// Lookup returns the updater registered under a name.
+func (registry *Registry) Lookup(name string) (Updater, bool) {
+	updater, ok := registry.updaters[name]
+	return updater, ok
+}
+
+// Names returns the registered names in sorted order.
+func (registry *Registry) Names() []string {
+	names := make([]string, 0, len(registry.updaters))
+	for name := range registry.updaters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// terraformVersionUpdater sets required_version in terraform blocks.
+type terraformVersionUpdater struct{}
+
+func (terraformVersionUpdater) Update(file *File, entry Entry) ([]Change, error) {
+	version, ok := entry.(string)
+	if !ok {
+		return nil, fmt.Errorf("updater %q takes a string, not %T", TerraformVersionUpdater, entry)
+	}
+	opts := file.Options.terraformOptions(version)
+	opts.events = file.events
+	updated, err := editTerraformVersions(file, opts)
+	if err != nil || !updated {
+		return nil, err
+	}
+	return []Change{{Kind: TerraformVersionChange, File: file.Name, Version: version}}, nil
+}
+
+// providerUpdater sets provider versions in required_providers blocks.
+type providerUpdater struct{}
+
+func (providerUpdater) Update(file *File, entry Entry) ([]Change, error) {
+	update, ok := entry.(*ProviderUpdate)
+	if !ok {
+		return nil, fmt.Errorf("updater %q takes a *ProviderUpdate, not %T", ProviderUpdater, entry)
+	}
+	opts := file.Options.providerOptions(update)
+	opts.filename, opts.events = file.Name, file.events
+	updated, changedBlocks, err := editProviderVersions(file, opts)
+	if err != nil || !updated {
+		return nil, err
+	}
+	return []Change{{Kind: ProviderChange, File: file.Name, Name: update.Name, Version: update.Version, Previous: opts.previous.sorted(), Blocks: changedBlocks}}, nil
+}
+
+// moduleUpdater sets the version of module blocks.
+type moduleUpdater struct{}
+
+func (moduleUpdater) Update(file *File, entry Entry) ([]Change, error) {
+	update, ok := entry.(*ModuleUpdate)
+	if !ok {
+		return nil, fmt.Errorf("updater %q takes a *ModuleUpdate, not %T", ModuleUpdater, entry)
+	}
+	opts := file.Options.moduleOptions(update)
+	opts.filename, opts.events = file.Name, file.events
+	updated, changedBlocks, err := editModuleVersions(file, &opts)
+	if err != nil || !updated {
+		return nil, err
+	}
+	return []Change{update.change(file.Name, changedBlocks, opts.previous)}, nil
+}
+
+// update applies one entry to one file with the updater registered under name. The entries of
+// registered updaters are applied to native syntax files only.
+func (r *run) update(filename, name string, entry Entry) {
+	updater, _ := r.updaters.Lookup(name)
+	custom, isCustom := entry.(*CustomUpdate)
+	syntax := fileSyntax(filename, r.Terragrunt)
+	if isCustom && syntax != nativeSyntax {
+		return
+	}
+
+	file := &File{Name: filename, Options: r.Options, syntax: syntax, events: r.events.forFile(filename)}
+	var changes []Change
+	err := file.rewrite(r.DryRun, r.BeforeWrite, func() (bool, error) {
+		var err error
+		changes, err = updater.Update(file, entry)
+		return len(changes) > 0, err
+	})
+	if err != nil {
+		r.events.emit(Diagnostic{Kind: entryChangeKind(entry), File: filename, Err: err})
+		return
+	}
+	for _, change := range changes {
+		if isCustom {
+			change.Kind, change.File, change.Updater = CustomChange, filename, custom.Updater
+		}
+		r.events.emit(change)
+	}
+}
+
+// entryChangeKind returns the kind of the changes made for an entry, which diagnostics report.
+func entryChangeKind(entry Entry) ChangeKind {
+	switch entry.(type) {
+	case string:
+		return TerraformVersionChange
+	case *ProviderUpdate:
+		return ProviderChange
+	case *ModuleUpdate:
+		return ModuleChange
+	}
+	return CustomChange
+}
+
+// rewriteFile passes the contents of a file to edit and, unless dryRun is set, writes the result
+// back with the file's permissions when edit reports an update. beforeWrite, when set, is called
+// before the file is written.
+func rewriteFile(filename string, dryRun bool, beforeWrite func(string) error, edit func(src []byte) (output []byte, updated bool, err error)) (bool, error) {
+	// Get original file permissions to preserve them when writing
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Read the file
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	output, updated, err := edit(src)
+	if err != nil {
+		return false, err
+	}
+
+	// If we made changes, write the file back (unless in dry-run mode)
+	if updated && !dryRun {
+		if err := writeFile(filename, output, fileInfo.Mode().Perm(), beforeWrite); err != nil {
+			return false, fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+	return updated, nil
+}
+
+// writeFile writes data to a file with the given permissions, first passing the file's name to
+// beforeWrite when it is set. An error from beforeWrite leaves the file unchanged.
+func writeFile(filename string, data []byte, perm fs.FileMode, beforeWrite func(string) error) error {
+	if beforeWrite != nil {
+		if err := beforeWrite(filename); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filename, data, perm)
+}
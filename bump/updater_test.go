@@ -0,0 +1,159 @@
+package bump
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// localsUpdater sets a local value that pins a version, as an in-house convention might.
+type localsUpdater struct{}
+
+func (localsUpdater) Update(file *File, entry Entry) ([]Change, error) {
+	update := entry.(*CustomUpdate)
+	var changes []Change
+	for _, block := range file.HCL.Body().Blocks() {
+		attr := block.Body().GetAttribute(update.Name)
+		if block.Type() != "locals" || attr == nil {
+			continue
+		}
+		if !isLiteralString(attr) {
+			file.Skip("local.%s in %s is not a literal string, skipping", update.Name, file.Name)
+			continue
+		}
+		block.Body().SetAttributeValue(update.Name, cty.StringVal(update.Version))
+		changes = append(changes, Change{Name: "local." + update.Name, Version: update.Version})
+	}
+	return changes, nil
+}
+
+func TestEngineAppliesRegisteredUpdaters(t *testing.T) {
+	dir := t.TempDir()
+	input := "locals {\n  platform_version = \"1.0.0\" # pinned\n  region           = \"eu-west-1\"\n}\n\nlocals {\n  platform_version = var.platform\n}\n"
+	file := writeTestFile(t, dir, "locals.tf", input)
+	jsonInput := `{"locals": {"platform_version": "1.0.0"}}`
+	jsonFile := writeTestFile(t, dir, "locals.tf.json", jsonInput)
+
+	updaters := NewRegistry()
+	if err := updaters.Register("platform-locals", localsUpdater{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	engine := &Engine{Options: Options{OutputFormat: "text"}, Updaters: updaters}
+	config := Config{Custom: []CustomUpdate{{Updater: " platform-locals ", Name: "platform_version", Version: "2.0.0"}}}
+	result, err := engine.Apply(context.Background(), config, Files{file, jsonFile})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	wantChanges := []Change{{Kind: CustomChange, File: file, Name: "local.platform_version", Version: "2.0.0", Updater: "platform-locals"}}
+	if !reflect.DeepEqual(result.Changes, wantChanges) {
+		t.Errorf("changes = %#v, want %#v", result.Changes, wantChanges)
+	}
+	if len(result.Skips) != 1 || result.Skips[0].File != file || !strings.Contains(result.Skips[0].Message, "not a literal string") {
+		t.Errorf("skips = %#v, want the computed local reported", result.Skips)
+	}
+	want := strings.Replace(input, `"1.0.0"`, `"2.0.0"`, 1)
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("locals.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	if got := readTestFile(t, jsonFile); got != jsonInput {
+		t.Errorf("registered updater edited JSON syntax: %s", got)
+	}
+}
+
+func TestEngineRejectsUnregisteredUpdaters(t *testing.T) {
+	engine := &Engine{}
+	_, err := engine.Apply(context.Background(), Config{Custom: []CustomUpdate{{Updater: "platform-locals", Version: "2.0.0"}}}, Files{"main.tf"})
+	want := `custom update at index 0 names updater "platform-locals", which is not registered`
+	if err == nil || err.Error() != want {
+		t.Fatalf("Apply() error = %v, want %q", err, want)
+	}
+
+	_, err = engine.Apply(context.Background(), Config{Custom: []CustomUpdate{{Updater: "platform-locals"}}}, Files{"main.tf"})
+	if err == nil || err.Error() != "custom update at index 0 is missing 'version' field" {
+		t.Fatalf("Apply() error = %v, want the missing version reported", err)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+	tests := []struct {
+		name    string
+		updater Updater
+		want    string
+	}{
+		{"", localsUpdater{}, "updater name must not be empty"},
+		{"platform-locals", nil, `updater "platform-locals" is nil`},
+		{ModuleUpdater, localsUpdater{}, `updater "modules" is already registered`},
+	}
+	for _, tt := range tests {
+		if err := registry.Register(tt.name, tt.updater); err == nil || err.Error() != tt.want {
+			t.Errorf("Register(%q) error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := registry.Register("platform-locals", localsUpdater{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got, want := registry.Names(), []string{"modules", "platform-locals", "providers", "terraform_version"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
+
+func TestBuiltinUpdatersEditParsedFiles(t *testing.T) {
+	src := `terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+`
+	parsed, diags := hclwrite.ParseConfig([]byte(src), "main.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse: %s", diags.Error())
+	}
+	file := &File{Name: "main.tf", HCL: parsed}
+	registry := NewRegistry()
+
+	tests := []struct {
+		updater string
+		entry   Entry
+		want    Change
+	}{
+		{TerraformVersionUpdater, ">= 1.9", Change{Kind: TerraformVersionChange, File: "main.tf", Version: ">= 1.9"}},
+		{ProviderUpdater, &ProviderUpdate{Name: "aws", Version: "~> 6.0"}, Change{Kind: ProviderChange, File: "main.tf", Name: "aws", Version: "~> 6.0", Previous: []string{"~> 5.0"}, Blocks: []string{"0/0/attribute/aws"}}},
+		{ModuleUpdater, &ModuleUpdate{Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"}, Change{Kind: ModuleChange, File: "main.tf", Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0", Previous: []string{"4.0.0"}, Blocks: []string{"1"}}},
+	}
+	for _, tt := range tests {
+		updater, _ := registry.Lookup(tt.updater)
+		changes, err := updater.Update(file, tt.entry)
+		if err != nil {
+			t.Fatalf("%s: Update() error = %v", tt.updater, err)
+		}
+		if !reflect.DeepEqual(changes, []Change{tt.want}) {
+			t.Errorf("%s: changes = %#v, want %#v", tt.updater, changes, tt.want)
+		}
+	}
+
+	want := strings.NewReplacer(`">= 1.5"`, `">= 1.9"`, `"~> 5.0"`, `"~> 6.0"`, `"4.0.0"`, `"5.0.0"`).Replace(src)
+	if got := string(hclwrite.Format(parsed.Bytes())); got != want {
+		t.Errorf("file mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+
+	updater, _ := registry.Lookup(ModuleUpdater)
+	if _, err := updater.Update(file, &CustomUpdate{}); err == nil || err.Error() != `updater "modules" takes a *ModuleUpdate, not *bump.CustomUpdate` {
+		t.Errorf("Update() error = %v, want the entry type reported", err)
+	}
+}
@@ -0,0 +1,519 @@
+package bump
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semanticVersion is a version number as written in a Terraform constraint. Terraform accepts
+// one to three numeric segments, so the original precision is retained alongside the values.
+type semanticVersion struct {
+	segments   []int
+	prerelease string
+	metadata   string
+	vPrefix    bool
+}
+
+// constraintClause is one comma-separated comparison within a version constraint, such as the
+// "~> 4.2" in "~> 4.2, != 4.2.1". An empty operator is Terraform's implicit equality.
+type constraintClause struct {
+	operator string
+	version  semanticVersion
+}
+
+// constraintOperators lists the recognised operators, longest first so that prefix matching
+// does not read ">=" as ">".
+var constraintOperators = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// parseSemanticVersion parses a version such as "5.1", "v1.2.3" or "3.0.0-beta.1+build.5".
+//
+// Returns:
+//   - semanticVersion: The parsed version with its original precision
+//   - bool: false when the string is not a version
+func parseSemanticVersion(s string) (semanticVersion, bool) {
+	var version semanticVersion
+	s = strings.TrimSpace(s)
+	if trimmed, ok := strings.CutPrefix(s, "v"); ok {
+		version.vPrefix = true
+		s = trimmed
+	}
+	if base, metadata, ok := strings.Cut(s, "+"); ok {
+		if !validVersionIdentifiers(metadata) {
+			return semanticVersion{}, false
+		}
+		version.metadata = metadata
+		s = base
+	}
+	if base, prerelease, ok := strings.Cut(s, "-"); ok {
+		if !validVersionIdentifiers(prerelease) {
+			return semanticVersion{}, false
+		}
+		version.prerelease = prerelease
+		s = base
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semanticVersion{}, false
+	}
+	for _, part := range parts {
+		if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+			return semanticVersion{}, false
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return semanticVersion{}, false
+		}
+		version.segments = append(version.segments, value)
+	}
+	return version, true
+}
+
+func validVersionIdentifiers(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, character := range s {
+		if (character < '0' || character > '9') && (character < 'a' || character > 'z') &&
+			(character < 'A' || character > 'Z') && character != '.' && character != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the version with the precision and prefix it was written with.
+func (v semanticVersion) String() string {
+	var builder strings.Builder
+	if v.vPrefix {
+		builder.WriteByte('v')
+	}
+	for index, segment := range v.segments {
+		if index > 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(strconv.Itoa(segment))
+	}
+	if v.prerelease != "" {
+		builder.WriteString("-" + v.prerelease)
+	}
+	if v.metadata != "" {
+		builder.WriteString("+" + v.metadata)
+	}
+	return builder.String()
+}
+
+// segment returns the numeric segment at index, treating unwritten segments as zero.
+func (v semanticVersion) segment(index int) int {
+	if index < len(v.segments) {
+		return v.segments[index]
+	}
+	return 0
+}
+
+// withPrecision returns the version truncated or zero-padded to the given number of segments.
+// Pre-release and build metadata only survive when the full three segments are kept.
+func (v semanticVersion) withPrecision(precision int) semanticVersion {
+	result := semanticVersion{vPrefix: v.vPrefix}
+	for index := 0; index < precision; index++ {
+		result.segments = append(result.segments, v.segment(index))
+	}
+	if precision >= len(v.segments) && precision == 3 {
+		result.prerelease = v.prerelease
+		result.metadata = v.metadata
+	}
+	return result
+}
+
+// parseConstraint parses a Terraform version constraint into its comma-separated clauses.
+//
+// Returns:
+//   - []constraintClause: The clauses in their written order
+//   - bool: false when any clause is not an operator followed by a version
+func parseConstraint(s string) ([]constraintClause, bool) {
+	if strings.TrimSpace(s) == "" {
+		return nil, false
+	}
+
+	var clauses []constraintClause
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		clause := constraintClause{}
+		for _, operator := range constraintOperators {
+			if rest, ok := strings.CutPrefix(part, operator); ok {
+				clause.operator = operator
+				part = rest
+				break
+			}
+		}
+		version, ok := parseSemanticVersion(part)
+		if !ok {
+			return nil, false
+		}
+		clause.version = version
+		clauses = append(clauses, clause)
+	}
+	return clauses, true
+}
+
+// formatConstraint joins clauses using Terraform's conventional "op version, op version" style.
+func formatConstraint(clauses []constraintClause) string {
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		if clause.operator == "" {
+			parts = append(parts, clause.version.String())
+			continue
+		}
+		parts = append(parts, clause.operator+" "+clause.version.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isLowerBoundOperator reports whether an operator sets the floor of a constraint.
+func isLowerBoundOperator(operator string) bool {
+	switch operator {
+	case "", "=", ">=", ">", "~>":
+		return true
+	}
+	return false
+}
+
+// isUpperBoundOperator reports whether an operator sets the ceiling of a constraint.
+func isUpperBoundOperator(operator string) bool {
+	return operator == "<" || operator == "<="
+}
+
+// constraintTargetVersion extracts the version to move to from a configured target, which may
+// be a bare version ("5.1.0") or a constraint whose floor names the version ("~> 5.1").
+func constraintTargetVersion(target string) (semanticVersion, bool) {
+	clauses, ok := parseConstraint(target)
+	if !ok {
+		return semanticVersion{}, false
+	}
+	for _, clause := range clauses {
+		if isLowerBoundOperator(clause.operator) {
+			return clause.version, true
+		}
+	}
+	return semanticVersion{}, false
+}
+
+// preserveConstraintOperator rewrites the current constraint so that it names the target version
+// while keeping the operators and precision already in use.
+//
+// Single clauses keep their operator and segment count, so "~> 4.2" becomes "~> 5.1" for a
+// 5.1.0 target. Ranges move their floor to the target and shift each ceiling by the same
+// distance it previously sat above the floor, so ">= 4.0, < 5.0" becomes ">= 5.1, < 6.0".
+// Exclusions ("!=") are kept unchanged.
+//
+// Parameters:
+//   - current: The constraint currently written in the file
+//   - target: The configured target version or constraint
+//
+// Returns:
+//   - string: The rewritten constraint, or target when either value cannot be interpreted
+func preserveConstraintOperator(current, target string) string {
+	clauses, ok := parseConstraint(current)
+	if !ok {
+		return target
+	}
+	version, ok := constraintTargetVersion(target)
+	if !ok {
+		return target
+	}
+
+	floorIndex := -1
+	for index, clause := range clauses {
+		if isLowerBoundOperator(clause.operator) {
+			floorIndex = index
+			break
+		}
+	}
+
+	rewritten := make([]constraintClause, len(clauses))
+	copy(rewritten, clauses)
+	if floorIndex < 0 {
+		for index, clause := range clauses {
+			if isUpperBoundOperator(clause.operator) {
+				rewritten[index].version = retainVersionStyle(version, clause.version)
+			}
+		}
+		return formatConstraint(rewritten)
+	}
+
+	oldFloor := clauses[floorIndex].version
+	newFloor := retainVersionStyle(version, oldFloor)
+	rewritten[floorIndex].version = newFloor
+	for index, clause := range clauses {
+		if isUpperBoundOperator(clause.operator) {
+			rewritten[index].version = shiftCeiling(clause.version, oldFloor, newFloor)
+		}
+	}
+	return formatConstraint(rewritten)
+}
+
+// retainVersionStyle formats version with the precision and prefix of the version it replaces.
+func retainVersionStyle(version, replaced semanticVersion) semanticVersion {
+	result := version.withPrecision(len(replaced.segments))
+	result.vPrefix = replaced.vPrefix
+	return result
+}
+
+// shiftCeiling moves an upper bound so that it keeps its distance from a moved floor. The first
+// segment in which the ceiling differs from the old floor is offset from the new floor by the
+// same amount; later segments keep their written values.
+func shiftCeiling(ceiling, oldFloor, newFloor semanticVersion) semanticVersion {
+	precision := len(ceiling.segments)
+	shifted := semanticVersion{vPrefix: ceiling.vPrefix}
+	differs := -1
+	for index := 0; index < precision; index++ {
+		if ceiling.segment(index) != oldFloor.segment(index) {
+			differs = index
+			break
+		}
+	}
+	for index := 0; index < precision; index++ {
+		switch {
+		case differs < 0 || index < differs:
+			shifted.segments = append(shifted.segments, newFloor.segment(index))
+		case index == differs:
+			shifted.segments = append(shifted.segments, newFloor.segment(index)+ceiling.segment(index)-oldFloor.segment(index))
+		default:
+			shifted.segments = append(shifted.segments, ceiling.segment(index))
+		}
+	}
+	return shifted
+}
+
+// compareVersions orders two versions by semantic-versioning precedence. Unwritten segments
+// count as zero, a pre-release sorts before its release, and build metadata is ignored.
+//
+// Returns:
+//   - int: -1 when a < b, 0 when they are equal, and 1 when a > b
+func compareVersions(a, b semanticVersion) int {
+	for index := 0; index < max(len(a.segments), len(b.segments), 3); index++ {
+		if a.segment(index) != b.segment(index) {
+			if a.segment(index) < b.segment(index) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(a.prerelease, b.prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for index := 0; index < len(aParts) && index < len(bParts); index++ {
+		if result := comparePrereleaseIdentifier(aParts[index], bParts[index]); result != 0 {
+			return result
+		}
+	}
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+func comparePrereleaseIdentifier(a, b string) int {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// versionBound is one end of the interval that a constraint permits.
+type versionBound struct {
+	version   semanticVersion
+	inclusive bool
+}
+
+// versionRange is the interval of versions that a constraint permits. A nil bound is open.
+type versionRange struct {
+	lower *versionBound
+	upper *versionBound
+}
+
+// constraintRange intersects the clauses of a constraint into a single interval. Exclusions
+// ("!=") do not narrow the interval.
+func constraintRange(clauses []constraintClause) versionRange {
+	var result versionRange
+	for _, clause := range clauses {
+		switch clause.operator {
+		case "", "=":
+			result.lower = tighterLower(result.lower, &versionBound{version: clause.version, inclusive: true})
+			result.upper = tighterUpper(result.upper, &versionBound{version: clause.version, inclusive: true})
+		case ">=":
+			result.lower = tighterLower(result.lower, &versionBound{version: clause.version, inclusive: true})
+		case ">":
+			result.lower = tighterLower(result.lower, &versionBound{version: clause.version})
+		case "<=":
+			result.upper = tighterUpper(result.upper, &versionBound{version: clause.version, inclusive: true})
+		case "<":
+			result.upper = tighterUpper(result.upper, &versionBound{version: clause.version})
+		case "~>":
+			result.lower = tighterLower(result.lower, &versionBound{version: clause.version, inclusive: true})
+			result.upper = tighterUpper(result.upper, &versionBound{version: pessimisticCeiling(clause.version)})
+		}
+	}
+	return result
+}
+
+// empty reports whether the interval permits no version, as when the clauses of "5, 0.0" each
+// require a different version.
+func (r versionRange) empty() bool {
+	if r.lower == nil || r.upper == nil {
+		return false
+	}
+	switch compareVersions(r.lower.version, r.upper.version) {
+	case 1:
+		return true
+	case 0:
+		return !r.lower.inclusive || !r.upper.inclusive
+	}
+	return false
+}
+
+// pessimisticCeiling returns the exclusive upper bound of "~> version": the rightmost written
+// segment may vary, so the segment before it is incremented ("~> 4.2" permits up to 5.0).
+func pessimisticCeiling(version semanticVersion) semanticVersion {
+	if len(version.segments) <= 1 {
+		return semanticVersion{segments: []int{version.segment(0) + 1}}
+	}
+	segments := append([]int(nil), version.segments[:len(version.segments)-1]...)
+	segments[len(segments)-1]++
+	return semanticVersion{segments: segments}
+}
+
+func tighterLower(current, candidate *versionBound) *versionBound {
+	if current == nil {
+		return candidate
+	}
+	switch compareVersions(candidate.version, current.version) {
+	case 1:
+		return candidate
+	case 0:
+		if !candidate.inclusive {
+			return candidate
+		}
+	}
+	return current
+}
+
+func tighterUpper(current, candidate *versionBound) *versionBound {
+	if current == nil {
+		return candidate
+	}
+	switch compareVersions(candidate.version, current.version) {
+	case -1:
+		return candidate
+	case 0:
+		if !candidate.inclusive {
+			return candidate
+		}
+	}
+	return current
+}
+
+// isDowngrade reports whether replacing the current constraint with the new one would roll a
+// block backwards: every version the new value permits is older than the oldest version the
+// current value permits. Loosening a constraint, such as replacing "5.2.0" with "~> 5.0", is not
+// a downgrade because it still permits the current version.
+//
+// Values that cannot be interpreted as constraints, or a current value without a floor, are never
+// reported as downgrades.
+func isDowngrade(current, next string) bool {
+	currentClauses, ok := parseConstraint(current)
+	if !ok {
+		return false
+	}
+	nextClauses, ok := parseConstraint(next)
+	if !ok {
+		return false
+	}
+
+	floor := constraintRange(currentClauses).lower
+	ceiling := constraintRange(nextClauses).upper
+	if floor == nil || ceiling == nil {
+		return false
+	}
+	comparison := compareVersions(ceiling.version, floor.version)
+	return comparison < 0 || (comparison == 0 && (!ceiling.inclusive || !floor.inclusive))
+}
+
+// Pre-release policies control whether a pre-release target such as "6.0.0-beta1" may be written.
+// An empty policy behaves as prereleaseAllow.
+const (
+	prereleaseAllow     = "allow"
+	prereleaseIfCurrent = "if-current"
+	prereleaseExclude   = "exclude"
+)
+
+// ValidPrereleasePolicy reports whether policy is empty or one of the recognised policies.
+func ValidPrereleasePolicy(policy string) bool {
+	switch policy {
+	case "", prereleaseAllow, prereleaseIfCurrent, prereleaseExclude:
+		return true
+	}
+	return false
+}
+
+// hasPrerelease reports whether any clause of a constraint names a pre-release version.
+func hasPrerelease(constraint string) bool {
+	clauses, ok := parseConstraint(constraint)
+	if !ok {
+		return false
+	}
+	for _, clause := range clauses {
+		if clause.version.prerelease != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// prereleasePermitted reports whether a policy permits replacing the current constraint with the
+// new one. Values without a pre-release are always permitted; "if-current" permits a pre-release
+// only when the current value already names one.
+func prereleasePermitted(policy, current, next string) bool {
+	if !hasPrerelease(next) {
+		return true
+	}
+	switch policy {
+	case prereleaseExclude:
+		return false
+	case prereleaseIfCurrent:
+		return hasPrerelease(current)
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package bump
+
+import "testing"
+
+func TestParseConstraintContract(t *testing.T) {
+	tests := []struct {
+		input, want string
+		ok          bool
+	}{
+		{input: "5.0.0", want: "5.0.0", ok: true},
+		{input: "v1.2", want: "v1.2", ok: true},
+		{input: "~>4.2", want: "~> 4.2", ok: true},
+		{input: ">=4.0,<5.0", want: ">= 4.0, < 5.0", ok: true},
+		{input: "~> 3.0.0-beta.1+build.5", want: "~> 3.0.0-beta.1+build.5", ok: true},
+		{input: "!= 1.0.0", want: "!= 1.0.0", ok: true},
+		{input: "", ok: false},
+		{input: "latest", ok: false},
+		{input: "1.2.3.4", ok: false},
+		{input: ">= 1.0,", ok: false},
+		{input: "local.vpc_version", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			clauses, ok := parseConstraint(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("parseConstraint(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			}
+			if ok && formatConstraint(clauses) != tt.want {
+				t.Errorf("formatConstraint = %q, want %q", formatConstraint(clauses), tt.want)
+			}
+		})
+	}
+}
+
+func TestPreserveConstraintOperatorContract(t *testing.T) {
+	tests := []struct {
+		name, current, target, want string
+	}{
+		{name: "pessimistic minor", current: "~> 4.2", target: "5.1.0", want: "~> 5.1"},
+		{name: "pessimistic patch", current: "~> 4.2.0", target: "5.1.3", want: "~> 5.1.3"},
+		{name: "pessimistic padded", current: "~> 4.2.0", target: "5.1", want: "~> 5.1.0"},
+		{name: "exact", current: "4.2.0", target: "5.1.0", want: "5.1.0"},
+		{name: "explicit equality", current: "= 4.2.0", target: "5.1.0", want: "= 5.1.0"},
+		{name: "minimum", current: ">= 4.0", target: "5.1.0", want: ">= 5.1"},
+		{name: "major range", current: ">= 4.0, < 5.0", target: "5.1.0", want: ">= 5.1, < 6.0"},
+		{name: "minor range", current: ">= 4.2.0, < 4.5.0", target: "5.1.0", want: ">= 5.1.0, < 5.4.0"},
+		{name: "exclusion kept", current: ">= 4.0, != 4.3.1, <= 5.0", target: "5.2.0", want: ">= 5.2, != 4.3.1, <= 6.0"},
+		{name: "compact spacing normalised", current: ">=4.0,<5.0", target: "5.1.0", want: ">= 5.1, < 6.0"},
+		{name: "v prefix follows current", current: "~> v4.2", target: "5.1.0", want: "~> v5.1"},
+		{name: "target constraint floor", current: "~> 4.2", target: "~> 5.1.0", want: "~> 5.1"},
+		{name: "ceiling only", current: "< 5.0", target: "6.0.0", want: "< 6.0"},
+		{name: "pre-release kept at full precision", current: "4.2.0", target: "5.0.0-beta1", want: "5.0.0-beta1"},
+		{name: "pre-release dropped when truncated", current: "~> 4.2", target: "5.0.0-beta1", want: "~> 5.0"},
+		{name: "non-literal current", current: "local.vpc_version", target: "5.1.0", want: "5.1.0"},
+		{name: "unparseable target", current: "~> 4.2", target: "latest", want: "latest"},
+		{name: "ceiling-only target", current: "~> 4.2", target: "< 6.0", want: "< 6.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preserveConstraintOperator(tt.current, tt.target); got != tt.want {
+				t.Errorf("preserveConstraintOperator(%q, %q) = %q, want %q", tt.current, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDowngradeContract(t *testing.T) {
+	tests := []struct {
+		name, current, next string
+		want                bool
+	}{
+		{name: "older exact pin", current: "5.2.0", next: "5.0.0", want: true},
+		{name: "newer exact pin", current: "5.0.0", next: "5.2.0", want: false},
+		{name: "same version", current: "5.2.0", next: "5.2.0", want: false},
+		{name: "padded precision", current: "5.2", next: "5.2.0", want: false},
+		{name: "pessimistic ceiling below floor", current: "~> 5.2", next: "~> 4.67", want: true},
+		{name: "loosened constraint permits current", current: "5.2.0", next: "~> 5.0", want: false},
+		{name: "range ceiling at floor", current: ">= 5.0", next: ">= 4.0, < 5.0", want: true},
+		{name: "inclusive ceiling at floor", current: ">= 5.0", next: "<= 5.0", want: false},
+		{name: "exclusive floor", current: "> 5.0.0", next: "5.0.0", want: true},
+		{name: "open-ended target", current: "5.2.0", next: ">= 4.0", want: false},
+		{name: "pre-release precedes release", current: "6.0.0", next: "6.0.0-beta1", want: true},
+		{name: "release follows pre-release", current: "6.0.0-beta1", next: "6.0.0", want: false},
+		{name: "numeric pre-release identifiers", current: "6.0.0-rc.10", next: "6.0.0-rc.9", want: true},
+		{name: "ceiling-only current", current: "< 6.0", next: "4.0.0", want: false},
+		{name: "non-literal current", current: "local.vpc_version", next: "5.0.0", want: false},
+		{name: "unparseable target", current: "5.2.0", next: "latest", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDowngrade(tt.current, tt.next); got != tt.want {
+				t.Errorf("isDowngrade(%q, %q) = %v, want %v", tt.current, tt.next, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrereleasePermittedContract(t *testing.T) {
+	tests := []struct {
+		name, policy, current, next string
+		want                        bool
+	}{
+		{name: "release always permitted", policy: prereleaseExclude, current: "5.2.0", next: "6.0.0", want: true},
+		{name: "default allows", policy: "", current: "5.2.0", next: "6.0.0-beta1", want: true},
+		{name: "allow", policy: prereleaseAllow, current: "5.2.0", next: "6.0.0-beta1", want: true},
+		{name: "exclude", policy: prereleaseExclude, current: "6.0.0-alpha1", next: "6.0.0-beta1", want: false},
+		{name: "if-current from release", policy: prereleaseIfCurrent, current: "5.2.0", next: "6.0.0-beta1", want: false},
+		{name: "if-current from pre-release", policy: prereleaseIfCurrent, current: "~> 6.0.0-alpha1", next: "6.0.0-beta1", want: true},
+		{name: "if-current without current", policy: prereleaseIfCurrent, current: "", next: "6.0.0-beta1", want: false},
+		{name: "pre-release in range clause", policy: prereleaseExclude, current: "5.2.0", next: ">= 6.0.0-rc.1, < 7.0", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prereleasePermitted(tt.policy, tt.current, tt.next); got != tt.want {
+				t.Errorf("prereleasePermitted(%q, %q, %q) = %v, want %v", tt.policy, tt.current, tt.next, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,20 @@ package main
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
 )
 
 func TestParseFlagsContract(t *testing.T) {
-	args := []string{"tf-version-bump", "-pattern", "**/*.tf", "-module", "example/module", "-to", "2.0.0", "-from", "1.0.0", "-from", "1.5.0", "-ignore-version", "3.0.0", "-ignore-modules", "vpc, legacy-*", "-config", "config.yml", "-force-add", "-dry-run", "-verbose", "-version", "-output", "md", "-terraform-version", ">= 1.5", "-provider", "aws"}
+	args := []string{"tf-version-bump", "-pattern", "**/*.tf", "-module", "example/module", "-to", "2.0.0", "-from", "1.0.0", "-from", "1.5.0", "-ignore-version", "3.0.0", "-ignore-modules", "vpc, legacy-*", "-config", "config.yml", "-force-add", "-dry-run", "-verbose", "-version", "-output", "md", "-terraform-version", ">= 1.5", "-provider", "aws", "-preserve-operator", "-allow-downgrade", "-prerelease", "if-current", "-terragrunt", "-opentofu", "-provider-source", "hashicorp/aws", "-add-missing", "-follow-references", "-lint", "-lint-rule", "git-missing-ref=warning", "-policy", "policy.yml", "-advisories", "advisories.json", "-fix"}
 	withFlagArgs(t, args, func() {
 		got := parseFlags()
-		want := &cliFlags{pattern: "**/*.tf", moduleSource: "example/module", toVersion: "2.0.0", fromVersions: stringSliceFlag{"1.0.0", "1.5.0"}, ignoreVersions: stringSliceFlag{"3.0.0"}, ignoreModules: "vpc, legacy-*", configFile: "config.yml", forceAdd: true, dryRun: true, verbose: true, showVersion: true, output: "md", terraformVersion: ">= 1.5", providerName: "aws"}
+		want := &cliFlags{pattern: "**/*.tf", moduleSource: "example/module", toVersion: "2.0.0", fromVersions: stringSliceFlag{"1.0.0", "1.5.0"}, ignoreVersions: stringSliceFlag{"3.0.0"}, ignoreModules: "vpc, legacy-*", configFile: "config.yml", forceAdd: true, dryRun: true, verbose: true, showVersion: true, output: "md", terraformVersion: ">= 1.5", providerName: "aws", preserveOperator: true, allowDowngrade: true, prerelease: "if-current", terragrunt: true, opentofu: true, providerSource: "hashicorp/aws", addMissing: true, followReferences: true, lint: true, lintRules: stringSliceFlag{"git-missing-ref=warning"}, policyFile: "policy.yml", advisoryFile: "advisories.json", fix: true, flagsSet: 27}
 		if !reflect.DeepEqual(got, want) {
 			t.Fatalf("flags = %#v, want %#v", got, want)
 		}
@@ -22,7 +26,7 @@ func TestParseFlagsContract(t *testing.T) {
 func TestLoadModuleUpdatesContract(t *testing.T) {
 	flags := &cliFlags{pattern: "*.tf", moduleSource: "example/module", toVersion: "2.0.0", fromVersions: stringSliceFlag{"1.0.0", "1.5.0"}, ignoreVersions: stringSliceFlag{"3.0.0", "~> 3.0"}, ignoreModules: "vpc, legacy-*"}
 	got := loadModuleUpdates(flags)
-	want := []ModuleUpdate{{Source: "example/module", Version: "2.0.0", From: FromVersions{"1.0.0", "1.5.0"}, IgnoreVersions: FromVersions{"3.0.0", "~> 3.0"}, IgnoreModules: []string{"vpc", "legacy-*"}}}
+	want := []bump.ModuleUpdate{{Source: "example/module", Version: "2.0.0", From: bump.FromVersions{"1.0.0", "1.5.0"}, IgnoreVersions: bump.FromVersions{"3.0.0", "~> 3.0"}, IgnoreModules: []string{"vpc", "legacy-*"}}}
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("updates = %#v, want %#v", got, want)
 	}
@@ -39,13 +43,38 @@ func TestParseFlagsRejectsInvalidOutput(t *testing.T) {
 	}
 }
 
+func TestParseFlagsRejectsInvalidPrereleasePolicy(t *testing.T) {
+	restore, _ := stubExit(t)
+	t.Cleanup(restore)
+	got := captureLog(t, func() {
+		withFlagArgs(t, []string{"tf-version-bump", "-prerelease", "never"}, func() { requireExitCall(t, func() { parseFlags() }) })
+	})
+	if got != "Error: Invalid pre-release policy 'never'. Must be 'allow', 'if-current', or 'exclude'\n" {
+		t.Fatalf("diagnostic: %q", got)
+	}
+}
+
 func TestValidateOperationModesContract(t *testing.T) {
 	tests := []struct {
 		name  string
 		flags *cliFlags
 		want  string
 	}{
-		{"config mixed", &cliFlags{configFile: "x", moduleSource: "m"}, "Error: Cannot use -config with other operation flags (-module, -to, -terraform-version, -provider, -from, -ignore-version, -ignore-modules)\n"},
+		{"config mixed", &cliFlags{configFile: "x", moduleSource: "m"}, "Error: Cannot use -config with other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)\n"},
+		{"config with add-missing", &cliFlags{configFile: "x", addMissing: true}, "Error: Cannot use -config with other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)\n"},
+		{"add-missing without provider", &cliFlags{moduleSource: "m", addMissing: true, providerSource: "hashicorp/aws"}, "Error: -provider-source and -add-missing require -provider\n"},
+		{"add-missing without source", &cliFlags{providerName: "aws", addMissing: true}, "Error: -add-missing requires -provider-source\n"},
+		{"invalid provider source", &cliFlags{providerName: "aws", addMissing: true, providerSource: "a/b/c/d"}, "Error: Invalid provider source 'a/b/c/d': Invalid provider source string: The \"source\" attribute must be in the format \"[hostname/][namespace/]name\"\n"},
+		{"lint with operation", &cliFlags{lint: true, providerName: "aws"}, "Error: Cannot use -lint with -config or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)\n"},
+		{"lint with report file", &cliFlags{lint: true, reportFile: "report.json"}, "Error: Cannot use -report-file with -lint\n"},
+		{"invalid lint severity", &cliFlags{lint: true, lintRules: stringSliceFlag{"git-missing-ref=fatal"}}, "Error: Invalid -lint-rule: invalid severity \"fatal\" for lint rule \"git-missing-ref\": must be 'error', 'warning', or 'off'\n"},
+		{"lint rule without lint", &cliFlags{moduleSource: "m", lintRules: stringSliceFlag{"git-missing-ref=off"}}, "Error: -lint-rule requires -lint\n"},
+		{"policy with lint", &cliFlags{policyFile: "policy.yml", lint: true}, "Error: Cannot use -policy with -config, -lint, or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)\n"},
+		{"advisories with policy", &cliFlags{advisoryFile: "advisories.json", policyFile: "policy.yml"}, "Error: Cannot use -advisories with -config, -lint, -policy, or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)\n"},
+		{"advisories with report file", &cliFlags{advisoryFile: "advisories.json", reportFile: "report.json"}, "Error: Cannot use -report-file with -advisories\n"},
+		{"policy with preserve operator", &cliFlags{policyFile: "policy.yml", preserveOperator: true}, "Error: Cannot use -policy with -preserve-operator, -allow-downgrade, -prerelease, or -follow-references\n"},
+		{"advisories without fix with prerelease", &cliFlags{advisoryFile: "advisories.json", prerelease: "exclude"}, "Error: -preserve-operator, -allow-downgrade, -prerelease, and -follow-references require -advisories -fix\n"},
+		{"fix without policy", &cliFlags{moduleSource: "m", fix: true}, "Error: -fix requires -policy or -advisories\n"},
 		{"no operation", &cliFlags{}, "Usage:\n"},
 		{"multiple operations", &cliFlags{moduleSource: "m", terraformVersion: "x"}, "Error: Cannot use -module, -terraform-version, and -provider flags together. Choose one operation mode or use a config file.\n"},
 	}
@@ -121,7 +150,8 @@ func TestRunCLIModeMarkdownOutput(t *testing.T) {
 	dir := t.TempDir()
 	file := writeTestFile(t, dir, "main.tf", "module \"x\" {\n  source = \"example/module\"\n  version = \"1.0.0\"\n}\n")
 	r := runMainCommand(t, []string{"tf-version-bump", "-pattern", file, "-module", "example/module", "-to", "2.0.0", "-output", "md"})
-	want := "Found 1 file(s) matching pattern `" + file + "`\n✓ Updated module source `example/module` to version `2.0.0` in " + file + "\n\nSuccessfully updated 1 file(s)\n"
+	want := "Found 1 file(s) matching pattern `" + file + "`\n✓ Updated module source `example/module` to version `2.0.0` in " + file + "\n\nSuccessfully updated 1 file(s)\n" +
+		"Updated 1 module directory(ies):\n  " + dir + ": 1 update(s) in 1 file(s)\n"
 	if r.stdout != want || r.diagnostics != "" || r.exitCode != -1 {
 		t.Fatalf("result %#v", r)
 	}
@@ -175,7 +205,8 @@ func TestCommandDryRunOutputContract(t *testing.T) {
 			result := runMainCommand(t, args)
 			wantStdout := "Found 1 file(s) matching pattern " + tt.selectionQuote + file + tt.selectionQuote + "\n" +
 				"Running in dry-run mode - no files will be modified\n" +
-				tt.wantOperation(file) + "\n" + tt.wantSummary
+				tt.wantOperation(file) + "\n" + tt.wantSummary +
+				"Would update 1 module directory(ies):\n  " + filepath.Dir(file) + ": 1 update(s) in 1 file(s)\n"
 
 			if result.stdout != wantStdout {
 				t.Errorf("stdout = %q, want %q", result.stdout, wantStdout)
@@ -213,7 +244,8 @@ func TestCommandConfigDryRunOutputContract(t *testing.T) {
 		"==================================================\n" +
 		"Terraform version: would update 1 file(s)\n" +
 		"Providers: would apply 1 update(s)\n" +
-		"Modules: would apply 1 update(s)\n"
+		"Modules: would apply 1 update(s)\n" +
+		"Would update 1 module directory(ies):\n  " + dir + ": 3 update(s) in 1 file(s)\n"
 
 	if result.stdout != wantStdout {
 		t.Errorf("stdout = %q, want %q", result.stdout, wantStdout)
@@ -227,7 +259,7 @@ func TestCommandConfigDryRunOutputContract(t *testing.T) {
 	if got := readTestFile(t, file); got != input {
 		t.Errorf("config dry run content = %q, want %q", got, input)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 0,\n  \"provider_blocks_updated\": 0\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 0,\n  \"provider_blocks_updated\": 0,\n" + directoryReport(dir, 3, 1)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Errorf("dry-run report = %q, want %q", got, wantReport)
 	}
@@ -288,7 +320,7 @@ modules:
 	if result.exitCode != -1 || result.diagnostics != "" {
 		t.Fatalf("result = %#v", result)
 	}
-	want := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 2,\n  \"provider_blocks_updated\": 2\n}\n"
+	want := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 2,\n  \"provider_blocks_updated\": 2,\n" + directoryReport(dir, 2, 1)
 	if got := readTestFile(t, report); got != want {
 		t.Fatalf("report = %q, want %q", got, want)
 	}
@@ -342,7 +374,7 @@ modules:
 	if result.exitCode != -1 || result.diagnostics != "" {
 		t.Fatalf("result = %#v", result)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 3,\n  \"provider_blocks_updated\": 2\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 3,\n  \"provider_blocks_updated\": 2,\n" + directoryReport(dir, 4, 2)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Errorf("report = %q, want %q", got, wantReport)
 	}
@@ -371,7 +403,7 @@ func TestCommandReportCountsForceAddedModuleBlock(t *testing.T) {
 	if result.exitCode != -1 || result.diagnostics != "" {
 		t.Fatalf("result = %#v", result)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 0\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 0,\n" + directoryReport(dir, 1, 1)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Errorf("report = %q, want %q", got, wantReport)
 	}
@@ -381,19 +413,21 @@ func TestCommandReportCountsForceAddedModuleBlock(t *testing.T) {
 	}
 }
 
-func TestProcessFilesSkipsReportBookkeepingWhenDisabled(t *testing.T) {
+func TestModuleModeSkipsReportBookkeepingWhenDisabled(t *testing.T) {
 	dir := t.TempDir()
 	file := writeTestFile(t, dir, "main.tf", "module \"example\" {\n  source  = \"example/module\"\n  version = \"1.0.0\"\n}\n")
-	flags := &cliFlags{}
-	updates := []ModuleUpdate{{Source: "example/module", Version: "2.0.0"}}
+	flags := &cliFlags{pattern: "*.tf", moduleSource: "example/module", toVersion: "2.0.0", output: "text"}
 
-	var updatesApplied, updateErrors int
+	var err error
 	captureStdout(t, func() {
-		updatesApplied, updateErrors = processFiles([]string{file}, updates, flags)
+		err = runCLIMode([]string{file}, flags)
 	})
 
-	if updatesApplied != 1 || updateErrors != 0 {
-		t.Fatalf("processFiles() = (%d, %d), want (1, 0)", updatesApplied, updateErrors)
+	if err != nil {
+		t.Fatalf("runCLIMode() error = %v", err)
+	}
+	if got := readTestFile(t, file); !strings.Contains(got, `version = "2.0.0"`) {
+		t.Fatalf("main.tf not updated:\n%s", got)
 	}
 	if flags.report.moduleBlockIDs != nil || flags.report.fileIdentities != nil {
 		t.Fatalf("disabled report bookkeeping = %#v", flags.report)
@@ -475,7 +509,7 @@ modules:
 	if result.exitCode != -1 || result.diagnostics != "" {
 		t.Fatalf("result = %#v", result)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 1\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 1,\n" + directoryReport(dir, 4, 1)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Errorf("report = %q, want %q", got, wantReport)
 	}
@@ -507,7 +541,7 @@ func TestCommandReportCountsHardLinkedBlockOnce(t *testing.T) {
 	if result.exitCode != -1 || result.diagnostics != "" {
 		t.Fatalf("result = %#v", result)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 0\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 0,\n" + directoryReport(dir, 3, 2)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Errorf("report = %q, want %q", got, wantReport)
 	}
@@ -711,11 +745,12 @@ modules:
 		"Config File Update Summary\n" +
 		"==================================================\n" +
 		"Providers: 1 update(s) applied\n" +
-		"Modules: 1 update(s) applied\n"
+		"Modules: 1 update(s) applied\n" +
+		"Updated 1 module directory(ies):\n  " + dir + ": 2 update(s) in 1 file(s)\n"
 	if result.exitCode != -1 || result.diagnostics != "" || result.stdout != wantStdout {
 		t.Fatalf("result = %#v, want stdout %q", result, wantStdout)
 	}
-	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 0,\n  \"provider_blocks_updated\": 0\n}\n"
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 0,\n  \"provider_blocks_updated\": 0,\n" + directoryReport(dir, 2, 1)
 	if got := readTestFile(t, report); got != wantReport {
 		t.Fatalf("report = %q, want %q", got, wantReport)
 	}
@@ -739,6 +774,7 @@ func TestCommandReportsAggregateFileFailure(t *testing.T) {
 			} else {
 				wantStdout += "==================================================\nConfig File Update Summary\n==================================================\nModules: 1 update(s) applied\n"
 			}
+			wantStdout += "Updated 1 module directory(ies):\n  " + dir + ": 1 update(s) in 1 file(s)\n"
 			wantDiag := "Error processing " + bad + ": failed to parse HCL: " + bad + ":1,1-2: Argument or block definition required; An argument or block definition is required here.\n1 module update error(s)\n"
 			wantHCL := "module \"example\" {\n  source  = \"example/module\"\n  version = \"2.0.0\"\n}\n"
 			if r.stdout != wantStdout || r.diagnostics != wantDiag || r.exitCode != 1 || readTestFile(t, good) != wantHCL {
@@ -747,3 +783,82 @@ func TestCommandReportsAggregateFileFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestCommandPreserveOperatorFlag(t *testing.T) {
+	file := writeTestFile(t, t.TempDir(), "main.tf", "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 4.2\"\n}\n")
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", file, "-module", "terraform-aws-modules/vpc/aws", "-to", "5.1.0", "-preserve-operator"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"~> 5.1\"\n}\n"
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
+
+func TestCommandAllowDowngradeFlag(t *testing.T) {
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.2.0\"\n}\n"
+	file := writeTestFile(t, t.TempDir(), "main.tf", input)
+	args := []string{"tf-version-bump", "-pattern", file, "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0"}
+
+	result := runMainCommand(t, args)
+	if result.exitCode != -1 || readTestFile(t, file) != input {
+		t.Fatalf("result = %#v, want the downgrade skipped", result)
+	}
+
+	result = runMainCommand(t, append(args, "-allow-downgrade"))
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if got := readTestFile(t, file); !strings.Contains(got, `version = "5.0.0"`) {
+		t.Errorf("content = %q, want the downgrade applied", got)
+	}
+}
+
+func TestCommandConfigPrereleasePolicy(t *testing.T) {
+	dir := t.TempDir()
+	stable := "module \"stable\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.2.0\"\n}\n"
+	beta := "module \"beta\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"6.0.0-beta1\"\n}\n"
+	stableFile := writeTestFile(t, dir, "stable.tf", stable)
+	betaFile := writeTestFile(t, dir, "beta.tf", beta)
+	config := writeTestFile(t, dir, "updates.yml", "prerelease: if-current\nmodules:\n  - source: terraform-aws-modules/vpc/aws\n    version: 6.0.0-rc.1\n")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-config", config})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if got := readTestFile(t, stableFile); got != stable {
+		t.Errorf("stable content = %q, want unchanged", got)
+	}
+	if got := readTestFile(t, betaFile); !strings.Contains(got, `version = "6.0.0-rc.1"`) {
+		t.Errorf("beta content = %q, want the pre-release target", got)
+	}
+}
+
+func TestCommandUpdatesNativeAndJSONSyntax(t *testing.T) {
+	dir := t.TempDir()
+	native := writeTestFile(t, dir, "main.tf", "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n")
+	generated := writeTestFile(t, dir, "cdk.tf.json", "{\n  \"module\": {\n    \"vpc\": {\n      \"source\": \"terraform-aws-modules/vpc/aws\",\n      \"version\": \"4.0.0\"\n    }\n  }\n}\n")
+	report := dir + "/report.json"
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.{tf,tf.json}", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0", "-report-file", report})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if got := readTestFile(t, native); !strings.Contains(got, `version = "5.0.0"`) {
+		t.Errorf("native content = %q", got)
+	}
+	if got := readTestFile(t, generated); !strings.Contains(got, `"version": "5.0.0"`) {
+		t.Errorf("JSON content = %q", got)
+	}
+	if got := readTestFile(t, report); !strings.Contains(got, `"module_blocks_updated": 2`) {
+		t.Errorf("report = %q", got)
+	}
+}
+
+// directoryReport returns the end of an update report whose changes are all in one module
+// directory.
+func directoryReport(path string, changes, files int) string {
+	return "  \"directories\": [\n    {\n      \"path\": \"" + path + "\",\n      \"changes\": " + strconv.Itoa(changes) +
+		",\n      \"files_updated\": " + strconv.Itoa(files) + "\n    }\n  ]\n}\n"
+}
@@ -1,187 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"fmt"
-	"io"
-	"os"
-	"strings"
-
-	"go.yaml.in/yaml/v3"
-)
-
-// FromVersions is a custom type that can unmarshal both string and []string from YAML
-type FromVersions []string
-
-// UnmarshalYAML implements custom unmarshaling to handle both string and array formats
-func (f *FromVersions) UnmarshalYAML(value *yaml.Node) error {
-	// Check if it's a sequence (array)
-	if value.Kind == yaml.SequenceNode {
-		for _, item := range value.Content {
-			if item.ShortTag() != "!!str" {
-				return fmt.Errorf("version filter array contains non-string values")
-			}
-		}
-
-		var slice []string
-		if err := value.Decode(&slice); err != nil {
-			return fmt.Errorf("version filter array contains non-string values: %w", err)
-		}
-		*f = FromVersions(slice)
-		return nil
-	}
-
-	// Check if it's a scalar (single value)
-	if value.Kind == yaml.ScalarNode {
-		// Only accept string scalars, reject numbers and booleans
-		if value.Tag != "!!str" {
-			return fmt.Errorf("version filter must be a string or array of strings, got %s", value.Tag)
-		}
-
-		str := value.Value
-
-		if str == "" {
-			*f = FromVersions{}
-		} else {
-			*f = FromVersions{str}
-		}
-		return nil
-	}
-
-	return fmt.Errorf("version filter must be either a string or an array of strings, got node kind %v", value.Kind)
-}
-
-// ModuleUpdate represents a single module source and its target version.
-// It is used both for single module updates via CLI flags and for batch
-// updates from YAML configuration files.
-type ModuleUpdate struct {
-	Source         string       `yaml:"source"`          // Module source (e.g., "terraform-aws-modules/vpc/aws")
-	Version        string       `yaml:"version"`         // Target version (e.g., "5.0.0")
-	From           FromVersions `yaml:"from"`            // Optional: only update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
-	IgnoreVersions FromVersions `yaml:"ignore_versions"` // Optional: skip update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
-	IgnoreModules  []string     `yaml:"ignore_modules"`  // Optional: list of module names or patterns to ignore (e.g., ["vpc", "legacy-*"])
-}
-
-// ProviderUpdate represents a provider version update in required_providers blocks
-type ProviderUpdate struct {
-	Name    string `yaml:"name"`    // Provider name (e.g., "aws", "azurerm")
-	Version string `yaml:"version"` // Target version (e.g., "~> 5.0")
-}
-
-// Config represents the structure of a YAML configuration file for batch updates.
-// The YAML file can contain:
-// - A "modules" key with a list of module updates
-// - A "terraform_version" key to update Terraform required_version
-// - A "providers" key with a list of provider updates
-//
-// Example YAML:
-//
-//	terraform_version: ">= 1.5"
-//
-//	providers:
-//	  - name: "aws"
-//	    version: "~> 5.0"
-//	  - name: "azurerm"
-//	    version: "~> 3.5"
-//
-//	modules:
-//	  - source: "terraform-aws-modules/vpc/aws"
-//	    version: "5.0.0"
-//	    from: "4.0.0"          # Optional: only update if current version is 4.0.0
-//	    ignore_versions:       # Optional: versions to skip
-//	      - "3.0.0"
-//	      - "~> 3.0"
-//	    ignore_modules:        # Optional: module names or patterns to ignore
-//	      - "legacy-vpc"
-//	      - "test-*"
-//	  - source: "terraform-aws-modules/s3-bucket/aws"
-//	    version: "4.0.0"
-type Config struct {
-	TerraformVersion string           `yaml:"terraform_version"` // Optional: Terraform required_version to set
-	Providers        []ProviderUpdate `yaml:"providers"`         // Optional: List of provider updates
-	Modules          []ModuleUpdate   `yaml:"modules"`           // Optional: List of module updates
-}
-
-// loadConfig reads and parses a YAML configuration file containing module, terraform version,
-// and provider updates. It validates that all required fields are present.
-//
-// Parameters:
-//   - filename: Path to the YAML configuration file
-//
-// Returns:
-//   - *Config: Configuration structure with all updates
-//   - error: Any error encountered during reading, parsing, or validation
-func loadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var config Config
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
-	decoder.KnownFields(true) // Strict mode: error on unknown fields
-	if err := decoder.Decode(&config); err != nil {
-		// EOF indicates an empty file or a file with only comments, which is valid
-		if err == io.EOF {
-			return &Config{}, nil
-		}
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
-	}
-
-	// Trim and validate terraform_version
-	config.TerraformVersion = strings.TrimSpace(config.TerraformVersion)
-
-	if err := sanitizeProviderUpdates(config.Providers); err != nil {
-		return nil, err
-	}
-	if err := sanitizeModuleUpdates(config.Modules); err != nil {
-		return nil, err
-	}
-
-	return &config, nil
-}
-
-func sanitizeProviderUpdates(providers []ProviderUpdate) error {
-	for i := range providers {
-		providers[i].Name = strings.TrimSpace(providers[i].Name)
-		providers[i].Version = strings.TrimSpace(providers[i].Version)
-
-		if providers[i].Name == "" {
-			return fmt.Errorf("provider at index %d is missing 'name' field", i)
-		}
-		if providers[i].Version == "" {
-			return fmt.Errorf("provider at index %d is missing 'version' field", i)
-		}
-	}
-
-	return nil
-}
-
-func sanitizeModuleUpdates(modules []ModuleUpdate) error {
-	for i := range modules {
-		modules[i].Source = strings.TrimSpace(modules[i].Source)
-		modules[i].Version = strings.TrimSpace(modules[i].Version)
-		modules[i].From = FromVersions(trimNonEmptyStrings(modules[i].From))
-		modules[i].IgnoreVersions = FromVersions(trimNonEmptyStrings(modules[i].IgnoreVersions))
-		modules[i].IgnoreModules = trimNonEmptyStrings(modules[i].IgnoreModules)
-
-		if modules[i].Source == "" {
-			return fmt.Errorf("module at index %d is missing 'source' field", i)
-		}
-		if modules[i].Version == "" {
-			return fmt.Errorf("module at index %d is missing 'version' field", i)
-		}
-	}
-
-	return nil
-}
-
-func trimNonEmptyStrings(values []string) []string {
-	filtered := make([]string, 0, len(values))
-	for _, value := range values {
-		if trimmed := strings.TrimSpace(value); trimmed != "" {
-			filtered = append(filtered, trimmed)
-		}
-	}
-	return filtered
-}
@@ -128,8 +128,8 @@ func assertDistinctSchemaContracts(t *testing.T, schema *configSchema) {
 	}
 	assertVersionConstraintNode(t, "module version", moduleVersion)
 
-	if len(schema.AnyOf) != 3 {
-		t.Fatalf("schema anyOf clauses = %d, want exactly 3", len(schema.AnyOf))
+	if len(schema.AnyOf) != 5 {
+		t.Fatalf("schema anyOf clauses = %d, want exactly 5", len(schema.AnyOf))
 	}
 	requiredTopLevel := make(map[string]bool, len(schema.AnyOf))
 	for _, clause := range schema.AnyOf {
@@ -138,7 +138,7 @@ func assertDistinctSchemaContracts(t *testing.T, schema *configSchema) {
 		}
 		requiredTopLevel[clause.Required[0]] = true
 	}
-	for _, field := range []string{"modules", "providers", "terraform_version"} {
+	for _, field := range []string{"migrations", "modules", "provider_migrations", "providers", "terraform_version"} {
 		if !requiredTopLevel[field] {
 			t.Fatalf("schema anyOf should contain a singleton required clause for %q", field)
 		}
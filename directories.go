@@ -0,0 +1,146 @@
+package main
+
+// Terraform treats the files of a directory as one root module, so the results of an update are
+// also reported per module directory: a directory counts once however many of its files changed. The selected directories are then checked for providers
+// that their files declare differently.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// directoryResult is the outcome of one module directory, as listed in the update report.
+type directoryResult struct {
+	Path         string   `json:"path"`
+	Changes      int      `json:"changes"`
+	FilesUpdated int      `json:"files_updated"`
+	files        []string // The updated files, in the order first changed
+}
+
+// providerConflictRecord lists a provider that the files of a module directory declare
+// differently in the update report.
+type providerConflictRecord struct {
+	Directory    string                      `json:"directory"`
+	Provider     string                      `json:"provider"`
+	Field        string                      `json:"field"`
+	Declarations []providerDeclarationRecord `json:"declarations"`
+}
+
+// providerDeclarationRecord is one of the conflicting declarations of a provider.
+type providerDeclarationRecord struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Value string `json:"value"`
+}
+
+// recordDirectory counts a change against the module directory of its file.
+func (flags *cliFlags) recordDirectory(change bump.Change) {
+	path := filepath.Dir(change.File)
+	index := slices.IndexFunc(flags.directories, func(result directoryResult) bool { return result.Path == path })
+	if index < 0 {
+		flags.directories = append(flags.directories, directoryResult{Path: path})
+		index = len(flags.directories) - 1
+	}
+	result := &flags.directories[index]
+	result.Changes++
+	if !slices.Contains(result.files, change.File) {
+		result.files = append(result.files, change.File)
+		result.FilesUpdated = len(result.files)
+	}
+}
+
+// runUpdateMode runs align, config file, or CLI mode, then reports the module directories the run updated
+// and warns about providers declared differently within each selected directory.
+func runUpdateMode(files []string, flags *cliFlags) error {
+	var err error
+	switch {
+	case flags.align:
+		err = runAlignMode(files, flags)
+	case flags.configFile != "":
+		err = runConfigFileMode(files, flags)
+	default:
+		err = runCLIMode(files, flags)
+	}
+	printDirectorySummary(flags.directories, flags.dryRun)
+	conflicts := findProviderConflicts(files, flags.options())
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", describeConflict(conflict, flags.output))
+	}
+	// Branch mode reports each branch instead
+	if flags.branches == "" {
+		flags.report.Directories = flags.directories
+		flags.report.ProviderConflicts = conflictRecords(conflicts)
+		flags.markdown.conflicts = conflicts
+	}
+	return err
+}
+
+// printDirectorySummary prints the number of module directories with changes and the changes of
+// each. It prints nothing when no directory changed.
+func printDirectorySummary(directories []directoryResult, dryRun bool) {
+	if len(directories) == 0 {
+		return
+	}
+	if dryRun {
+		fmt.Printf("Would update %d module directory(ies):\n", len(directories))
+	} else {
+		fmt.Printf("Updated %d module directory(ies):\n", len(directories))
+	}
+	for _, result := range directories {
+		fmt.Printf("  %s: %d update(s) in %d file(s)\n", result.Path, result.Changes, result.FilesUpdated)
+	}
+}
+
+// findProviderConflicts returns the provider conflicts of each module directory of the files. A
+// directory whose files cannot be read is not checked; the update has already reported them.
+func findProviderConflicts(files []string, opts bump.Options) []bump.ProviderConflict {
+	var conflicts []bump.ProviderConflict
+	for _, directory := range bump.Files(files).Directories() {
+		found, err := bump.FindProviderConflicts(directory, opts)
+		if err != nil {
+			continue
+		}
+		conflicts = append(conflicts, found...)
+	}
+	return conflicts
+}
+
+// describeConflict returns the warning printed for a provider conflict.
+func describeConflict(conflict bump.ProviderConflict, outputFormat string) string {
+	values := make([]string, len(conflict.Declarations))
+	for index, declaration := range conflict.Declarations {
+		values[index] = fmt.Sprintf("%s in %s:%d", quote(conflictValue(conflict, declaration), outputFormat), declaration.File, declaration.Pos.Line)
+	}
+	return fmt.Sprintf("Module directory %s declares provider %s with different %ss: %s",
+		conflict.Directory, quote(conflict.Name, outputFormat), conflict.Field, strings.Join(values, ", "))
+}
+
+// conflictValue returns the conflicting field of a declaration.
+func conflictValue(conflict bump.ProviderConflict, declaration bump.ProviderDeclaration) string {
+	if conflict.Field == "source" {
+		return declaration.Source
+	}
+	return declaration.Version
+}
+
+// conflictRecords returns the update report records of provider conflicts.
+func conflictRecords(conflicts []bump.ProviderConflict) []providerConflictRecord {
+	var records []providerConflictRecord
+	for _, conflict := range conflicts {
+		record := providerConflictRecord{Directory: conflict.Directory, Provider: conflict.Name, Field: conflict.Field}
+		for _, declaration := range conflict.Declarations {
+			record.Declarations = append(record.Declarations, providerDeclarationRecord{
+				File:  declaration.File,
+				Line:  declaration.Pos.Line,
+				Value: conflictValue(conflict, declaration),
+			})
+		}
+		records = append(records, record)
+	}
+	return records
+}
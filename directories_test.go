@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandReportsModuleDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, env := range []string{"dev", "prod"} {
+		if err := os.MkdirAll(filepath.Join(dir, "envs", env), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	module := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	writeTestFile(t, dir, "envs/prod/main.tf", module)
+	writeTestFile(t, dir, "envs/prod/network.tf", module+"\n"+strings.Replace(module, `"vpc"`, `"vpc2"`, 1))
+	writeTestFile(t, dir, "envs/dev/main.tf", module)
+	writeTestFile(t, dir, "envs/prod/versions.tf", "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n  }\n}\n")
+	writeTestFile(t, dir, "envs/prod/providers.tf", "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 6.0\"\n    }\n  }\n}\n")
+	reports := t.TempDir()
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "envs/**/*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0",
+		"-report-file", filepath.Join(reports, "report.json"), "-markdown-report", filepath.Join(reports, "report.md")})
+	if result.exitCode != -1 {
+		t.Fatalf("result = %#v", result)
+	}
+	prod, dev := filepath.Join("envs", "prod"), filepath.Join("envs", "dev")
+	wantSummary := "Updated 2 module directory(ies):\n  " + dev + ": 1 update(s) in 1 file(s)\n  " + prod + ": 2 update(s) in 2 file(s)\n"
+	if !strings.HasSuffix(result.stdout, wantSummary) {
+		t.Errorf("stdout = %q, want suffix %q", result.stdout, wantSummary)
+	}
+	report := readTestFile(t, filepath.Join(reports, "report.json"))
+	for _, want := range []string{
+		"\"path\": \"" + prod + "\",\n      \"changes\": 2,\n      \"files_updated\": 2",
+		"\"provider_conflicts\": [\n    {\n      \"directory\": \"" + prod + "\",\n      \"provider\": \"aws\",\n      \"field\": \"version\"",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report = %s, want it to contain %s", report, want)
+		}
+	}
+	markdown := readTestFile(t, filepath.Join(reports, "report.md"))
+	for _, want := range []string{
+		"### Module directories\n\n| Directory | Updates | Files |\n| --- | ---: | ---: |\n| `" + dev + "` | 1 | 1 |\n| `" + prod + "` | 2 | 2 |\n",
+		"### Provider conflicts\n\n- `" + prod + "` declares provider `aws` with different versions: `~> 6.0` in `" + filepath.Join(prod, "providers.tf") + ":3`",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown report = %s, want it to contain %s", markdown, want)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"unicode"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
 )
 
 func TestExampleConfigsMatchDocumentedContract(t *testing.T) {
@@ -16,7 +18,7 @@ func TestExampleConfigsMatchDocumentedContract(t *testing.T) {
 	constraintPatterns := compileConstraintRegexps(t, loadConfigSchema(t).Definitions.VersionConstraint)
 
 	for _, filename := range configFiles {
-		config, err := loadConfig(filename)
+		config, err := bump.LoadConfig(filename)
 		if err != nil {
 			t.Fatalf("load %s: %v", filename, err)
 		}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+	"go.yaml.in/yaml/v3"
+)
+
+// starterConfigHeader opens a generated starter config.
+const starterConfigHeader = `# Starter config generated by tf-version-bump from the versions in use.
+# Edit the versions to describe the next rollout, then apply it with -config.
+`
+
+// starterConfig is the YAML written by -export-config: the subset of the config file format that
+// records versions, with empty lists left out.
+type starterConfig struct {
+	TerraformVersion string            `yaml:"terraform_version,omitempty"`
+	Providers        []starterProvider `yaml:"providers,omitempty"`
+	Modules          []starterModule   `yaml:"modules,omitempty"`
+}
+
+type starterProvider struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+type starterModule struct {
+	Source  string `yaml:"source"`
+	Version string `yaml:"version"`
+}
+
+// validateExportConfigFlags validates the flags of -export-config, which reads the files without
+// modifying them.
+func validateExportConfigFlags(flags *cliFlags) {
+	if flags.configFile != "" || flags.lint || flags.policyFile != "" || flags.advisoryFile != "" || flags.align || flags.hasOperationFlags() {
+		fatalf("Error: Cannot use -export-config with -config, -lint, -policy, -advisories, -align, or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)")
+	}
+	if flags.reportFile != "" || flags.markdownReport != "" || flags.branches != "" || flags.backup || flags.interactive || flags.dryRun {
+		fatalf("Error: Cannot use -export-config with -report-file, -markdown-report, -branches, -backup, -interactive, or -dry-run")
+	}
+}
+
+// runExportConfigMode writes a starter config listing every module source, provider, and the
+// Terraform required_version in use at its dominant version. "-" writes it to standard output; an
+// existing file is never overwritten.
+//
+// Parameters:
+//   - files: The files to read
+//   - flags: The command-line flags, including the destination
+//
+// Returns:
+//   - error: Non-nil when a file cannot be read, no version is found, or the config cannot be
+//     written
+func runExportConfigMode(files []string, flags *cliFlags) error {
+	var pins []bump.PinnedVersion
+	var requiredVersions []string
+	fileErrors := 0
+	for _, file := range files {
+		filePins, err := bump.FindPinnedVersions(file, flags.options())
+		if err == nil {
+			var versions []string
+			versions, err = bump.FindRequiredVersions(file, flags.options())
+			requiredVersions = append(requiredVersions, versions...)
+		}
+		if err != nil {
+			log.Printf("Error processing %s: %v", file, err)
+			fileErrors++
+			continue
+		}
+		pins = append(pins, filePins...)
+	}
+	// A config missing the versions of unreadable files would not be a faithful baseline
+	if fileErrors > 0 {
+		return fmt.Errorf("%d file(s) could not be read; no config was written", fileErrors)
+	}
+
+	config := bump.StarterConfig(pins, requiredVersions)
+	if config.TerraformVersion == "" && len(config.Providers) == 0 && len(config.Modules) == 0 {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error: No module, provider, or required_version versions found in %d file(s)", len(files))
+	}
+	data, err := marshalStarterConfig(config)
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error writing starter config: %w", err)
+	}
+	if flags.exportConfig == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := writeNewFile(flags.exportConfig, data); err != nil {
+		return err
+	}
+
+	terraform := ""
+	if config.TerraformVersion != "" {
+		terraform = ", required_version " + quote(config.TerraformVersion, flags.output)
+	}
+	fmt.Printf("✓ Wrote starter config %s: %d module source(s), %d provider(s)%s\n",
+		flags.exportConfig, len(config.Modules), len(config.Providers), terraform)
+	return nil
+}
+
+// marshalStarterConfig returns the YAML of a starter config, with its header comment.
+func marshalStarterConfig(config bump.Config) ([]byte, error) {
+	starter := starterConfig{TerraformVersion: config.TerraformVersion}
+	for _, provider := range config.Providers {
+		starter.Providers = append(starter.Providers, starterProvider{Name: provider.Name, Version: provider.Version})
+	}
+	for _, module := range config.Modules {
+		starter.Modules = append(starter.Modules, starterModule{Source: module.Source, Version: module.Version})
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(starterConfigHeader)
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(starter); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeNewFile writes data to a file that must not already exist.
+func writeNewFile(filename string, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error: %s already exists; remove it or choose another file", filename)
+	}
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error writing starter config: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error writing starter config: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error writing starter config: %w", err)
+	}
+	return nil
+}
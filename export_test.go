@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+func TestCommandExportsStarterConfig(t *testing.T) {
+	dir := t.TempDir()
+	module := "module \"%s\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"%s\"\n}\n"
+	writeTestFile(t, dir, "a.tf", fmt.Sprintf(module, "a", "4.0.0"))
+	writeTestFile(t, dir, "b.tf", fmt.Sprintf(module, "b", "5.0.0"))
+	writeTestFile(t, dir, "c.tf", fmt.Sprintf(module, "c", "5.0.0"))
+	writeTestFile(t, dir, "versions.tf", "terraform {\n  required_version = \">= 1.5\"\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n  }\n}\n")
+	patterns := compileConstraintRegexps(t, loadConfigSchema(t).Definitions.VersionConstraint)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-export-config", "versions.yml"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if !strings.HasSuffix(result.stdout, "✓ Wrote starter config versions.yml: 1 module source(s), 1 provider(s), required_version '>= 1.5'\n") {
+		t.Errorf("stdout = %q", result.stdout)
+	}
+	want := starterConfigHeader + `terraform_version: '>= 1.5'
+providers:
+  - name: aws
+    version: ~> 5.0
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: 5.0.0
+`
+	if got := readTestFile(t, "versions.yml"); got != want {
+		t.Errorf("versions.yml mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+
+	// The starter config is accepted by config mode and by the schema's version pattern
+	config, err := bump.LoadConfig("versions.yml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	for _, version := range []string{config.TerraformVersion, config.Providers[0].Version, config.Modules[0].Version} {
+		if !patterns[0].MatchString(version) {
+			t.Errorf("version %q does not match the schema pattern", version)
+		}
+	}
+
+	// An existing file is never overwritten
+	result = runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-export-config", "versions.yml"})
+	if result.exitCode != 1 || !strings.Contains(result.diagnostics, "Error: versions.yml already exists") {
+		t.Errorf("result = %#v, want the existing file refused", result)
+	}
+}
+
+func TestExportConfigFlagValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, args := range [][]string{
+		{"-pattern", "*.tf", "-export-config", filepath.Join("out", "versions.yml"), "-module", "example/module"},
+		{"-pattern", "*.tf", "-export-config", "versions.yml", "-dry-run"},
+	} {
+		result := runMainCommand(t, append([]string{"tf-version-bump"}, args...))
+		if result.exitCode != 1 || !strings.Contains(result.diagnostics, "Error: Cannot use -export-config with") {
+			t.Errorf("result = %#v, want a flag error", result)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+// Config groups name sets of module and provider entries. -group applies one group; without it, a
+// config with groups applies the entries outside every group and then each group in turn,
+// reporting each group separately.
+
+import (
+	"fmt"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// groupResult is the outcome of one config group, as listed in the update report.
+type groupResult struct {
+	Name                  string `json:"name"`
+	Changes               int    `json:"changes"`
+	ModuleBlocksUpdated   int    `json:"module_blocks_updated"`
+	ProviderBlocksUpdated int    `json:"provider_blocks_updated"`
+}
+
+// configRun is one application of a config file: a group, or the entries outside every group.
+type configRun struct {
+	group  string // Empty for the entries outside every group
+	config bump.Config
+}
+
+// configRuns splits a config into the runs to apply, in order: the -group group alone, the whole
+// config when it has no groups, or the entries outside every group followed by each group.
+func (flags *cliFlags) configRuns(config *bump.Config) ([]configRun, error) {
+	if flags.group != "" {
+		group, err := config.Group(flags.group)
+		if err != nil {
+			//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+			return nil, fmt.Errorf("Error: Unknown group %s in config file %s", quote(flags.group, flags.output), flags.configFile)
+		}
+		return []configRun{{group: flags.group, config: group}}, nil
+	}
+	if len(config.Groups) == 0 {
+		return []configRun{{config: *config}}, nil
+	}
+
+	var runs []configRun
+	if ungrouped := config.Ungrouped(); hasUpdates(ungrouped) {
+		runs = append(runs, configRun{config: ungrouped})
+	}
+	for _, group := range config.Groups {
+		selected, err := config.Group(group.Name)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, configRun{group: group.Name, config: selected})
+	}
+	return runs, nil
+}
+
+// hasUpdates reports whether a config has any entry to apply.
+func hasUpdates(config bump.Config) bool {
+	return config.TerraformVersion != "" || len(config.Providers) > 0 || len(config.Modules) > 0 ||
+		len(config.Migrations) > 0 || len(config.ProviderMigrations) > 0 || len(config.Custom) > 0
+}
+
+// applyConfigRun applies one run of a config file and records a group's results in the update
+// report. With sections, the run is headed in the output and has its own section of the Markdown
+// report; a lone -group names the Markdown report instead.
+//
+// Returns:
+//   - changes: Number of changes of each kind
+//   - errors: Number of files of each kind of update that could not be processed
+//   - error: An invalid config
+func (flags *cliFlags) applyConfigRun(files []string, run configRun, sections bool) (changes, errors map[bump.ChangeKind]int, err error) {
+	switch {
+	case sections && run.group == "":
+		fmt.Println("\nUngrouped updates")
+		flags.markdown.startGroup("")
+	case sections:
+		fmt.Printf("\nGroup %s\n", quote(run.group, flags.output))
+		flags.markdown.startGroup(run.group)
+	default:
+		flags.markdown.group = run.group
+	}
+	// Branch mode reports each branch instead
+	if run.group == "" || flags.branches != "" {
+		return flags.apply(files, run.config)
+	}
+
+	flags.groupReport = &updateReport{}
+	defer func() { flags.groupReport = nil }()
+	changeCount := flags.changeCount
+	changes, errors, err = flags.apply(files, run.config)
+	flags.report.Groups = append(flags.report.Groups, groupResult{
+		Name:                  run.group,
+		Changes:               flags.changeCount - changeCount,
+		ModuleBlocksUpdated:   flags.groupReport.ModuleBlocksUpdated,
+		ProviderBlocksUpdated: flags.groupReport.ProviderBlocksUpdated,
+	})
+	return changes, errors, err
+}
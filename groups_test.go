@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const groupTestConfig = `terraform_version: ">= 1.9"
+providers:
+  - name: aws
+    version: "~> 6.0"
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: "5.0.0"
+  - source: terraform-aws-modules/eks/aws
+    version: "20.0.0"
+groups:
+  - name: aws-core
+    modules: [terraform-aws-modules/vpc/aws]
+    providers: [aws]
+  - name: eks
+    modules: [terraform-aws-modules/eks/aws]
+`
+
+const groupTestInput = `terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "eks" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = "19.0.0"
+}
+`
+
+func TestCommandAppliesEveryGroupSeparately(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", groupTestInput)
+	writeTestFile(t, dir, "config.yml", groupTestConfig)
+	reports := t.TempDir()
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-config", "config.yml",
+		"-report-file", filepath.Join(reports, "report.json"), "-markdown-report", filepath.Join(reports, "report.md")})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := strings.NewReplacer(`">= 1.5"`, `">= 1.9"`, `"~> 5.0"`, `"~> 6.0"`, `"4.0.0"`, `"5.0.0"`, `"19.0.0"`, `"20.0.0"`).Replace(groupTestInput)
+	if got := readTestFile(t, "main.tf"); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	ungrouped := strings.Index(result.stdout, "\nUngrouped updates\n")
+	core := strings.Index(result.stdout, "\nGroup 'aws-core'\n")
+	eks := strings.Index(result.stdout, "\nGroup 'eks'\n")
+	if ungrouped < 0 || core < ungrouped || eks < core {
+		t.Errorf("stdout = %q, want a heading per group in order", result.stdout)
+	}
+
+	var report updateReport
+	if err := json.Unmarshal([]byte(readTestFile(t, filepath.Join(reports, "report.json"))), &report); err != nil {
+		t.Fatal(err)
+	}
+	wantGroups := []groupResult{
+		{Name: "aws-core", Changes: 2, ModuleBlocksUpdated: 1, ProviderBlocksUpdated: 1},
+		{Name: "eks", Changes: 1, ModuleBlocksUpdated: 1},
+	}
+	if !reflect.DeepEqual(report.Groups, wantGroups) || report.ModuleBlocksUpdated != 2 || report.ProviderBlocksUpdated != 1 {
+		t.Errorf("report = %#v, want groups %#v", report, wantGroups)
+	}
+
+	markdown := readTestFile(t, filepath.Join(reports, "report.md"))
+	for _, section := range []string{
+		"## Terraform version updates\n\n4 update(s) across 1 file(s).\n",
+		"\n### Ungrouped updates\n\n1 update(s) across 1 file(s).\n",
+		"\n### Group `aws-core`\n\n2 update(s) across 1 file(s).\n\n| Update | From | To | Blocks | Files |\n",
+		"| Provider `aws` | `~> 5.0` | `~> 6.0` | 1 | 1 |\n| Module `terraform-aws-modules/vpc/aws` | `4.0.0` | `5.0.0` | 1 | 1 |\n\n#### Changes by file\n",
+		"\n### Group `eks`\n\n1 update(s) across 1 file(s).\n",
+	} {
+		if !strings.Contains(markdown, section) {
+			t.Errorf("Markdown report missing %q:\n%s", section, markdown)
+		}
+	}
+}
+
+func TestCommandAppliesOneGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", groupTestInput)
+	writeTestFile(t, dir, "config.yml", groupTestConfig)
+	reports := t.TempDir()
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-config", "config.yml", "-group", "eks",
+		"-report-file", filepath.Join(reports, "report.json"), "-markdown-report", filepath.Join(reports, "report.md")})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := strings.Replace(groupTestInput, `"19.0.0"`, `"20.0.0"`, 1)
+	if got := readTestFile(t, "main.tf"); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	if strings.Contains(result.stdout, "Group 'eks'") {
+		t.Errorf("stdout = %q, want no group headings for a single group", result.stdout)
+	}
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 1,\n  \"provider_blocks_updated\": 0,\n" +
+		"  \"groups\": [\n    {\n      \"name\": \"eks\",\n      \"changes\": 1,\n      \"module_blocks_updated\": 1,\n      \"provider_blocks_updated\": 0\n    }\n  ],\n" + directoryReport(".", 1, 1)
+	if got := readTestFile(t, filepath.Join(reports, "report.json")); got != wantReport {
+		t.Errorf("report = %s, want %s", got, wantReport)
+	}
+	if got := readTestFile(t, filepath.Join(reports, "report.md")); !strings.HasPrefix(got, "## Terraform version updates: group `eks`\n\n1 update(s) across 1 file(s).\n") {
+		t.Errorf("Markdown report = %s", got)
+	}
+}
+
+func TestGroupFlagValidation(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", groupTestInput)
+	config := writeTestFile(t, dir, "config.yml", groupTestConfig)
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "without a config",
+			args: []string{"-module", "example/module", "-to", "1.0.0", "-group", "eks"},
+			want: "Error: -group requires -config",
+		},
+		{
+			name: "unknown group",
+			args: []string{"-config", config, "-group", "gcp"},
+			want: "Error: Unknown group 'gcp' in config file " + config,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runMainCommand(t, append([]string{"tf-version-bump", "-pattern", dir + "/*.tf"}, tt.args...))
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// confirmer asks on the terminal whether each proposed change may be made, for -interactive.
+type confirmer struct {
+	input        *bufio.Reader
+	outputFormat string
+	acceptAll    map[string]bool // Sources whose changes were all accepted
+	quit         bool
+}
+
+// validateInteractiveFlags validates the flags of interactive mode.
+func validateInteractiveFlags(flags *cliFlags) {
+	if !flags.interactive {
+		return
+	}
+	if flags.dryRun {
+		fatalf("Error: Cannot use -interactive with -dry-run")
+	}
+	if flags.lint || flags.policyFile != "" || flags.advisoryFile != "" || flags.branches != "" {
+		fatalf("Error: Cannot use -interactive with -lint, -policy, -advisories, or -branches")
+	}
+}
+
+// newConfirmer returns a confirmer that reads answers from input.
+func newConfirmer(input io.Reader, outputFormat string) *confirmer {
+	return &confirmer{input: bufio.NewReader(input), outputFormat: outputFormat, acceptAll: make(map[string]bool)}
+}
+
+// confirm shows a proposed change as a small diff and asks whether to accept it, skip it, accept
+// every change to the same source, or quit. Quitting, or reaching the end of the input, skips the
+// change and all that follow.
+func (c *confirmer) confirm(proposal bump.Proposal) bool {
+	source := fmt.Sprintf("%d\x00%s", proposal.Kind, proposal.Name)
+	if c.quit {
+		return false
+	}
+	if c.acceptAll[source] {
+		return true
+	}
+
+	fmt.Printf("\n%s\n%s", c.describe(proposal), proposalDiff(proposal))
+	for {
+		fmt.Print("Apply this change? [y]es, [n]o, [a]ll for this source, [q]uit: ")
+		line, err := c.input.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "a", "all":
+			c.acceptAll[source] = true
+			return true
+		case "q", "quit":
+			c.quit = true
+			fmt.Println("Quitting: the remaining changes are skipped")
+			return false
+		}
+		if err != nil {
+			c.quit = true
+			fmt.Println("\nNo more input: the remaining changes are skipped")
+			return false
+		}
+		fmt.Println("Please answer y, n, a, or q.")
+	}
+}
+
+// describe names the block of a proposed change.
+func (c *confirmer) describe(proposal bump.Proposal) string {
+	switch proposal.Kind {
+	case bump.ModuleChange:
+		return fmt.Sprintf("Module %s in %s (source: %s)", quote(proposal.Block, c.outputFormat), proposal.File, quote(proposal.Name, c.outputFormat))
+	case bump.ProviderChange:
+		return fmt.Sprintf("Provider %s in %s", quote(proposal.Name, c.outputFormat), proposal.File)
+	default:
+		return "Terraform required_version in " + proposal.File
+	}
+}
+
+// proposalDiff returns the lines that a proposed change removes from and adds to the attribute it
+// edits.
+func proposalDiff(proposal bump.Proposal) string {
+	var diff strings.Builder
+	if proposal.Before != "" {
+		fmt.Fprintf(&diff, "  - %s = %q\n", proposal.Attribute, proposal.Before)
+	}
+	fmt.Fprintf(&diff, "  + %s = %q\n", proposal.Attribute, proposal.After)
+	return diff.String()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withInteractiveAnswers scripts the answers read by -interactive prompts.
+func withInteractiveAnswers(t *testing.T, answers string) {
+	t.Helper()
+	original := interactiveInput
+	interactiveInput = strings.NewReader(answers)
+	t.Cleanup(func() { interactiveInput = original })
+}
+
+func TestCommandInteractiveWritesOnlyAcceptedChanges(t *testing.T) {
+	dir := t.TempDir()
+	module := func(name string) string {
+		return "module \"" + name + "\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	}
+	file := writeTestFile(t, dir, "main.tf", module("one")+"\n"+module("two")+"\n"+module("three"))
+	withInteractiveAnswers(t, "maybe\nn\ny\n")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0", "-interactive"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := module("one") + "\n" + strings.Replace(module("two"), "4.0.0", "5.0.0", 1) + "\n" + module("three")
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	for _, line := range []string{
+		"Module 'one' in " + file + " (source: 'terraform-aws-modules/vpc/aws')\n  - version = \"4.0.0\"\n  + version = \"5.0.0\"\n",
+		"Apply this change? [y]es, [n]o, [a]ll for this source, [q]uit: Please answer y, n, a, or q.\n",
+		"Module 'three' in " + file,
+		"No more input: the remaining changes are skipped",
+		"Successfully updated 1 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+}
+
+func TestCommandInteractiveShowsTerragruntSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "vpc")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	source := "git::https://github.com/example/modules.git//vpc"
+	file := writeTestFile(t, dir, "terragrunt.hcl", "terraform {\n  source = \""+source+"?ref=v1.2.3\"\n}\n")
+	withInteractiveAnswers(t, "y\n")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", file, "-terragrunt", "-module", source, "-to", "1.3.0", "-interactive"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	wantDiff := "Module 'vpc' in " + file + " (source: '" + source + "')\n" +
+		"  - source = \"" + source + "?ref=v1.2.3\"\n  + source = \"" + source + "?ref=v1.3.0\"\n"
+	if !strings.Contains(result.stdout, wantDiff) {
+		t.Errorf("stdout = %q, want %q", result.stdout, wantDiff)
+	}
+	if got := readTestFile(t, file); !strings.Contains(got, "?ref=v1.3.0") {
+		t.Errorf("terragrunt.hcl not updated:\n%s", got)
+	}
+}
+
+func TestCommandInteractiveAcceptAllAndQuit(t *testing.T) {
+	dir := t.TempDir()
+	provider := "terraform {\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n  }\n}\n"
+	module := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	first := writeTestFile(t, dir, "a.tf", provider+"\n"+module)
+	second := writeTestFile(t, dir, "b.tf", provider)
+	config := writeTestFile(t, dir, "config.yml", `providers:
+  - name: aws
+    version: "~> 6.0"
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: "5.0.0"
+`)
+	withInteractiveAnswers(t, "a\nq\n")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-config", config, "-interactive"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if got := strings.Count(result.stdout, "Apply this change?"); got != 2 {
+		t.Errorf("prompted %d times, want once for the provider and once for the module:\n%s", got, result.stdout)
+	}
+	for _, file := range []string{first, second} {
+		if got := readTestFile(t, file); !strings.Contains(got, `version = "~> 6.0"`) {
+			t.Errorf("%s provider not updated:\n%s", file, got)
+		}
+	}
+	if got := readTestFile(t, first); !strings.Contains(got, `version = "4.0.0"`) {
+		t.Errorf("module updated after quitting:\n%s", got)
+	}
+	if !strings.Contains(result.stdout, "Quitting: the remaining changes are skipped") {
+		t.Errorf("stdout = %q, want the quit message", result.stdout)
+	}
+}
+
+func TestInteractiveFlagValidation(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", "module \"vpc\" {\n  source  = \"example/vpc/aws\"\n  version = \"1.0.0\"\n}\n")
+	config := writeTestFile(t, dir, "config.yml", `migrations:
+  - from_source: example/vpc/aws
+    to_source: example/network/aws
+    version: "1.0.0"
+`)
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "dry run",
+			args: []string{"-module", "example/vpc/aws", "-to", "2.0.0", "-dry-run"},
+			want: "Error: Cannot use -interactive with -dry-run",
+		},
+		{
+			name: "lint",
+			args: []string{"-lint"},
+			want: "Error: Cannot use -interactive with -lint, -policy, -advisories, or -branches",
+		},
+		{
+			name: "config with migrations",
+			args: []string{"-config", config},
+			want: "Error: -interactive cannot confirm migrations or provider_migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"tf-version-bump", "-pattern", dir + "/*.tf", "-interactive"}, tt.args...)
+			result := runMainCommand(t, args)
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+// Backups let a run be undone where the working copy is not under version control. With -backup,
+// the original bytes, mode, and SHA-256 of each file are saved to a run directory before the file
+// is first written; -undo restores them, refusing any file that has changed since the run.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// backupRoot is the directory, relative to the working directory, that holds a run directory for
+// each backed-up run.
+var backupRoot = filepath.Join(".tf-version-bump", "runs")
+
+// backupManifestName is the name of the manifest within a run directory.
+const backupManifestName = "manifest.json"
+
+// backupManifest lists the files saved by one run.
+type backupManifest struct {
+	SchemaVersion int           `json:"schema_version"`
+	Created       time.Time     `json:"created"`
+	Files         []backupEntry `json:"files"`
+}
+
+// backupEntry records one file saved before a run first wrote it.
+type backupEntry struct {
+	Path   string      `json:"path"`   // Absolute path of the file
+	Backup string      `json:"backup"` // Copy of the original bytes, relative to the run directory
+	Mode   fs.FileMode `json:"mode"`
+	Before string      `json:"sha256_before"`
+	After  string      `json:"sha256_after,omitempty"` // Empty until the run finishes
+}
+
+// backupJournal saves each file a run writes. The run directory is created on the first write, so
+// a run that changes nothing leaves no backup.
+type backupJournal struct {
+	dir      string
+	manifest backupManifest
+	saved    map[string]bool
+}
+
+// newBackupJournal returns an empty journal for -backup.
+func newBackupJournal() *backupJournal {
+	return &backupJournal{saved: make(map[string]bool)}
+}
+
+// save copies a file's original bytes into the run directory before it is first written. It is
+// the engine's BeforeWrite hook; an error leaves the file unwritten.
+func (j *backupJournal) save(filename string) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filename, err)
+	}
+	if j.saved[path] {
+		return nil
+	}
+	if err := j.create(); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filename, err)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filename, err)
+	}
+	entry := backupEntry{
+		Path:   path,
+		Backup: filepath.Join("files", strconv.Itoa(len(j.manifest.Files))),
+		Mode:   info.Mode().Perm(),
+		Before: fileHash(src),
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, entry.Backup), src, 0o600); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filename, err)
+	}
+	j.manifest.Files = append(j.manifest.Files, entry)
+	j.saved[path] = true
+	return j.writeManifest()
+}
+
+// create makes the run directory on the first write of a run. The run id is the UTC start time
+// followed by a random suffix.
+func (j *backupJournal) create() error {
+	if j.dir != "" {
+		return nil
+	}
+	if err := os.MkdirAll(backupRoot, 0o755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	now := time.Now().UTC()
+	dir, err := os.MkdirTemp(backupRoot, now.Format("20060102T150405Z")+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "files"), 0o700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	j.dir = dir
+	j.manifest = backupManifest{SchemaVersion: 1, Created: now.Truncate(time.Second)}
+	return nil
+}
+
+// finish records the hash each saved file was left with, which -undo requires before restoring
+// it, and prints how to undo the run. It does nothing when no file was saved.
+func (j *backupJournal) finish() error {
+	if j == nil || j.dir == "" {
+		return nil
+	}
+	for index := range j.manifest.Files {
+		entry := &j.manifest.Files[index]
+		src, err := os.ReadFile(entry.Path)
+		if err != nil {
+			return fmt.Errorf("failed to record backup of %s: %w", entry.Path, err)
+		}
+		entry.After = fileHash(src)
+	}
+	if err := j.writeManifest(); err != nil {
+		return err
+	}
+	id := filepath.Base(j.dir)
+	fmt.Printf("Backup saved as run %s; restore it with -undo %s\n", id, id)
+	return nil
+}
+
+// writeManifest writes the manifest of the run directory.
+func (j *backupJournal) writeManifest() error {
+	data, err := json.MarshalIndent(j.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, backupManifestName), append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// validateBackupFlags validates the flags of backups and undo.
+func validateBackupFlags(flags *cliFlags) {
+	if flags.undo != "" && flags.flagsSet > 1 {
+		fatalf("Error: -undo cannot be used with other flags")
+	}
+	if !flags.backup {
+		return
+	}
+	if flags.dryRun {
+		fatalf("Error: Cannot use -backup with -dry-run")
+	}
+	if flags.branches != "" {
+		fatalf("Error: Cannot use -backup with -branches")
+	}
+}
+
+// runUndoCommand restores the files saved by a run and exits non-zero when any is refused.
+func runUndoCommand(id string) {
+	if err := undoRun(id); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// undoRun restores each file saved by a run that still has the contents the run left. A file that
+// already has its original contents is left alone; any other file has changed since the run and
+// is refused, while the remaining files are still restored.
+func undoRun(id string) error {
+	if id == "." || id == ".." || filepath.Base(id) != id {
+		return fmt.Errorf("Error: Invalid run id '%s'", id) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+	dir := filepath.Join(backupRoot, id)
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Error: No backup run '%s' in %s", id, backupRoot) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading backup run '%s': %w", id, err) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("Error reading backup run '%s': %w", id, err) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+
+	restored, refused := 0, 0
+	for _, entry := range manifest.Files {
+		done, err := restoreEntry(dir, id, entry)
+		if err != nil {
+			log.Printf("Refusing to restore %s: %v", entry.Path, err)
+			refused++
+			continue
+		}
+		if done {
+			fmt.Printf("✓ Restored %s\n", entry.Path)
+			restored++
+		} else {
+			fmt.Printf("  ⊗ %s already has its original contents\n", entry.Path)
+		}
+	}
+	fmt.Printf("\nRestored %d file(s) from run %s\n", restored, id)
+	if refused > 0 {
+		return fmt.Errorf("Error: %d file(s) could not be restored", refused) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+	return nil
+}
+
+// restoreEntry restores one saved file when it still has the contents the run left, reporting
+// whether it was written. The error explains a refusal.
+func restoreEntry(dir, id string, entry backupEntry) (bool, error) {
+	if entry.After == "" {
+		return false, fmt.Errorf("run %s did not finish", id)
+	}
+	current, err := os.ReadFile(entry.Path)
+	if err != nil {
+		return false, err
+	}
+	switch fileHash(current) {
+	case entry.Before:
+		return false, nil
+	case entry.After:
+	default:
+		return false, fmt.Errorf("it has changed since run %s", id)
+	}
+
+	original, err := os.ReadFile(filepath.Join(dir, entry.Backup))
+	if err != nil {
+		return false, err
+	}
+	if fileHash(original) != entry.Before {
+		return false, fmt.Errorf("its backup in run %s is damaged", id)
+	}
+	if err := os.WriteFile(entry.Path, original, entry.Mode); err != nil {
+		return false, err
+	}
+	return true, os.Chmod(entry.Path, entry.Mode)
+}
+
+// fileHash returns the hex SHA-256 of a file's contents.
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCommandBackupAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	input := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	first := writeTestFile(t, dir, "a.tf", input)
+	second := writeTestFile(t, dir, "b.tf", input)
+	writeTestFile(t, dir, "c.tf", "terraform {}\n")
+	if err := os.Chmod(first, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0", "-backup"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	match := regexp.MustCompile(`Backup saved as run (\S+); restore it with -undo (\S+)\n`).FindStringSubmatch(result.stdout)
+	if match == nil || match[1] != match[2] {
+		t.Fatalf("stdout = %q, want the backup run id", result.stdout)
+	}
+	id := match[1]
+	backups, err := filepath.Glob(filepath.Join(".tf-version-bump", "runs", id, "files", "*"))
+	if err != nil || len(backups) != 2 {
+		t.Errorf("backups = %q, %v, want one per written file", backups, err)
+	}
+
+	// A file edited since the run is refused; the others are restored with their modes
+	changed := strings.Replace(readTestFile(t, second), "5.0.0", "5.1.0", 1)
+	writeTestFile(t, dir, "b.tf", changed)
+	result = runMainCommand(t, []string{"tf-version-bump", "-undo", id})
+	if result.exitCode != 1 || !strings.Contains(result.diagnostics, "Refusing to restore "+second+": it has changed since run "+id) ||
+		!strings.Contains(result.diagnostics, "Error: 1 file(s) could not be restored") {
+		t.Fatalf("result = %#v, want b.tf refused", result)
+	}
+	if !strings.Contains(result.stdout, "✓ Restored "+first) || !strings.Contains(result.stdout, "Restored 1 file(s) from run "+id) {
+		t.Errorf("stdout = %q, want a.tf restored", result.stdout)
+	}
+	if got := readTestFile(t, first); got != input {
+		t.Errorf("a.tf not restored:\n%s", got)
+	}
+	if info, err := os.Stat(first); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("a.tf mode = %v, %v, want 0640", info.Mode().Perm(), err)
+	}
+	if got := readTestFile(t, second); got != changed {
+		t.Errorf("b.tf overwritten despite the refusal:\n%s", got)
+	}
+
+	// Undoing again, through the undo subcommand, leaves restored files alone
+	writeTestFile(t, dir, "b.tf", input)
+	result = runMainCommand(t, []string{"tf-version-bump", "undo", id})
+	if result.exitCode != -1 || result.diagnostics != "" || strings.Count(result.stdout, "already has its original contents") != 2 {
+		t.Errorf("result = %#v, want both files already restored", result)
+	}
+}
+
+func TestCommandBackupSkippedWhenNothingChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"5.0.0\"\n}\n")
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-module", "example/other", "-to", "5.0.0", "-backup"})
+	if result.exitCode != -1 || strings.Contains(result.stdout, "Backup saved") {
+		t.Errorf("result = %#v, want no backup", result)
+	}
+	if _, err := os.Stat(".tf-version-bump"); !os.IsNotExist(err) {
+		t.Errorf("backup directory created for an unchanged run: %v", err)
+	}
+}
+
+func TestBackupFlagValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "undo with other flags",
+			args: []string{"-undo", "run", "-pattern", "*.tf"},
+			want: "Error: -undo cannot be used with other flags",
+		},
+		{
+			name: "undo with a path",
+			args: []string{"-undo", "../run"},
+			want: "Error: Invalid run id '../run'",
+		},
+		{
+			name: "undo unknown run",
+			args: []string{"-undo", "missing"},
+			want: "Error: No backup run 'missing' in " + filepath.Join(".tf-version-bump", "runs"),
+		},
+		{
+			name: "backup in a dry run",
+			args: []string{"-pattern", "*.tf", "-module", "example/module", "-to", "1.0.0", "-backup", "-dry-run"},
+			want: "Error: Cannot use -backup with -dry-run",
+		},
+		{
+			name: "backup in branch mode",
+			args: []string{"-pattern", "*.tf", "-module", "example/module", "-to", "1.0.0", "-backup", "-branches", "release/*"},
+			want: "Error: Cannot use -backup with -branches",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runMainCommand(t, append([]string{"tf-version-bump"}, tt.args...))
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// validateLintFlags validates the flags of lint mode, which reads files without updating them.
+func validateLintFlags(flags *cliFlags) {
+	if flags.configFile != "" || flags.hasOperationFlags() {
+		fatalf("Error: Cannot use -lint with -config or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)")
+	}
+	if flags.reportFile != "" {
+		fatalf("Error: Cannot use -report-file with -lint")
+	}
+	if _, err := bump.LintSeverities(flags.lintRules); err != nil {
+		fatalf("Error: Invalid -lint-rule: %v", err)
+	}
+}
+
+// runLintMode lints every file and prints the findings followed by a summary.
+//
+// Parameters:
+//   - files: The files to lint
+//   - flags: The command-line flags, including the -lint-rule settings
+//
+// Returns:
+//   - error: Non-nil when a file cannot be read or parsed, or any finding has error severity
+func runLintMode(files []string, flags *cliFlags) error {
+	severities, err := bump.LintSeverities(flags.lintRules)
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error: Invalid -lint-rule: %w", err)
+	}
+
+	var errorCount, warningCount, fileErrors int
+	for _, file := range files {
+		findings, err := bump.LintFile(file, severities, flags.options())
+		if err != nil {
+			log.Printf("Error processing %s: %v", file, err)
+			fileErrors++
+			continue
+		}
+		for _, finding := range findings {
+			fmt.Printf("%s:%d:%d: %s [%s] %s\n", file, finding.Pos.Line, finding.Pos.Column, finding.Severity, finding.Rule, finding.Message)
+			if finding.Severity == bump.LintError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+	}
+
+	if errorCount == 0 && warningCount == 0 {
+		fmt.Printf("\n✓ No lint findings in %d file(s)\n", len(files)-fileErrors)
+	} else {
+		fmt.Printf("\nLint: %d error(s), %d warning(s) in %d file(s)\n", errorCount, warningCount, len(files)-fileErrors)
+	}
+	if fileErrors > 0 {
+		return fmt.Errorf("%d file(s) could not be linted", fileErrors)
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%d lint error(s)", errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandLintExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "main.tf", "module \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}\n")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-lint"})
+	if result.exitCode != 1 || result.diagnostics != "1 lint error(s)\n" {
+		t.Fatalf("result = %#v, want exit 1", result)
+	}
+	for _, line := range []string{
+		file + ":1:1: error [module-missing-version] Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') has no version",
+		"Lint: 1 error(s), 0 warning(s) in 1 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-lint", "-lint-rule", "module-missing-version=off"})
+	if result.exitCode != -1 || !strings.Contains(result.stdout, "✓ No lint findings in 1 file(s)") {
+		t.Fatalf("result = %#v, want success without findings", result)
+	}
+}
@@ -1,5 +1,6 @@
 // Package main provides a CLI tool for updating Terraform module versions, Terraform versions,
-// and provider versions across multiple files.
+// and provider versions across multiple files. The updates themselves are made by the bump
+// package; this package parses flags, selects files, and prints what the engine reports.
 //
 // The tool supports four modes of operation:
 //  1. Single Module Mode: Update one module at a time via command-line flags
@@ -7,14 +8,17 @@
 //  3. Terraform Version Mode: Update Terraform required_version in terraform blocks
 //  4. Provider Version Mode: Update provider versions in terraform required_providers blocks
 //
-// It uses the official HashiCorp HCL library to parse and modify Terraform files while retaining
-// comments and HCL structure. Changed files are normalised by hclwrite.Format.
+// These run under the bump subcommand, which is also the default when no subcommand is given. The
+// list, check, lint, init, and validate-config subcommands read files without taking an operation.
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
@@ -24,11 +28,7 @@ import (
 	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
-	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclsyntax"
-	"github.com/hashicorp/hcl/v2/hclwrite"
-	tfaddr "github.com/hashicorp/terraform-registry-address"
-	"github.com/zclconf/go-cty/cty"
+	"github.com/yesdevnull/tf-version-bump/bump"
 )
 
 type visibleDirFS struct {
@@ -59,9 +59,10 @@ var (
 )
 
 var (
-	hookMu   sync.Mutex // guards test hook variables
-	exitFunc = os.Exit
-	fatalf   = func(format string, v ...interface{}) {
+	hookMu           sync.Mutex // guards test hook variables
+	exitFunc                    = os.Exit
+	interactiveInput io.Reader  = os.Stdin // answers to -interactive prompts
+	fatalf                      = func(format string, v ...interface{}) {
 		log.Printf(format, v...)
 		exitFunc(1)
 	}
@@ -110,45 +111,115 @@ type cliFlags struct {
 	ignoreVersions   stringSliceFlag
 	ignoreModules    string
 	configFile       string
+	group            string
 	forceAdd         bool
+	preserveOperator bool
+	allowDowngrade   bool
+	prerelease       string
+	terragrunt       bool
+	opentofu         bool
+	followReferences bool
+	lint             bool
+	lintRules        stringSliceFlag
+	policyFile       string
+	advisoryFile     string
+	fix              bool
+	align            bool
+	alignScope       string
+	exportConfig     string
+	list             bool
+	validateConfigs  []string
 	dryRun           bool
+	interactive      bool
+	backup           bool
+	undo             string
+	flagsSet         int // Number of flags set on the command line
 	verbose          bool
 	showVersion      bool
 	output           string
 	terraformVersion string
 	providerName     string
+	providerSource   string
+	addMissing       bool
 	reportFile       string
+	markdownReport   string
+	branches         string
+	commitMessage    string
 	report           updateReport
+	markdown         markdownReport
+	journal          *backupJournal
+	confirmer        *confirmer
+	groupReport      *updateReport // Block counts of the config group being applied
+	directories      []directoryResult
+	changeCount      int
 }
 
 type updateReport struct {
-	SchemaVersion         int `json:"schema_version"`
-	ModuleBlocksUpdated   int `json:"module_blocks_updated"`
-	ProviderBlocksUpdated int `json:"provider_blocks_updated"`
+	SchemaVersion         int                       `json:"schema_version"`
+	ModuleBlocksUpdated   int                       `json:"module_blocks_updated"`
+	ProviderBlocksUpdated int                       `json:"provider_blocks_updated"`
+	ProviderMigrations    []providerMigrationRecord `json:"provider_migrations,omitempty"`
+	Branches              []branchResult            `json:"branches,omitempty"`
+	Groups                []groupResult             `json:"groups,omitempty"`
+	Directories           []directoryResult         `json:"directories,omitempty"`
+	ProviderConflicts     []providerConflictRecord  `json:"provider_conflicts,omitempty"`
 	moduleBlockIDs        map[string]struct{}
 	providerBlockIDs      map[string]struct{}
 	fileIdentities        []fs.FileInfo
 }
 
+// providerMigrationRecord lists a migrated entry in the update report.
+type providerMigrationRecord struct {
+	File       string `json:"file"`
+	Name       string `json:"name"`
+	FromSource string `json:"from_source"`
+	ToSource   string `json:"to_source"`
+	Version    string `json:"version"`
+}
+
 type preparedReportFile struct {
 	destination string
 	file        *os.File
 }
 
-func (flags *cliFlags) reportRecorder() *updateReport {
-	if flags.reportFile == "" {
-		return nil
+// recordChange counts the blocks of a change in the update report, when one is written.
+func (flags *cliFlags) recordChange(change bump.Change) {
+	if flags.reportFile == "" || flags.dryRun || len(change.Blocks) == 0 {
+		return
+	}
+	report := &flags.report
+	switch change.Kind {
+	case bump.ModuleChange, bump.ModuleMigrationChange:
+		report.recordModuleBlocks(change.File, change.Blocks)
+	case bump.ProviderMigrationChange:
+		report.ProviderMigrations = append(report.ProviderMigrations, providerMigrationRecord{
+			File:       change.File,
+			Name:       change.Name,
+			FromSource: change.FromSource,
+			ToSource:   change.ToSource,
+			Version:    change.Version,
+		})
+		report.recordProviderBlocks(change.File, change.Blocks)
+	case bump.ProviderChange:
+		report.recordProviderBlocks(change.File, change.Blocks)
+	}
+	if group := flags.groupReport; group != nil {
+		switch change.Kind {
+		case bump.ModuleChange:
+			group.recordModuleBlocks(change.File, change.Blocks)
+		case bump.ProviderChange:
+			group.recordProviderBlocks(change.File, change.Blocks)
+		}
 	}
-	return &flags.report
 }
 
-func (report *updateReport) recordModuleBlocks(filename string, blockIndexes []int) {
+func (report *updateReport) recordModuleBlocks(filename string, blocks []string) {
 	fileID := report.fileIdentity(filename)
 	if report.moduleBlockIDs == nil {
 		report.moduleBlockIDs = make(map[string]struct{})
 	}
-	for _, blockIndex := range blockIndexes {
-		blockID := fmt.Sprintf("%s\x00%d", fileID, blockIndex)
+	for _, block := range blocks {
+		blockID := fileID + "\x00" + block
 		if _, recorded := report.moduleBlockIDs[blockID]; recorded {
 			continue
 		}
@@ -198,37 +269,75 @@ func canonicalFileIdentity(filename string) string {
 	return filepath.Clean(filename)
 }
 
-// parseFlags parses and validates command-line flags
+// parseFlags parses and validates command-line flags. A first argument naming a subcommand selects
+// that subcommand's flags; otherwise every flag is accepted, as for the bump subcommand.
 func parseFlags() *cliFlags {
 	flags := &cliFlags{}
-
-	flag.StringVar(&flags.pattern, "pattern", "", "Glob pattern for Terraform files; '**' matches any depth (e.g., '*.tf' or 'modules/**/*.tf')")
-	flag.StringVar(&flags.moduleSource, "module", "", "Source of the module to update (e.g., 'terraform-aws-modules/vpc/aws')")
-	flag.StringVar(&flags.toVersion, "to", "", "Desired version number")
-	flag.Var(&flags.fromVersions, "from", "Optional: version to update from (can be specified multiple times, e.g., -from 3.0.0 -from '~> 3.0')")
-	flag.Var(&flags.ignoreVersions, "ignore-version", "Optional: version(s) to skip (can be specified multiple times, e.g., -ignore-version 3.0.0 -ignore-version '~> 3.0')")
-	flag.StringVar(&flags.ignoreModules, "ignore-modules", "", "Optional: comma-separated list of module names or patterns to ignore (e.g., 'vpc,legacy-*')")
-	flag.StringVar(&flags.configFile, "config", "", "Path to YAML config file with multiple module updates")
-	flag.BoolVar(&flags.forceAdd, "force-add", false, "Add a missing version attribute to registry modules (default: skip with warning)")
-	flag.BoolVar(&flags.dryRun, "dry-run", false, "Show what changes would be made without actually modifying files")
-	flag.BoolVar(&flags.verbose, "verbose", false, "Show verbose output including skipped modules")
-	flag.BoolVar(&flags.showVersion, "version", false, "Print version information and exit")
-	flag.StringVar(&flags.output, "output", "text", "Output format: 'text' (default) or 'md' (Markdown)")
-	flag.StringVar(&flags.terraformVersion, "terraform-version", "", "Update Terraform required_version in terraform blocks")
-	flag.StringVar(&flags.providerName, "provider", "", "Provider name to update (e.g., 'aws', 'azurerm')")
-	flag.StringVar(&flags.reportFile, "report-file", "", "Write exact updated module and provider block counts as JSON")
-	flag.Parse()
+	if command := findSubcommand(os.Args[1:]); command != nil {
+		flags.parseSubcommand(command, os.Args[2:])
+	} else {
+		flags.define(flag.CommandLine)
+		flag.CommandLine.Usage = printCommandUsage
+		flag.Parse()
+		flags.flagsSet = flag.NFlag()
+	}
 
 	// Validate output format
 	if flags.output != "text" && flags.output != "md" {
 		fatalf("Error: Invalid output format '%s'. Must be 'text' or 'md'", flags.output)
 	}
 
+	if !bump.ValidPrereleasePolicy(flags.prerelease) {
+		fatalf("Error: Invalid pre-release policy '%s'. Must be 'allow', 'if-current', or 'exclude'", flags.prerelease)
+	}
+
 	return flags
 }
 
+// define registers every flag on a flag set.
+func (flags *cliFlags) define(set *flag.FlagSet) {
+	set.StringVar(&flags.pattern, "pattern", "", "Glob pattern for Terraform files; '**' matches any depth (e.g., '*.tf' or 'modules/**/*.tf')")
+	set.StringVar(&flags.moduleSource, "module", "", "Source of the module to update (e.g., 'terraform-aws-modules/vpc/aws')")
+	set.StringVar(&flags.toVersion, "to", "", "Desired version number")
+	set.Var(&flags.fromVersions, "from", "Optional: version to update from (can be specified multiple times, e.g., -from 3.0.0 -from '~> 3.0')")
+	set.Var(&flags.ignoreVersions, "ignore-version", "Optional: version(s) to skip (can be specified multiple times, e.g., -ignore-version 3.0.0 -ignore-version '~> 3.0')")
+	set.StringVar(&flags.ignoreModules, "ignore-modules", "", "Optional: comma-separated list of module names or patterns to ignore (e.g., 'vpc,legacy-*')")
+	set.StringVar(&flags.configFile, "config", "", "Path to YAML config file with multiple module updates")
+	set.StringVar(&flags.group, "group", "", "With -config, apply only the module and provider entries of this config group")
+	set.BoolVar(&flags.forceAdd, "force-add", false, "Add a missing version attribute to registry modules and provider entries (default: skip with warning)")
+	set.BoolVar(&flags.preserveOperator, "preserve-operator", false, "Keep the operator and precision of existing version constraints (e.g., '~> 4.2' becomes '~> 5.1')")
+	set.BoolVar(&flags.allowDowngrade, "allow-downgrade", false, "Apply updates that would move a version constraint below its current version (default: skip with warning)")
+	set.StringVar(&flags.prerelease, "prerelease", "", "Pre-release policy: 'allow' (default), 'if-current' (only where the current version is a pre-release), or 'exclude'")
+	set.BoolVar(&flags.terragrunt, "terragrunt", false, "Treat selected .hcl files as Terragrunt configuration (terraform.source refs and generate blocks)")
+	set.BoolVar(&flags.followReferences, "follow-references", false, "Update the literal local value or variable default behind a version written as local.<name> or var.<name>")
+	set.BoolVar(&flags.opentofu, "opentofu", false, "Apply OpenTofu rules: .tofu files override same-named .tf files and registry.opentofu.org addresses match their Terraform equivalents")
+	set.BoolVar(&flags.lint, "lint", false, "Report unpinned modules and providers without modifying files; exits non-zero on error findings")
+	set.Var(&flags.lintRules, "lint-rule", "Optional: set a lint rule's severity as <rule>=<error|warning|off> (can be specified multiple times, e.g., -lint-rule open-ended-constraint=error)")
+	set.StringVar(&flags.policyFile, "policy", "", "Path to YAML policy file of minimum and maximum versions; reports blocks that permit versions outside it")
+	set.StringVar(&flags.advisoryFile, "advisories", "", "Path to JSON advisory file of affected versions (OSV format); reports blocks that permit an affected version")
+	set.BoolVar(&flags.fix, "fix", false, "With -policy, raise constraints below a policy minimum to the minimum; with -advisories, update affected blocks to the first fixed version")
+	set.BoolVar(&flags.align, "align", false, "Move every module source and provider to the highest version already in use for it; -module and -provider limit the sources aligned")
+	set.StringVar(&flags.alignScope, "align-scope", "", "With -align, the files aligned together: 'repo' (default, every selected file) or 'directory' (each module directory)")
+	set.StringVar(&flags.exportConfig, "export-config", "", "Write a starter YAML config of the module, provider, and required_version versions in use to this file ('-' for standard output)")
+	set.BoolVar(&flags.dryRun, "dry-run", false, "Show what changes would be made without actually modifying files")
+	set.BoolVar(&flags.interactive, "interactive", false, "Ask before each module, provider, or required_version change: accept, skip, accept all for the source, or quit")
+	set.BoolVar(&flags.backup, "backup", false, "Save the original of each file before it is written, so the run can be restored with -undo")
+	set.StringVar(&flags.undo, "undo", "", "Restore the files saved by a -backup run, given its id; files changed since the run are refused")
+	set.BoolVar(&flags.verbose, "verbose", false, "Show verbose output including skipped modules")
+	set.BoolVar(&flags.showVersion, "version", false, "Print version information and exit")
+	set.StringVar(&flags.output, "output", "text", "Output format: 'text' (default) or 'md' (Markdown)")
+	set.StringVar(&flags.terraformVersion, "terraform-version", "", "Update Terraform required_version in terraform blocks")
+	set.StringVar(&flags.providerName, "provider", "", "Provider name to update (e.g., 'aws', 'azurerm')")
+	set.StringVar(&flags.providerSource, "provider-source", "", "Provider source address used by -add-missing (e.g., 'hashicorp/aws')")
+	set.BoolVar(&flags.addMissing, "add-missing", false, "Add the provider to required_providers blocks that do not declare it (requires -provider-source)")
+	set.StringVar(&flags.reportFile, "report-file", "", "Write exact updated module and provider block counts as JSON")
+	set.StringVar(&flags.markdownReport, "markdown-report", "", "Write a Markdown summary of the changes and skipped blocks, for pull request bodies")
+	set.StringVar(&flags.branches, "branches", "", "Apply the update to each local branch matching this glob (e.g., 'release/*') in a temporary worktree, committing the changes")
+	set.StringVar(&flags.commitMessage, "commit-message", "", "With -branches, the commit message template (e.g., 'chore: bump {{.Module}} to {{.Version}} on {{.Branch}}')")
+}
+
 // loadModuleUpdates loads module updates for single module CLI mode
-func loadModuleUpdates(flags *cliFlags) []ModuleUpdate {
+func loadModuleUpdates(flags *cliFlags) []bump.ModuleUpdate {
 	// Single module mode - validate required flags
 	if flags.pattern == "" || flags.moduleSource == "" || flags.toVersion == "" {
 		fmt.Println("Usage:")
@@ -238,51 +347,126 @@ func loadModuleUpdates(flags *cliFlags) []ModuleUpdate {
 		exitFunc(1)
 	}
 
-	// Parse ignore patterns from comma-separated list
-	var ignorePatterns []string
-	if flags.ignoreModules != "" {
-		for _, p := range strings.Split(flags.ignoreModules, ",") {
-			if trimmed := strings.TrimSpace(p); trimmed != "" {
-				ignorePatterns = append(ignorePatterns, trimmed)
-			}
+	return []bump.ModuleUpdate{
+		{Source: flags.moduleSource, Version: flags.toVersion, From: bump.FromVersions(flags.fromVersions), IgnoreVersions: bump.FromVersions(flags.ignoreVersions), IgnoreModules: ignorePatterns(flags.ignoreModules)},
+	}
+}
+
+// ignorePatterns parses the comma-separated -ignore-modules list.
+func ignorePatterns(list string) []string {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			patterns = append(patterns, trimmed)
 		}
 	}
+	return patterns
+}
 
-	return []ModuleUpdate{
-		{Source: flags.moduleSource, Version: flags.toVersion, From: FromVersions(flags.fromVersions), IgnoreVersions: FromVersions(flags.ignoreVersions), IgnoreModules: ignorePatterns},
+// options returns the behaviour flags as engine options.
+func (flags *cliFlags) options() bump.Options {
+	options := bump.Options{
+		DryRun:           flags.dryRun,
+		ForceAdd:         flags.forceAdd,
+		PreserveOperator: flags.preserveOperator,
+		AllowDowngrade:   flags.allowDowngrade,
+		Prerelease:       flags.prerelease,
+		Terragrunt:       flags.terragrunt,
+		OpenTofu:         flags.opentofu,
+		FollowReferences: flags.followReferences,
+		OutputFormat:     flags.output,
+	}
+	if flags.interactive {
+		// One confirmer serves every run, so "all" and "quit" answers carry across config groups
+		if flags.confirmer == nil {
+			flags.confirmer = newConfirmer(interactiveInput, flags.output)
+		}
+		options.Confirm = flags.confirmer.confirm
+	}
+	if flags.journal != nil {
+		options.BeforeWrite = flags.journal.save
 	}
+	return options
 }
 
-// processFiles processes all matching files and applies module updates.
-func processFiles(files []string, updates []ModuleUpdate, flags *cliFlags) (totalUpdates, totalErrors int) {
-	for _, file := range files {
-		for _, update := range updates {
-			updated, changedBlocks, err := updateModuleVersionWithCount(file, update.Source, update.Version, update.From, update.IgnoreVersions, update.IgnoreModules, flags.forceAdd, flags.dryRun, flags.verbose, flags.output)
-			if err != nil {
-				log.Printf("Error processing %s: %v", file, err)
-				totalErrors++
-				continue
-			}
-			if updated {
-				if report := flags.reportRecorder(); report != nil && !flags.dryRun && len(changedBlocks) > 0 {
-					report.recordModuleBlocks(file, changedBlocks)
-				}
-				prefix := "✓"
-				action := "Updated"
-				if flags.dryRun {
-					prefix = "→"
-					action = "Would update"
-				}
-				if len(update.From) > 0 {
-					fmt.Printf("%s %s module source %s from version(s) %v to %s in %s\n", prefix, action, quote(update.Source, flags.output), update.From, quote(update.Version, flags.output), file)
-				} else {
-					fmt.Printf("%s %s module source %s to version %s in %s\n", prefix, action, quote(update.Source, flags.output), quote(update.Version, flags.output), file)
-				}
-				totalUpdates++
-			}
+// engine returns an update engine with the behaviour flags as its options, which prints each event
+// as it happens.
+func (flags *cliFlags) engine() *bump.Engine {
+	return &bump.Engine{Options: flags.options(), OnEvent: flags.printEvent}
+}
+
+// printEvent prints an engine event: changes to stdout, skips as warnings to stderr, except that
+// filtered skips go to stdout with -verbose, and files that could not be processed to the log.
+// Changed blocks and module directories are recorded in the update report, and changes and skips
+// in the Markdown report.
+func (flags *cliFlags) printEvent(event bump.Event) {
+	if flags.markdownReport != "" {
+		flags.markdown.record(event)
+	}
+	switch event := event.(type) {
+	case bump.Change:
+		flags.changeCount++
+		flags.recordChange(event)
+		flags.recordDirectory(event)
+		fmt.Println(flags.describeChange(event))
+	case bump.Skip:
+		if !event.Filtered {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", event.Message)
+		} else if flags.verbose {
+			fmt.Printf("  ⊗ %s\n", event.Message)
+		}
+	case bump.Diagnostic:
+		log.Printf("Error processing %s: %v", event.File, event.Err)
+	}
+}
+
+// describeChange returns the line printed for a change.
+func (flags *cliFlags) describeChange(change bump.Change) string {
+	prefix, updated, migrated := "✓", "Updated", "Migrated"
+	if flags.dryRun {
+		prefix, updated, migrated = "→", "Would update", "Would migrate"
+	}
+	version := quote(change.Version, flags.output)
+	switch change.Kind {
+	case bump.TerraformVersionChange:
+		return fmt.Sprintf("%s %s Terraform required_version to %s in %s", prefix, updated, version, change.File)
+	case bump.ProviderMigrationChange:
+		return fmt.Sprintf("%s %s provider %s from %s to %s (version %s) in %s", prefix, migrated, quote(change.Name, flags.output),
+			quote(change.FromSource, flags.output), quote(change.ToSource, flags.output), version, change.File)
+	case bump.ProviderChange:
+		return fmt.Sprintf("%s %s provider %s to version %s in %s", prefix, updated, quote(change.Name, flags.output), version, change.File)
+	case bump.ModuleMigrationChange:
+		return fmt.Sprintf("%s %s module source %s to %s in %s", prefix, migrated, quote(change.FromSource, flags.output), quote(change.ToSource, flags.output), change.File)
+	case bump.ModuleChange:
+		if len(change.From) > 0 {
+			return fmt.Sprintf("%s %s module source %s from version(s) %v to %s in %s", prefix, updated, quote(change.Source, flags.output), change.From, version, change.File)
 		}
+		return fmt.Sprintf("%s %s module source %s to version %s in %s", prefix, updated, quote(change.Source, flags.output), version, change.File)
+	default:
+		return fmt.Sprintf("  %s %s to %s in %s", updated, change.Name, version, change.File)
+	}
+}
+
+// apply runs the updates of a config on the files with the update engine.
+//
+// Returns:
+//   - changes: Number of changes of each kind
+//   - errors: Number of files of each kind of update that could not be processed
+//   - error: An invalid config
+func (flags *cliFlags) apply(files []string, config bump.Config) (changes, errors map[bump.ChangeKind]int, err error) {
+	result, err := flags.engine().Apply(context.Background(), config, bump.Files(files))
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = make(map[bump.ChangeKind]int)
+	for _, change := range result.Changes {
+		changes[change.Kind]++
+	}
+	errors = make(map[bump.ChangeKind]int)
+	for _, diagnostic := range result.Diagnostics {
+		errors[diagnostic.Kind]++
 	}
-	return totalUpdates, totalErrors
+	return changes, errors, nil
 }
 
 // printSummary prints the final summary of updates
@@ -313,8 +497,22 @@ func main() {
 		exitFunc(0)
 	}
 
+	if len(flags.validateConfigs) > 0 {
+		runValidateConfigCommand(flags.validateConfigs)
+		return
+	}
+	validateBackupFlags(flags)
+	if flags.undo != "" {
+		runUndoCommand(flags.undo)
+		return
+	}
+
 	// Validate operation modes
 	validateOperationModes(flags)
+	if flags.branches != "" {
+		runBranchCommand(flags)
+		return
+	}
 
 	// Find and validate matching files
 	files := findMatchingFiles(flags)
@@ -327,16 +525,40 @@ func main() {
 	if err != nil {
 		fatalf("%v", err)
 	}
+	preparedMarkdown, err := prepareUpdateReport(flags.markdownReport, inputFiles)
+	if err != nil {
+		_ = preparedReport.discard()
+		fatalf("%v", err)
+	}
+	if flags.backup {
+		flags.journal = newBackupJournal()
+	}
 
 	// Run the appropriate operation mode
-	if flags.configFile != "" {
-		err = runConfigFileMode(files, flags)
-	} else {
-		err = runCLIMode(files, flags)
+	switch {
+	case flags.list:
+		err = runListMode(files, flags)
+	case flags.exportConfig != "":
+		err = runExportConfigMode(files, flags)
+	case flags.advisoryFile != "":
+		err = runAdvisoryMode(files, flags)
+	case flags.policyFile != "":
+		err = runPolicyMode(files, flags)
+	case flags.lint:
+		err = runLintMode(files, flags)
+	default:
+		err = runUpdateMode(files, flags)
+	}
+	if backupErr := flags.journal.finish(); backupErr != nil {
+		if err == nil {
+			err = fmt.Errorf("Error saving backup: %w", backupErr) //nolint:staticcheck // User-facing CLI diagnostic.
+		} else {
+			err = fmt.Errorf("%w; failed to save backup: %v", err, backupErr)
+		}
 	}
 	if err != nil {
-		if preparedReport != nil {
-			if discardErr := preparedReport.discard(); discardErr != nil {
+		for _, prepared := range []*preparedReportFile{preparedReport, preparedMarkdown} {
+			if discardErr := prepared.discard(); discardErr != nil {
 				err = fmt.Errorf("%w; failed to discard prepared report: %v", err, discardErr)
 			}
 		}
@@ -345,12 +567,21 @@ func main() {
 	if preparedReport != nil {
 		flags.report.SchemaVersion = 1
 		if publishErr := preparedReport.publish(flags.report); publishErr != nil {
+			_ = preparedMarkdown.discard()
 			fatalf("Error writing update report: %v", publishErr)
 		}
 	}
+	if preparedMarkdown != nil {
+		if publishErr := preparedMarkdown.write(flags.markdown.render(flags.dryRun, flags.output)); publishErr != nil {
+			fatalf("Error writing Markdown report: %v", publishErr)
+		}
+	}
 }
 
 func validateRequiredOperationFlags(flags *cliFlags) {
+	if flags.align {
+		return
+	}
 	if flags.moduleSource != "" {
 		_ = loadModuleUpdates(flags)
 	}
@@ -375,12 +606,20 @@ func prepareUpdateReport(reportFile string, inputFiles []string) (*preparedRepor
 }
 
 func (prepared *preparedReportFile) publish(report updateReport) error {
-	data, err := json.MarshalIndent(report, "", "  ")
-	if err != nil {
+	// Constraint operators such as "<" stay readable rather than HTML-escaped.
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
 		_ = prepared.discard()
 		return fmt.Errorf("create report: %w", err)
 	}
-	data = append(data, '\n')
+	return prepared.write(data.Bytes())
+}
+
+// write writes data to the prepared file and moves it to the report's destination.
+func (prepared *preparedReportFile) write(data []byte) error {
 	if _, err := prepared.file.Write(data); err != nil {
 		_ = prepared.discard()
 		return err
@@ -456,16 +695,91 @@ func validateReportFileDoesNotOverwriteInput(reportFile string, inputFiles []str
 	return nil
 }
 
+// hasOperationFlags reports whether any flag that selects or shapes a single CLI-mode operation is
+// set. Config mode takes these from the config file instead.
+func (flags *cliFlags) hasOperationFlags() bool {
+	return flags.moduleSource != "" || flags.terraformVersion != "" || flags.providerName != "" ||
+		flags.providerSource != "" || flags.addMissing ||
+		flags.toVersion != "" || len(flags.fromVersions) > 0 || len(flags.ignoreVersions) > 0 || flags.ignoreModules != ""
+}
+
+// hasUpdateBehaviourFlags reports whether any flag that shapes how a version is written is set.
+// Of the check modes, only -advisories -fix writes versions.
+func (flags *cliFlags) hasUpdateBehaviourFlags() bool {
+	return flags.preserveOperator || flags.allowDowngrade || flags.prerelease != "" || flags.followReferences
+}
+
+// validateProviderAddFlags validates the flags that add missing provider entries in CLI mode.
+func validateProviderAddFlags(flags *cliFlags) {
+	if (flags.providerSource != "" || flags.addMissing) && flags.providerName == "" {
+		fatalf("Error: -provider-source and -add-missing require -provider")
+	}
+	if flags.addMissing && flags.providerSource == "" {
+		fatalf("Error: -add-missing requires -provider-source")
+	}
+	if flags.providerSource != "" {
+		if err := bump.ValidateProviderSource(flags.providerSource); err != nil {
+			fatalf("Error: Invalid provider source '%s': %v", flags.providerSource, err)
+		}
+	}
+}
+
 // validateOperationModes validates that the CLI flags are properly set
 func validateOperationModes(flags *cliFlags) {
+	validateBranchFlags(flags)
+	validateMarkdownReportFlags(flags)
+	validateInteractiveFlags(flags)
+	// The list subcommand takes only file selection flags
+	if flags.list {
+		return
+	}
+	if flags.exportConfig != "" {
+		validateExportConfigFlags(flags)
+		return
+	}
+	if flags.align {
+		validateAlignFlags(flags)
+	}
+
+	// Advisory, policy, and lint modes check files rather than take an operation, so they are
+	// exclusive with every operation
+	if flags.advisoryFile != "" {
+		validateAdvisoryFlags(flags)
+		return
+	}
+	if flags.policyFile != "" {
+		validatePolicyFlags(flags)
+		return
+	}
+	if flags.fix {
+		fatalf("Error: -fix requires -policy or -advisories")
+	}
+	if flags.lint {
+		validateLintFlags(flags)
+		return
+	}
+	if len(flags.lintRules) > 0 {
+		fatalf("Error: -lint-rule requires -lint")
+	}
+
+	if flags.group != "" && flags.configFile == "" {
+		fatalf("Error: -group requires -config")
+	}
+	if flags.align {
+		return
+	}
+	if flags.alignScope != "" {
+		fatalf("Error: -align-scope requires -align")
+	}
+
 	// Config file mode is exclusive with all other CLI flags
 	if flags.configFile != "" {
-		if flags.moduleSource != "" || flags.terraformVersion != "" || flags.providerName != "" ||
-			flags.toVersion != "" || len(flags.fromVersions) > 0 || len(flags.ignoreVersions) > 0 || flags.ignoreModules != "" {
-			fatalf("Error: Cannot use -config with other operation flags (-module, -to, -terraform-version, -provider, -from, -ignore-version, -ignore-modules)")
+		if flags.hasOperationFlags() {
+			fatalf("Error: Cannot use -config with other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)")
 		}
 		return
 	}
+	validateProviderAddFlags(flags)
 
 	// CLI mode - validate that at least one operation is specified
 	modesSet := 0
@@ -485,6 +799,11 @@ func validateOperationModes(flags *cliFlags) {
 		fmt.Println("  Config file:       tf-version-bump -pattern <glob> -config <config-file>")
 		fmt.Println("  Terraform version: tf-version-bump -pattern <glob> -terraform-version <version>")
 		fmt.Println("  Provider version:  tf-version-bump -pattern <glob> -provider <name> -to <version>")
+		fmt.Println("  Starter config:    tf-version-bump -pattern <glob> -export-config <file>")
+		fmt.Println("  Align versions:    tf-version-bump -pattern <glob> -align [-align-scope directory]")
+		fmt.Println("  Lint:              tf-version-bump -pattern <glob> -lint")
+		fmt.Println("  Policy check:      tf-version-bump -pattern <glob> -policy <policy-file> [-fix]")
+		fmt.Println("  Advisory check:    tf-version-bump -pattern <glob> -advisories <advisory-file> [-fix]")
 		flag.PrintDefaults()
 		exitFunc(1)
 	}
@@ -500,6 +819,21 @@ func findMatchingFiles(flags *cliFlags) []string {
 		fatalf("Error: -pattern flag is required")
 	}
 
+	files, err := flags.matchFiles()
+	if err != nil {
+		fatalf("Error matching pattern: %v", err)
+	}
+	if len(files) == 0 {
+		fatalf("No files matched pattern: %s", flags.pattern)
+	}
+
+	flags.printFileSelection(files)
+	return files
+}
+
+// matchFiles returns the files matching the pattern in sorted order, without the .tf files that
+// OpenTofu mode drops for their .tofu overrides.
+func (flags *cliFlags) matchFiles() ([]string, error) {
 	// doublestar rather than filepath.Glob: it supports '**', which spans zero or more
 	// directories. filepath.Glob treats '**' as a plain '*', silently matching only one
 	// level deep.
@@ -525,7 +859,7 @@ func findMatchingFiles(flags *cliFlags) []string {
 		doublestar.WithFilesOnly(),
 	)
 	if err != nil {
-		fatalf("Error matching pattern: %v", err)
+		return nil, err
 	}
 
 	files := make([]string, 0, len(matches))
@@ -544,49 +878,70 @@ func findMatchingFiles(flags *cliFlags) []string {
 	// doublestar walks depth-first, so results come back in traversal order rather than
 	// sorted. File order is user-visible in the per-file output.
 	slices.Sort(files)
-
-	if len(files) == 0 {
-		fatalf("No files matched pattern: %s", flags.pattern)
+	if flags.opentofu {
+		files = dropOverriddenFiles(files, flags.verbose, flags.output)
 	}
+	return files, nil
+}
 
+// printFileSelection prints how many files the pattern matched. Nothing is printed when standard
+// output carries a starter config.
+func (flags *cliFlags) printFileSelection(files []string) {
+	if flags.exportConfig == "-" {
+		return
+	}
 	fmt.Printf("Found %d file(s) matching pattern %s\n", len(files), quote(flags.pattern, flags.output))
 
 	if flags.dryRun {
 		fmt.Println("Running in dry-run mode - no files will be modified")
 	}
-
-	return files
 }
 
 // runConfigFileMode handles config file mode operations.
 func runConfigFileMode(files []string, flags *cliFlags) error {
-	config, err := loadConfig(flags.configFile)
+	config, err := bump.LoadConfig(flags.configFile)
 	if err != nil {
 		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
 		return fmt.Errorf("Error loading config file: %w", err)
 	}
-
-	var terraformUpdates, terraformErrors, providerUpdates, providerErrors, moduleUpdates, moduleErrors int
-
-	// Process terraform version if specified
-	if config.TerraformVersion != "" {
-		terraformUpdates, terraformErrors = processTerraformVersion(files, config.TerraformVersion, flags.dryRun, flags.output)
+	runs, err := flags.configRuns(config)
+	if err != nil {
+		return err
 	}
-
-	// Process provider updates if specified
-	for _, provider := range config.Providers {
-		count, errors := processProviderVersion(files, provider.Name, provider.Version, flags.dryRun, flags.output, flags.reportRecorder())
-		providerUpdates += count
-		providerErrors += errors
+	for _, run := range runs {
+		if flags.interactive && (len(run.config.Migrations) > 0 || len(run.config.ProviderMigrations) > 0) {
+			//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+			return fmt.Errorf("Error: -interactive cannot confirm migrations or provider_migrations; apply them without -interactive first")
+		}
 	}
 
-	// Process module updates if specified
-	if len(config.Modules) > 0 {
-		moduleUpdates, moduleErrors = processFiles(files, config.Modules, flags)
+	// Without -group, a config with groups reports the outside entries and each group separately
+	sections := flags.group == "" && len(config.Groups) > 0
+	changes, errors := make(map[bump.ChangeKind]int), make(map[bump.ChangeKind]int)
+	for _, run := range runs {
+		runChanges, runErrors, err := flags.applyConfigRun(files, run, sections)
+		if err != nil {
+			return err
+		}
+		for kind, count := range runChanges {
+			changes[kind] += count
+		}
+		for kind, count := range runErrors {
+			errors[kind] += count
+		}
 	}
 
 	// Print summary
-	printConfigSummary(terraformUpdates, providerUpdates, moduleUpdates, flags.dryRun)
+	printConfigSummary(configSummary{
+		terraformUpdates:   changes[bump.TerraformVersionChange],
+		providerMigrations: changes[bump.ProviderMigrationChange],
+		providerUpdates:    changes[bump.ProviderChange],
+		moduleMigrations:   changes[bump.ModuleMigrationChange],
+		moduleUpdates:      changes[bump.ModuleChange],
+	}, flags.dryRun)
+	terraformErrors := errors[bump.TerraformVersionChange]
+	providerErrors := errors[bump.ProviderMigrationChange] + errors[bump.ProviderChange]
+	moduleErrors := errors[bump.ModuleMigrationChange] + errors[bump.ModuleChange]
 	if terraformErrors == 0 && providerErrors == 0 && moduleErrors > 0 {
 		return fmt.Errorf("%d module update error(s)", moduleErrors)
 	}
@@ -598,15 +953,14 @@ func runConfigFileMode(files []string, flags *cliFlags) error {
 
 // runCLIMode handles CLI mode operations
 func runCLIMode(files []string, flags *cliFlags) error {
-	var totalUpdates int
-	var updates []ModuleUpdate
-
 	switch {
 	case flags.terraformVersion != "":
-		var totalErrors int
-		totalUpdates, totalErrors = processTerraformVersion(files, flags.terraformVersion, flags.dryRun, flags.output)
-		printTerraformSummary(totalUpdates, flags.dryRun)
-		if totalErrors > 0 {
+		changes, errors, err := flags.apply(files, bump.Config{TerraformVersion: flags.terraformVersion})
+		if err != nil {
+			return err
+		}
+		printTerraformSummary(changes[bump.TerraformVersionChange], flags.dryRun)
+		if totalErrors := errors[bump.TerraformVersionChange]; totalErrors > 0 {
 			return fmt.Errorf("%d Terraform version update error(s)", totalErrors)
 		}
 		return nil
@@ -614,54 +968,71 @@ func runCLIMode(files []string, flags *cliFlags) error {
 		if flags.toVersion == "" {
 			fatalf("Error: -to flag is required when using -provider")
 		}
-		var totalErrors int
-		totalUpdates, totalErrors = processProviderVersion(files, flags.providerName, flags.toVersion, flags.dryRun, flags.output, flags.reportRecorder())
-		printProviderSummary(flags.providerName, totalUpdates, flags.dryRun, flags.output)
-		if totalErrors > 0 {
+		provider := bump.ProviderUpdate{Name: flags.providerName, Version: flags.toVersion, Source: flags.providerSource, AddMissing: flags.addMissing}
+		changes, errors, err := flags.apply(files, bump.Config{Providers: []bump.ProviderUpdate{provider}})
+		if err != nil {
+			return err
+		}
+		printProviderSummary(flags.providerName, changes[bump.ProviderChange], flags.dryRun, flags.output)
+		if totalErrors := errors[bump.ProviderChange]; totalErrors > 0 {
 			return fmt.Errorf("%d provider update error(s)", totalErrors)
 		}
 		return nil
 	default:
-		updates = loadModuleUpdates(flags)
-		var totalErrors int
-		totalUpdates, totalErrors = processFiles(files, updates, flags)
-		printSummary(totalUpdates, len(updates), flags.dryRun)
-		if totalErrors > 0 {
+		updates := loadModuleUpdates(flags)
+		changes, errors, err := flags.apply(files, bump.Config{Modules: updates})
+		if err != nil {
+			return err
+		}
+		printSummary(changes[bump.ModuleChange], len(updates), flags.dryRun)
+		if totalErrors := errors[bump.ModuleChange]; totalErrors > 0 {
 			return fmt.Errorf("%d module update error(s)", totalErrors)
 		}
 		return nil
 	}
 }
 
+// configSummary counts the operations applied by each group of a config file.
+type configSummary struct {
+	terraformUpdates   int
+	providerMigrations int
+	providerUpdates    int
+	moduleMigrations   int
+	moduleUpdates      int
+}
+
 // printConfigSummary prints the summary for config file mode
-func printConfigSummary(terraformUpdates, providerUpdates, moduleUpdates int, dryRun bool) {
-	if terraformUpdates > 0 || providerUpdates > 0 || moduleUpdates > 0 {
-		fmt.Println("\n" + strings.Repeat("=", 50))
-		fmt.Println("Config File Update Summary")
-		fmt.Println(strings.Repeat("=", 50))
-		if terraformUpdates > 0 {
-			if dryRun {
-				fmt.Printf("Terraform version: would update %d file(s)\n", terraformUpdates)
-			} else {
-				fmt.Printf("Terraform version: %d file(s) updated\n", terraformUpdates)
-			}
-		}
-		if providerUpdates > 0 {
-			if dryRun {
-				fmt.Printf("Providers: would apply %d update(s)\n", providerUpdates)
-			} else {
-				fmt.Printf("Providers: %d update(s) applied\n", providerUpdates)
-			}
-		}
-		if moduleUpdates > 0 {
-			if dryRun {
-				fmt.Printf("Modules: would apply %d update(s)\n", moduleUpdates)
-			} else {
-				fmt.Printf("Modules: %d update(s) applied\n", moduleUpdates)
-			}
+func printConfigSummary(summary configSummary, dryRun bool) {
+	if summary == (configSummary{}) {
+		fmt.Println("\nNo updates were performed. Config file may be empty or contain no matching items.")
+		return
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 50))
+	fmt.Println("Config File Update Summary")
+	fmt.Println(strings.Repeat("=", 50))
+	if summary.terraformUpdates > 0 {
+		if dryRun {
+			fmt.Printf("Terraform version: would update %d file(s)\n", summary.terraformUpdates)
+		} else {
+			fmt.Printf("Terraform version: %d file(s) updated\n", summary.terraformUpdates)
 		}
+	}
+	printConfigSummaryLine("Provider migrations", summary.providerMigrations, "migration", dryRun)
+	printConfigSummaryLine("Providers", summary.providerUpdates, "update", dryRun)
+	printConfigSummaryLine("Module migrations", summary.moduleMigrations, "migration", dryRun)
+	printConfigSummaryLine("Modules", summary.moduleUpdates, "update", dryRun)
+}
+
+// printConfigSummaryLine prints one group's count, omitting groups that applied nothing.
+func printConfigSummaryLine(label string, count int, noun string, dryRun bool) {
+	if count == 0 {
+		return
+	}
+	if dryRun {
+		fmt.Printf("%s: would apply %d %s(s)\n", label, count, noun)
 	} else {
-		fmt.Println("\nNo updates were performed. Config file may be empty or contain no matching items.")
+		fmt.Printf("%s: %d %s(s) applied\n", label, count, noun)
 	}
 }
 
@@ -682,721 +1053,3 @@ func printProviderSummary(providerName string, totalUpdates int, dryRun bool, ou
 		fmt.Printf("\nSuccessfully updated %s provider version in %d file(s)\n", quote(providerName, outputFormat), totalUpdates)
 	}
 }
-
-// containsVersion checks if a version string is present in a slice of versions.
-// This helper function reduces code duplication when checking version filters.
-//
-// Parameters:
-//   - versions: List of version strings to search through
-//   - version: The version string to search for
-//
-// Returns:
-//   - bool: true if the version is found in the list, false otherwise
-func containsVersion(versions []string, version string) bool {
-	for _, v := range versions {
-		if v == version {
-			return true
-		}
-	}
-	return false
-}
-
-// processTerraformVersion updates the required_version in terraform blocks across all files
-//
-// Parameters:
-//   - files: List of file paths to process
-//   - version: Target Terraform version to set
-//   - dryRun: If true, show what would be changed without modifying files
-//   - outputFormat: Output format ("text" or "md")
-//
-// Returns:
-//   - totalUpdates: Number of files that were updated (or would be updated in dry-run mode)
-//   - totalErrors: Number of files that could not be processed
-func processTerraformVersion(files []string, version string, dryRun bool, outputFormat string) (totalUpdates, totalErrors int) {
-	for _, file := range files {
-		updated, err := updateTerraformVersion(file, version, dryRun)
-		if err != nil {
-			log.Printf("Error processing %s: %v", file, err)
-			totalErrors++
-			continue
-		}
-		if updated {
-			prefix := "✓"
-			action := "Updated"
-			if dryRun {
-				prefix = "→"
-				action = "Would update"
-			}
-			fmt.Printf("%s %s Terraform required_version to %s in %s\n", prefix, action, quote(version, outputFormat), file)
-			totalUpdates++
-		}
-	}
-	return totalUpdates, totalErrors
-}
-
-// processProviderVersion updates provider versions in terraform required_providers blocks across all files
-//
-// Parameters:
-//   - files: List of file paths to process
-//   - providerName: Name of the provider to update (e.g., "aws", "azurerm")
-//   - version: Target provider version to set
-//   - dryRun: If true, show what would be changed without modifying files
-//   - outputFormat: Output format ("text" or "md")
-//
-// Returns:
-//   - totalUpdates: Number of files that were updated (or would be updated in dry-run mode)
-//   - totalErrors: Number of files that could not be processed
-func processProviderVersion(files []string, providerName, version string, dryRun bool, outputFormat string, report *updateReport) (totalUpdates, totalErrors int) {
-	for _, file := range files {
-		updated, changedBlocks, err := updateProviderVersionWithCount(file, providerName, version, dryRun)
-		if err != nil {
-			log.Printf("Error processing %s: %v", file, err)
-			totalErrors++
-			continue
-		}
-		if updated {
-			if report != nil && !dryRun && len(changedBlocks) > 0 {
-				report.recordProviderBlocks(file, changedBlocks)
-			}
-			prefix := "✓"
-			action := "Updated"
-			if dryRun {
-				prefix = "→"
-				action = "Would update"
-			}
-			fmt.Printf("%s %s provider %s to version %s in %s\n", prefix, action, quote(providerName, outputFormat), quote(version, outputFormat), file)
-			totalUpdates++
-		}
-	}
-	return totalUpdates, totalErrors
-}
-
-// updateTerraformVersion updates the required_version attribute in terraform blocks
-//
-// Parameters:
-//   - filename: Path to the Terraform file to process
-//   - version: Target Terraform version to set
-//   - dryRun: If true, show what would be changed without modifying files
-//
-// Returns:
-//   - bool: true if a terraform block was updated (or would be updated in dry-run mode)
-//   - error: Any error encountered during file reading, parsing, or writing
-func updateTerraformVersion(filename, version string, dryRun bool) (bool, error) {
-	// Get original file permissions to preserve them when writing
-	fileInfo, err := os.Stat(filename)
-	if err != nil {
-		return false, fmt.Errorf("failed to stat file: %w", err)
-	}
-	originalMode := fileInfo.Mode()
-
-	// Read the file
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return false, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse the file with hclwrite
-	file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return false, fmt.Errorf("failed to parse HCL: %s", diags.Error())
-	}
-
-	// Track if we made any changes
-	updated := false
-
-	// Iterate through all blocks in the file
-	for _, block := range file.Body().Blocks() {
-		// Look for terraform blocks
-		if block.Type() == "terraform" {
-			// Update or add the required_version attribute
-			block.Body().SetAttributeValue("required_version", cty.StringVal(version))
-			updated = true
-		}
-	}
-
-	// If we made changes, write the file back (unless in dry-run mode)
-	if updated && !dryRun {
-		output := hclwrite.Format(file.Bytes())
-		// Preserve original file permissions
-		if err := os.WriteFile(filename, output, originalMode.Perm()); err != nil {
-			return false, fmt.Errorf("failed to write file: %w", err)
-		}
-	}
-
-	return updated, nil
-}
-
-// updateProviderVersionWithCount updates a provider version and counts blocks whose values changed.
-//
-// This implementation supports both provider syntax styles:
-//
-// Block-based syntax:
-//
-//	required_providers { aws { source = "..." version = "..." } }
-//
-// Attribute-based syntax:
-//
-//	required_providers { aws = { source = "..." version = "..." } }
-//
-// Parameters:
-//   - filename: Path to the Terraform file to process
-//   - providerName: Name of the provider to update (e.g., "aws", "azurerm")
-//   - version: Target provider version to set
-//   - dryRun: If true, show what would be changed without modifying files
-//
-// Returns:
-//   - updated: true if a provider operation was applied (or would be applied in dry-run mode)
-//   - changedBlocks: locations of provider blocks whose version values differ from the target
-//   - error: Any error encountered during file reading, parsing, or writing
-func updateProviderVersionWithCount(filename, providerName, version string, dryRun bool) (updated bool, changedBlocks []string, err error) {
-	// Get original file permissions to preserve them when writing
-	fileInfo, err := os.Stat(filename)
-	if err != nil {
-		return false, nil, fmt.Errorf("failed to stat file: %w", err)
-	}
-	originalMode := fileInfo.Mode()
-
-	// Read the file
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return false, nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse the file with hclwrite
-	file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return false, nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
-	}
-
-	updated = false
-	changedBlocks = nil
-
-	// Iterate through all blocks in the file
-	for blockIndex, block := range file.Body().Blocks() {
-		blockUpdated, blockChanges := updateProviderTerraformBlockResult(block, providerName, version)
-		updated = updated || blockUpdated
-		for _, blockChange := range blockChanges {
-			changedBlocks = append(changedBlocks, fmt.Sprintf("%d/%s", blockIndex, blockChange))
-		}
-	}
-
-	// If we made changes, write the file back (unless in dry-run mode)
-	if updated && !dryRun {
-		output := hclwrite.Format(file.Bytes())
-		// Preserve original file permissions
-		if err := os.WriteFile(filename, output, originalMode.Perm()); err != nil {
-			return false, nil, fmt.Errorf("failed to write file: %w", err)
-		}
-	}
-
-	return updated, changedBlocks, nil
-}
-
-func updateProviderTerraformBlockResult(block *hclwrite.Block, providerName, version string) (updated bool, changedBlocks []string) {
-	if block.Type() != "terraform" {
-		return false, nil
-	}
-
-	updated = false
-	changedBlocks = nil
-	for nestedIndex, nestedBlock := range block.Body().Blocks() {
-		if nestedBlock.Type() != "required_providers" {
-			continue
-		}
-		blockSyntaxUpdated, blockSyntaxChanges := updateProviderBlockSyntaxResult(nestedBlock, providerName, version)
-		if blockSyntaxUpdated {
-			updated = true
-			for _, blockSyntaxIndex := range blockSyntaxChanges {
-				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/block/%d", nestedIndex, blockSyntaxIndex))
-			}
-			continue
-		}
-		attributeUpdated, attributeChanged := updateProviderAttributeVersionResult(nestedBlock, providerName, version)
-		if attributeUpdated {
-			updated = true
-			if attributeChanged {
-				changedBlocks = append(changedBlocks, fmt.Sprintf("%d/attribute/%s", nestedIndex, providerName))
-			}
-		}
-	}
-
-	return updated, changedBlocks
-}
-
-func updateProviderBlockSyntaxResult(nestedBlock *hclwrite.Block, providerName, version string) (updated bool, changedBlocks []int) {
-	updated = false
-	changedBlocks = nil
-	for providerIndex, providerBlock := range nestedBlock.Body().Blocks() {
-		if providerBlock.Type() != providerName {
-			continue
-		}
-		versionAttribute := providerBlock.Body().GetAttribute("version")
-		if versionAttribute == nil || attributeStringValue(versionAttribute) != version {
-			changedBlocks = append(changedBlocks, providerIndex)
-		}
-		providerBlock.Body().SetAttributeValue("version", cty.StringVal(version))
-		updated = true
-	}
-
-	return updated, changedBlocks
-}
-
-// updateProviderAttributeVersion updates the version value within a provider attribute's object expression
-// This handles the attribute-based syntax: aws = { source = "..." version = "..." }
-func updateProviderAttributeVersionResult(nestedBlock *hclwrite.Block, providerName, newVersion string) (updated, changed bool) {
-	objExpr, expression, ok := providerAttributeObject(nestedBlock, providerName)
-	if !ok {
-		return false, false
-	}
-
-	updatedExpression, hasVersion, changed := replaceProviderObjectVersion(objExpr, expression, newVersion)
-	if !hasVersion {
-		return false, false
-	}
-
-	newAttribute := append([]byte(providerName+" = "), updatedExpression...)
-	newExpr, diags := hclwrite.ParseConfig(newAttribute, "inline", hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return false, false
-	}
-
-	for _, newAttr := range newExpr.Body().Attributes() {
-		nestedBlock.Body().SetAttributeRaw(providerName, newAttr.Expr().BuildTokens(nil))
-		return true, changed
-	}
-
-	return false, false
-}
-
-func providerAttributeObject(nestedBlock *hclwrite.Block, providerName string) (*hclsyntax.ObjectConsExpr, []byte, bool) {
-	attr, exists := nestedBlock.Body().Attributes()[providerName]
-	if !exists {
-		return nil, nil, false
-	}
-
-	tokens := attr.Expr().BuildTokens(nil)
-	expression := tokens.Bytes()
-	expr, diags := hclsyntax.ParseExpression(expression, "inline", hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return nil, nil, false
-	}
-
-	objExpr, ok := expr.(*hclsyntax.ObjectConsExpr)
-	return objExpr, expression, ok
-}
-
-func replaceProviderObjectVersion(objExpr *hclsyntax.ObjectConsExpr, expression []byte, newVersion string) (updated []byte, hasVersion, changed bool) {
-	updated = append([]byte(nil), expression...)
-	hasVersion = false
-	changed = false
-	newValue := hclwrite.TokensForValue(cty.StringVal(newVersion)).Bytes()
-
-	for index := len(objExpr.Items) - 1; index >= 0; index-- {
-		item := objExpr.Items[index]
-		keyName, ok := providerObjectItemKey(item)
-		if !ok || keyName != "version" {
-			continue
-		}
-
-		valueRange := item.ValueExpr.Range()
-		if valueRange.Start.Byte < 0 || valueRange.End.Byte > len(expression) || valueRange.Start.Byte > valueRange.End.Byte {
-			return nil, false, false
-		}
-		hasVersion = true
-		if attributeExpressionStringValue(expression[valueRange.Start.Byte:valueRange.End.Byte]) == newVersion {
-			continue
-		}
-
-		next := make([]byte, 0, len(updated)-valueRange.End.Byte+valueRange.Start.Byte+len(newValue))
-		next = append(next, updated[:valueRange.Start.Byte]...)
-		next = append(next, newValue...)
-		next = append(next, updated[valueRange.End.Byte:]...)
-		updated = next
-		changed = true
-	}
-
-	return updated, hasVersion, changed
-}
-
-func attributeExpressionStringValue(expression []byte) string {
-	return trimQuotes(strings.TrimSpace(string(expression)))
-}
-
-func providerObjectItemKey(item hclsyntax.ObjectConsItem) (string, bool) {
-	keyExpr, ok := item.KeyExpr.(*hclsyntax.ObjectConsKeyExpr)
-	if !ok {
-		return "", false
-	}
-
-	traversal, ok := keyExpr.Wrapped.(*hclsyntax.ScopeTraversalExpr)
-	if !ok || len(traversal.Traversal) == 0 {
-		return "", false
-	}
-
-	rootName, ok := traversal.Traversal[0].(hcl.TraverseRoot)
-	if !ok {
-		return "", false
-	}
-
-	return rootName.Name, true
-}
-
-// updateModuleVersionWithCount updates modules with the specified source and counts changed blocks.
-//
-// The function retains comments and other HCL structures, then normalises the changed file with
-// hclwrite.Format.
-// If a matching module doesn't have a version attribute:
-//   - When forceAdd is false (default): a warning is printed and the module is skipped
-//   - When forceAdd is true and the source is a registry module: a version attribute is added
-//   - When forceAdd is true and the source is not a registry module: a warning is printed and the
-//     module is skipped because Terraform does not permit version constraints for those sources
-//
-// All modules with the same source attribute will be updated to the same version.
-// If fromVersions is specified, only modules with current version matching any in the list will be updated.
-// If ignoreVersions is specified, modules with current version matching any in the list will be skipped.
-// If ignorePatterns is specified, modules with names matching any pattern will be skipped.
-//
-// Parameters:
-//   - filename: Path to the Terraform file to process
-//   - moduleSource: The module source to match (e.g., "terraform-aws-modules/vpc/aws")
-//   - version: The target version to set (e.g., "5.0.0")
-//   - fromVersions: Optional: only update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
-//   - ignoreVersions: Optional: skip update if current version matches any in this list (e.g., ["4.0.0", "~> 3.0"])
-//   - ignorePatterns: Optional: list of module names or patterns to ignore (e.g., ["vpc", "legacy-*"])
-//   - forceAdd: If true, add a version attribute to registry modules that don't have one
-//   - dryRun: If true, show what would be changed without modifying files
-//   - verbose: If true, print informational messages about skipped modules
-//   - outputFormat: Output format ("text" or "md")
-//
-// Returns:
-//   - updated: true if at least one module operation was applied (or would be applied in dry-run mode)
-//   - changedBlocks: indexes of module blocks whose version values differ from the target
-//   - error: Any error encountered during file reading, parsing, or writing
-func updateModuleVersionWithCount(filename, moduleSource, version string, fromVersions, ignoreVersions, ignorePatterns []string, forceAdd, dryRun, verbose bool, outputFormat string) (updated bool, changedBlocks []int, err error) {
-	// Get original file permissions to preserve them when writing
-	fileInfo, err := os.Stat(filename)
-	if err != nil {
-		return false, nil, fmt.Errorf("failed to stat file: %w", err)
-	}
-	originalMode := fileInfo.Mode()
-
-	// Read the file
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return false, nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse the file with hclwrite
-	file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return false, nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
-	}
-
-	updated = false
-	changedBlocks = nil
-	opts := moduleUpdateOptions{
-		filename:       filename,
-		moduleSource:   moduleSource,
-		version:        version,
-		fromVersions:   fromVersions,
-		ignoreVersions: ignoreVersions,
-		ignorePatterns: ignorePatterns,
-		forceAdd:       forceAdd,
-		verbose:        verbose,
-		outputFormat:   outputFormat,
-	}
-
-	// Iterate through all blocks in the file
-	for blockIndex, block := range file.Body().Blocks() {
-		blockUpdated, blockChanged := updateModuleBlockResult(block, &opts)
-		if blockUpdated {
-			updated = true
-			if blockChanged {
-				changedBlocks = append(changedBlocks, blockIndex)
-			}
-		}
-	}
-
-	// If we made changes, write the file back (unless in dry-run mode)
-	if updated && !dryRun {
-		output := hclwrite.Format(file.Bytes())
-		// Preserve original file permissions
-		if err := os.WriteFile(filename, output, originalMode.Perm()); err != nil {
-			return false, nil, fmt.Errorf("failed to write file: %w", err)
-		}
-	}
-
-	return updated, changedBlocks, nil
-}
-
-type moduleUpdateOptions struct {
-	filename       string
-	moduleSource   string
-	version        string
-	fromVersions   []string
-	ignoreVersions []string
-	ignorePatterns []string
-	forceAdd       bool
-	verbose        bool
-	outputFormat   string
-}
-
-func updateModuleBlockResult(block *hclwrite.Block, opts *moduleUpdateOptions) (updated, changed bool) {
-	if block.Type() != "module" {
-		return false, false
-	}
-
-	moduleName := moduleBlockName(block)
-	sourceValue, ok := moduleSourceValue(block)
-	if !ok || sourceValue != opts.moduleSource {
-		return false, false
-	}
-
-	if isLocalModule(sourceValue) {
-		fmt.Fprintf(os.Stderr, "Warning: Module %s in %s (source: %s) is a local module and cannot be version-bumped, skipping\n",
-			quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
-		return false, false
-	}
-
-	if shouldIgnoreModule(moduleName, opts.ignorePatterns) {
-		if opts.verbose {
-			fmt.Printf("  ⊗ Skipped module %s in %s (matches ignore pattern)\n", quote(moduleName, opts.outputFormat), opts.filename)
-		}
-		return false, false
-	}
-
-	versionAttr := block.Body().GetAttribute("version")
-	if versionAttr == nil {
-		if !opts.forceAdd {
-			fmt.Fprintf(os.Stderr, "Warning: Module %s in %s (source: %s) has no version attribute, skipping\n",
-				quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
-			return false, false
-		}
-		if !isRegistryModule(sourceValue) {
-			fmt.Fprintf(os.Stderr, "Warning: Module %s in %s (source: %s) is not a registry module and cannot use a version attribute, skipping\n",
-				quote(moduleName, opts.outputFormat), opts.filename, quote(opts.moduleSource, opts.outputFormat))
-			return false, false
-		}
-	} else {
-		currentVersion := attributeStringValue(versionAttr)
-		if shouldSkipModuleVersion(moduleName, currentVersion, opts) {
-			return false, false
-		}
-		block.Body().SetAttributeValue("version", cty.StringVal(opts.version))
-		return true, currentVersion != opts.version
-	}
-
-	block.Body().SetAttributeValue("version", cty.StringVal(opts.version))
-	return true, true
-}
-
-func moduleBlockName(block *hclwrite.Block) string {
-	if len(block.Labels()) == 0 {
-		return ""
-	}
-	return block.Labels()[0]
-}
-
-func moduleSourceValue(block *hclwrite.Block) (string, bool) {
-	sourceAttr := block.Body().GetAttribute("source")
-	if sourceAttr == nil {
-		return "", false
-	}
-	return attributeStringValue(sourceAttr), true
-}
-
-func attributeStringValue(attr *hclwrite.Attribute) string {
-	tokens := attr.Expr().BuildTokens(nil)
-	return trimQuotes(strings.TrimSpace(string(tokens.Bytes())))
-}
-
-func shouldSkipModuleVersion(moduleName, currentVersion string, opts *moduleUpdateOptions) bool {
-	if len(opts.ignoreVersions) > 0 && containsVersion(opts.ignoreVersions, currentVersion) {
-		if opts.verbose {
-			fmt.Printf("  ⊗ Skipped module %s in %s (current version %s matches 'ignore-version' filter %v)\n", quote(moduleName, opts.outputFormat), opts.filename, quote(currentVersion, opts.outputFormat), opts.ignoreVersions)
-		}
-		return true
-	}
-
-	if len(opts.fromVersions) > 0 && !containsVersion(opts.fromVersions, currentVersion) {
-		if opts.verbose {
-			fmt.Printf("  ⊗ Skipped module %s in %s (current version %s does not match any 'from' filter %v)\n", quote(moduleName, opts.outputFormat), opts.filename, quote(currentVersion, opts.outputFormat), opts.fromVersions)
-		}
-		return true
-	}
-
-	return false
-}
-
-// isLocalModule checks if a module source is a local path.
-// Local modules use relative or absolute paths instead of registry sources.
-//
-// Parameters:
-//   - source: The module source to check
-//
-// Returns:
-//   - bool: true if the source is a local path, false otherwise
-//
-// Examples:
-//   - `./modules/vpc` returns true
-//   - `../shared/modules` returns true
-//   - `/absolute/path/module` returns true
-//   - `terraform-aws-modules/vpc/aws` returns false
-func isLocalModule(source string) bool {
-	return strings.HasPrefix(source, "./") ||
-		strings.HasPrefix(source, "../") ||
-		strings.HasPrefix(source, "/")
-}
-
-func isRegistryModule(source string) bool {
-	_, err := tfaddr.ParseModuleSource(source)
-	return err == nil
-}
-
-// shouldIgnoreModule checks if a module name matches any of the ignore patterns.
-// Patterns support wildcard matching using '*' for zero or more characters.
-//
-// Parameters:
-//   - moduleName: The name of the module to check
-//   - patterns: List of patterns to match against (e.g., ["vpc", "legacy-*", "*-test"])
-//
-// Returns:
-//   - bool: true if the module name matches any pattern, false otherwise
-//
-// Examples:
-//   - shouldIgnoreModule("vpc", ["vpc"]) returns true (exact match)
-//   - shouldIgnoreModule("legacy-vpc", ["legacy-*"]) returns true (wildcard prefix)
-//   - shouldIgnoreModule("vpc-test", ["*-test"]) returns true (wildcard suffix)
-//   - shouldIgnoreModule("prod-vpc-test", ["*-vpc-*"]) returns true (wildcard both sides)
-//   - shouldIgnoreModule("vpc", ["s3"]) returns false (no match)
-func shouldIgnoreModule(moduleName string, patterns []string) bool {
-	// Defensive: According to HCL/Terraform syntax, module blocks must have labels ("module" "name"),
-	// so moduleName should never be empty in practice. This check handles malformed HCL or unexpected
-	// parsing results. If moduleName is empty, do not ignore the module.
-	if moduleName == "" {
-		return false
-	}
-
-	if len(patterns) == 0 {
-		return false
-	}
-
-	for _, pattern := range patterns {
-		if matchPattern(moduleName, pattern) {
-			return true
-		}
-	}
-	return false
-}
-
-// matchPattern performs wildcard pattern matching.
-// Supports '*' as a wildcard that matches zero or more characters.
-//
-// Matching behavior:
-//   - Uses greedy matching for middle parts (finds first occurrence of each part in order)
-//   - Consecutive wildcards (**, ***, etc.) are treated as a single wildcard
-//   - For patterns with multiple wildcards and repeated literal parts (e.g., "a*c*c"),
-//     the algorithm ensures all parts fit without overlapping by checking that middle
-//     parts don't extend past where the suffix begins
-//
-// Parameters:
-//   - name: The string to match
-//   - pattern: The pattern to match against (may contain '*' wildcards)
-//
-// Returns:
-//   - bool: true if the name matches the pattern, false otherwise
-//
-// Examples:
-//   - matchPattern("vpc", "vpc") returns true (exact match)
-//   - matchPattern("legacy-vpc", "legacy-*") returns true (wildcard suffix)
-//   - matchPattern("vpc-test", "*-test") returns true (wildcard prefix)
-//   - matchPattern("prod-vpc-test", "*-vpc-*") returns true (wildcard both sides)
-//   - matchPattern("abc", "a**c") returns true (consecutive wildcards)
-//   - matchPattern("acc", "a*c*c") returns true (repeated parts, wildcards match zero chars)
-//   - matchPattern("vpc", "s3") returns false (no match)
-func matchPattern(name, pattern string) bool {
-	// If pattern has no wildcards, do exact match
-	if !strings.Contains(pattern, "*") {
-		return name == pattern
-	}
-
-	// Split pattern by '*' to get the literal parts
-	parts := strings.Split(pattern, "*")
-
-	if !patternBoundsMatch(name, parts) {
-		return false
-	}
-
-	pos, ok := orderedMiddlePartsEnd(name, parts)
-	return ok && suffixDoesNotOverlap(name, parts, pos)
-}
-
-func patternBoundsMatch(name string, parts []string) bool {
-	first := parts[0]
-	last := parts[len(parts)-1]
-
-	if first != "" && !strings.HasPrefix(name, first) {
-		return false
-	}
-	if last != "" && !strings.HasSuffix(name, last) {
-		return false
-	}
-
-	return first == "" || last == "" || len(name) >= len(first)+len(last)
-}
-
-func orderedMiddlePartsEnd(name string, parts []string) (int, bool) {
-	pos := 0
-	for i, part := range parts {
-		if part == "" {
-			continue
-		}
-		if i == 0 {
-			pos = len(part)
-			continue
-		}
-		if i == len(parts)-1 {
-			break
-		}
-
-		idx := strings.Index(name[pos:], part)
-		if idx == -1 {
-			return 0, false
-		}
-		pos += idx + len(part)
-	}
-
-	return pos, true
-}
-
-func suffixDoesNotOverlap(name string, parts []string, pos int) bool {
-	last := parts[len(parts)-1]
-	if last == "" {
-		return true
-	}
-
-	return pos <= len(name)-len(last)
-}
-
-// trimQuotes removes surrounding single or double quotes from a string.
-// If the string doesn't have matching quotes on both ends, it returns the original string.
-//
-// Parameters:
-//   - s: The string to trim quotes from
-//
-// Returns:
-//   - string: The string with quotes removed, or the original string if no matching quotes found
-//
-// Examples:
-//   - `"hello"` returns `hello`
-//   - `'hello'` returns `hello`
-//   - `hello` returns `hello`
-//   - `"hello'` returns `"hello'` (mismatched quotes)
-func trimQuotes(s string) string {
-	if len(s) >= 2 {
-		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-			return s[1 : len(s)-1]
-		}
-	}
-	return s
-}
@@ -0,0 +1,328 @@
+package main
+
+// The Markdown report is a document for pull request bodies: a summary table per module source and
+// provider, the changes of each file in collapsible sections, and the blocks that were skipped. A
+// run of every group of a config has a section of each per group.
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// markdownReport collects the changes and skips of a run for the Markdown report.
+type markdownReport struct {
+	changes []bump.Change
+	skips   []bump.Skip
+	group   string          // The config group applied with -group
+	groups  []markdownGroup // The sections of a run of every config group, in the order applied
+	// The providers declared differently within a selected module directory
+	conflicts []bump.ProviderConflict
+}
+
+// markdownGroup is the section of the Markdown report for one config group, or for the entries
+// outside every group.
+type markdownGroup struct {
+	name   string // Empty for the entries outside every group
+	report markdownReport
+}
+
+// markdownSummaryRow is one update in the summary table: the changes of one module source,
+// provider, or other subject to one target, across files.
+type markdownSummaryRow struct {
+	update    string
+	from      []string
+	to        string
+	blocks    int
+	hasBlocks bool
+	files     []string
+}
+
+// validateMarkdownReportFlags validates -markdown-report against the other report flags.
+func validateMarkdownReportFlags(flags *cliFlags) {
+	if flags.markdownReport == "" {
+		return
+	}
+	if flags.branches != "" {
+		fatalf("Error: Cannot use -markdown-report with -branches")
+	}
+	if flags.reportFile != "" && filepath.Clean(flags.reportFile) == filepath.Clean(flags.markdownReport) {
+		fatalf("Error: -markdown-report and -report-file must be different files")
+	}
+}
+
+// startGroup starts the section that later events are recorded in.
+func (report *markdownReport) startGroup(name string) {
+	report.groups = append(report.groups, markdownGroup{name: name})
+}
+
+// record adds an event of the run to the report, in the current group's section when there is
+// one. Diagnostics are not listed, because the report is written only when every file was
+// processed.
+func (report *markdownReport) record(event bump.Event) {
+	if len(report.groups) > 0 {
+		report.groups[len(report.groups)-1].report.record(event)
+		return
+	}
+	switch event := event.(type) {
+	case bump.Change:
+		report.changes = append(report.changes, event)
+	case bump.Skip:
+		report.skips = append(report.skips, event)
+	}
+}
+
+// render returns the Markdown document.
+//
+// Parameters:
+//   - dryRun: Whether the changes were only previewed
+//   - outputFormat: The -output format that skip messages were quoted with
+//
+// Returns:
+//   - []byte: The document, ending in a newline
+func (report *markdownReport) render(dryRun bool, outputFormat string) []byte {
+	var doc strings.Builder
+	doc.WriteString("## Terraform version updates")
+	if report.group != "" {
+		doc.WriteString(": group " + markdownCode(report.group))
+	}
+	doc.WriteString("\n\n")
+	if dryRun {
+		doc.WriteString("> [!NOTE]\n> Dry run: no files were changed.\n\n")
+	}
+
+	if len(report.groups) == 0 {
+		report.renderSection(&doc, "###", outputFormat)
+		report.renderConflicts(&doc)
+		return []byte(doc.String())
+	}
+	var all markdownReport
+	for _, group := range report.groups {
+		all.changes = append(all.changes, group.report.changes...)
+	}
+	fmt.Fprintf(&doc, "%d update(s) across %d file(s).\n", len(all.changes), len(all.changedFiles()))
+	for _, group := range report.groups {
+		if group.name == "" {
+			doc.WriteString("\n### Ungrouped updates\n\n")
+		} else {
+			fmt.Fprintf(&doc, "\n### Group %s\n\n", markdownCode(group.name))
+		}
+		group.report.renderSection(&doc, "####", outputFormat)
+	}
+	report.renderConflicts(&doc)
+	return []byte(doc.String())
+}
+
+// renderSection writes the summary table, changes by file, and skipped blocks of the report, with
+// subheadings at the given level.
+func (report *markdownReport) renderSection(doc *strings.Builder, heading, outputFormat string) {
+	files := report.changedFiles()
+	if len(report.changes) == 0 {
+		doc.WriteString("No updates were applied.\n")
+	} else {
+		fmt.Fprintf(doc, "%d update(s) across %d file(s).\n\n", len(report.changes), len(files))
+		doc.WriteString("| Update | From | To | Blocks | Files |\n| --- | --- | --- | ---: | ---: |\n")
+		for _, row := range summaryRows(report.changes) {
+			blocks := "—"
+			if row.hasBlocks {
+				blocks = fmt.Sprint(row.blocks)
+			}
+			fmt.Fprintf(doc, "| %s | %s | %s | %s | %d |\n", tableCell(row.update), tableCell(markdownVersions(row.from)),
+				tableCell(markdownCode(row.to)), blocks, len(row.files))
+		}
+		report.renderDirectories(doc, heading)
+		fmt.Fprintf(doc, "\n%s Changes by file\n", heading)
+		for _, file := range files {
+			report.renderFile(doc, file)
+		}
+	}
+
+	if len(report.skips) > 0 {
+		fmt.Fprintf(doc, "\n%s Skipped blocks\n\n", heading)
+		for _, skip := range report.skips {
+			message := skip.Message
+			if outputFormat != "md" {
+				message = escapeMarkdown(message)
+			}
+			fmt.Fprintf(doc, "- %s\n", message)
+		}
+	}
+}
+
+// renderDirectories writes the table of changes per module directory, when the changes span more
+// than one directory.
+func (report *markdownReport) renderDirectories(doc *strings.Builder, heading string) {
+	var directories []directoryResult
+	for _, change := range report.changes {
+		path := filepath.Dir(change.File)
+		index := slices.IndexFunc(directories, func(result directoryResult) bool { return result.Path == path })
+		if index < 0 {
+			directories = append(directories, directoryResult{Path: path})
+			index = len(directories) - 1
+		}
+		directories[index].Changes++
+		if !slices.Contains(directories[index].files, change.File) {
+			directories[index].files = append(directories[index].files, change.File)
+		}
+	}
+	if len(directories) < 2 {
+		return
+	}
+	fmt.Fprintf(doc, "\n%s Module directories\n\n| Directory | Updates | Files |\n| --- | ---: | ---: |\n", heading)
+	for _, result := range directories {
+		fmt.Fprintf(doc, "| %s | %d | %d |\n", tableCell(markdownCode(result.Path)), result.Changes, len(result.files))
+	}
+}
+
+// renderConflicts writes the providers declared differently within a module directory.
+func (report *markdownReport) renderConflicts(doc *strings.Builder) {
+	if len(report.conflicts) == 0 {
+		return
+	}
+	doc.WriteString("\n### Provider conflicts\n\n")
+	for _, conflict := range report.conflicts {
+		values := make([]string, len(conflict.Declarations))
+		for index, declaration := range conflict.Declarations {
+			values[index] = fmt.Sprintf("%s in %s", markdownCode(conflictValue(conflict, declaration)),
+				markdownCode(fmt.Sprintf("%s:%d", declaration.File, declaration.Pos.Line)))
+		}
+		fmt.Fprintf(doc, "- %s declares provider %s with different %ss: %s\n", markdownCode(conflict.Directory),
+			markdownCode(conflict.Name), conflict.Field, strings.Join(values, ", "))
+	}
+}
+
+// renderFile writes the collapsible section listing the changes of one file.
+func (report *markdownReport) renderFile(doc *strings.Builder, file string) {
+	var lines []string
+	for _, change := range report.changes {
+		if change.File != file {
+			continue
+		}
+		update, from, to := describeMarkdownChange(change)
+		line := "- " + update + ": "
+		if len(from) > 0 {
+			line += markdownVersions(from) + " → "
+		}
+		line += markdownCode(to)
+		if len(change.Blocks) > 0 {
+			line += fmt.Sprintf(" (%d block(s))", len(change.Blocks))
+		}
+		lines = append(lines, line)
+	}
+	fmt.Fprintf(doc, "\n<details>\n<summary><code>%s</code> (%d update(s))</summary>\n\n%s\n\n</details>\n",
+		html.EscapeString(file), len(lines), strings.Join(lines, "\n"))
+}
+
+// changedFiles returns the files with changes, in the order they were first changed.
+func (report *markdownReport) changedFiles() []string {
+	var files []string
+	for _, change := range report.changes {
+		if !slices.Contains(files, change.File) {
+			files = append(files, change.File)
+		}
+	}
+	return files
+}
+
+// summaryRows groups changes by subject and target, in the order each was first applied.
+func summaryRows(changes []bump.Change) []*markdownSummaryRow {
+	var rows []*markdownSummaryRow
+	index := make(map[string]*markdownSummaryRow)
+	for _, change := range changes {
+		update, from, to := describeMarkdownChange(change)
+		key := update + "\x00" + to
+		row, ok := index[key]
+		if !ok {
+			row = &markdownSummaryRow{update: update, to: to}
+			index[key] = row
+			rows = append(rows, row)
+		}
+		for _, version := range from {
+			if !slices.Contains(row.from, version) {
+				row.from = append(row.from, version)
+			}
+		}
+		if !slices.Contains(row.files, change.File) {
+			row.files = append(row.files, change.File)
+		}
+		row.blocks += len(change.Blocks)
+		row.hasBlocks = row.hasBlocks || change.Kind != bump.TerraformVersionChange && change.Kind != bump.ReferenceChange
+	}
+	for _, row := range rows {
+		slices.Sort(row.from)
+	}
+	return rows
+}
+
+// describeMarkdownChange returns the subject of a change in Markdown, the versions or source it
+// replaced, and the version or source it wrote.
+func describeMarkdownChange(change bump.Change) (update string, from []string, to string) {
+	switch change.Kind {
+	case bump.TerraformVersionChange:
+		return "Terraform `required_version`", nil, change.Version
+	case bump.ProviderMigrationChange:
+		return "Provider " + markdownCode(change.Name) + " source", []string{change.FromSource}, change.ToSource
+	case bump.ProviderChange:
+		return "Provider " + markdownCode(change.Name), change.Previous, change.Version
+	case bump.ModuleMigrationChange:
+		return "Module source", []string{change.FromSource}, change.ToSource
+	case bump.ModuleChange:
+		return "Module " + markdownCode(change.Source), change.Previous, change.Version
+	case bump.ReferenceChange:
+		return "Reference " + markdownCode(change.Name), nil, change.Version
+	default:
+		if change.Name != "" {
+			return markdownCode(change.Name) + " (" + markdownCode(change.Updater) + ")", nil, change.Version
+		}
+		return "Updater " + markdownCode(change.Updater), nil, change.Version
+	}
+}
+
+// markdownVersions formats replaced versions as code spans, with a missing version as "none".
+func markdownVersions(versions []string) string {
+	if len(versions) == 0 {
+		return "—"
+	}
+	formatted := make([]string, 0, len(versions))
+	for _, version := range versions {
+		if version == "" {
+			formatted = append(formatted, "none")
+		} else {
+			formatted = append(formatted, markdownCode(version))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// markdownCode formats a string as a code span, fenced with enough backticks for its contents.
+func markdownCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+// tableCell escapes the pipes that would otherwise end a table cell, including those in code spans.
+func tableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// escapeMarkdown escapes the characters of plain text that Markdown would read as formatting.
+func escapeMarkdown(s string) string {
+	var escaped strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>#|", r) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+func TestCommandWritesMarkdownReport(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.0.0"
+}
+
+module "newer" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "6.0.0"
+}
+`)
+	writeTestFile(t, dir, "prod.tf", `module "vpc_prod" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "4.1.0"
+}
+`)
+	writeTestFile(t, dir, "config.yml", `providers:
+  - name: aws
+    version: "~> 6.0"
+modules:
+  - source: terraform-aws-modules/vpc/aws
+    version: "5.0.0"
+`)
+	report := filepath.Join(t.TempDir(), "report.md")
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", "*.tf", "-config", "config.yml", "-markdown-report", report})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+
+	want := "## Terraform version updates\n\n" +
+		"3 update(s) across 2 file(s).\n\n" +
+		"| Update | From | To | Blocks | Files |\n| --- | --- | --- | ---: | ---: |\n" +
+		"| Provider `aws` | `~> 5.0` | `~> 6.0` | 1 | 1 |\n" +
+		"| Module `terraform-aws-modules/vpc/aws` | `4.0.0`, `4.1.0` | `5.0.0` | 2 | 2 |\n\n" +
+		"### Changes by file\n\n" +
+		"<details>\n<summary><code>main.tf</code> (2 update(s))</summary>\n\n" +
+		"- Provider `aws`: `~> 5.0` → `~> 6.0` (1 block(s))\n" +
+		"- Module `terraform-aws-modules/vpc/aws`: `4.0.0` → `5.0.0` (1 block(s))\n\n</details>\n\n" +
+		"<details>\n<summary><code>prod.tf</code> (1 update(s))</summary>\n\n" +
+		"- Module `terraform-aws-modules/vpc/aws`: `4.1.0` → `5.0.0` (1 block(s))\n\n</details>\n\n" +
+		"### Skipped blocks\n\n" +
+		"- Module 'newer' in main.tf (source: 'terraform-aws-modules/vpc/aws') is at version '6.0.0', which is newer than '5.0.0'; " +
+		"skipping downgrade (use allow\\_downgrade to override)\n"
+	if got := readTestFile(t, report); got != want {
+		t.Errorf("report mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestMarkdownReportRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		report markdownReport
+		dryRun bool
+		output string
+		want   string
+	}{
+		{
+			name:   "no changes in a dry run",
+			dryRun: true,
+			want:   "## Terraform version updates\n\n> [!NOTE]\n> Dry run: no files were changed.\n\nNo updates were applied.\n",
+		},
+		{
+			name: "terraform version and added module version",
+			report: markdownReport{changes: []bump.Change{
+				{Kind: bump.TerraformVersionChange, File: "a.tf", Version: ">= 1.9, < 2.0"},
+				{Kind: bump.ModuleChange, File: "a.tf", Source: "example/module", Version: "2.0.0", Previous: []string{""}, Blocks: []string{"1"}},
+			}},
+			want: "## Terraform version updates\n\n2 update(s) across 1 file(s).\n\n" +
+				"| Update | From | To | Blocks | Files |\n| --- | --- | --- | ---: | ---: |\n" +
+				"| Terraform `required_version` | — | `>= 1.9, < 2.0` | — | 1 |\n" +
+				"| Module `example/module` | none | `2.0.0` | 1 | 1 |\n\n" +
+				"### Changes by file\n\n<details>\n<summary><code>a.tf</code> (2 update(s))</summary>\n\n" +
+				"- Terraform `required_version`: `>= 1.9, < 2.0`\n" +
+				"- Module `example/module`: none → `2.0.0` (1 block(s))\n\n</details>\n",
+		},
+		{
+			name:   "skip quoted for Markdown",
+			report: markdownReport{skips: []bump.Skip{{File: "a.tf", Message: "Module `legacy_vpc` in a.tf (matches ignore pattern)", Filtered: true}}},
+			output: "md",
+			want:   "## Terraform version updates\n\nNo updates were applied.\n\n### Skipped blocks\n\n- Module `legacy_vpc` in a.tf (matches ignore pattern)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.report.render(tt.dryRun, tt.output)); got != tt.want {
+				t.Errorf("render mismatch:\n--- got ---\n%s--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownReportFlagValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "same file as the JSON report",
+			args: []string{"-report-file", "report.out", "-markdown-report", "./report.out"},
+			want: "Error: -markdown-report and -report-file must be different files",
+		},
+		{
+			name: "branch mode",
+			args: []string{"-branches", "release/*", "-markdown-report", "report.md"},
+			want: "Error: Cannot use -markdown-report with -branches",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"tf-version-bump", "-pattern", "*.tf", "-module", "example/module", "-to", "1.0.0"}, tt.args...)
+			result := runMainCommand(t, args)
+			if result.exitCode != 1 || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandAppliesMigrationsBeforeModuleUpdates(t *testing.T) {
+	dir := t.TempDir()
+	module := "module \"vpc\" {\n  source = \"git::https://github.com/example/terraform-aws-vpc.git?ref=v4.2.0\"\n}\n"
+	file := writeTestFile(t, dir, "main.tf", module)
+	config := writeTestFile(t, dir, "config.yml", `migrations:
+  - from_source: "git::https://github.com/example/terraform-aws-vpc.git"
+    to_source: "app.terraform.io/example/vpc/aws"
+    version: "5.0.0"
+modules:
+  - source: "app.terraform.io/example/vpc/aws"
+    version: "5.1.0"
+`)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-config", config})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := "module \"vpc\" {\n  source  = \"app.terraform.io/example/vpc/aws\"\n  version = \"5.1.0\"\n}\n"
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("content mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	for _, line := range []string{
+		"✓ Migrated module source 'git::https://github.com/example/terraform-aws-vpc.git' to 'app.terraform.io/example/vpc/aws' in " + file,
+		"Module migrations: 1 migration(s) applied",
+		"Modules: 1 update(s) applied",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+}
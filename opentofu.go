@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// When both main.tf and main.tofu exist in a directory, OpenTofu loads main.tofu and ignores
+// main.tf, so with -opentofu the overridden file is dropped from the selected files.
+
+// overridingTofuFile returns the .tofu counterpart of a .tf or .tf.json file, which OpenTofu loads
+// instead of the file when it exists.
+func overridingTofuFile(filename string) (string, bool) {
+	if base, ok := strings.CutSuffix(filename, ".tf.json"); ok {
+		return base + ".tofu.json", true
+	}
+	if base, ok := strings.CutSuffix(filename, ".tf"); ok {
+		return base + ".tofu", true
+	}
+	return "", false
+}
+
+// dropOverriddenFiles removes selected .tf and .tf.json files that OpenTofu ignores because a
+// same-named .tofu or .tofu.json file exists beside them. The .tofu file does not need to be
+// selected by the pattern itself.
+//
+// Parameters:
+//   - files: Selected file paths
+//   - verbose: If true, report each dropped file
+//   - outputFormat: Output format ("text" or "md")
+//
+// Returns:
+//   - []string: The selected files OpenTofu would load, in their original order
+func dropOverriddenFiles(files []string, verbose bool, outputFormat string) []string {
+	kept := make([]string, 0, len(files))
+	for _, filename := range files {
+		override, ok := overridingTofuFile(filename)
+		if ok {
+			if info, err := os.Stat(override); err == nil && !info.IsDir() {
+				if verbose {
+					fmt.Printf("  Skipping %s: overridden by %s\n", quote(filename, outputFormat), quote(override, outputFormat))
+				}
+				continue
+			}
+		}
+		kept = append(kept, filename)
+	}
+	return kept
+}
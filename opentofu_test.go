@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDropOverriddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.tf", "main.tofu", "versions.tf", "vars.tf.json", "vars.tofu.json", "outputs.tf.json"} {
+		writeTestFile(t, dir, name, "")
+	}
+	files := []string{
+		filepath.Join(dir, "main.tf"),
+		filepath.Join(dir, "outputs.tf.json"),
+		filepath.Join(dir, "vars.tf.json"),
+		filepath.Join(dir, "versions.tf"),
+	}
+
+	var got []string
+	stdout := captureStdout(t, func() {
+		got = dropOverriddenFiles(files, true, "text")
+	})
+	want := []string{filepath.Join(dir, "outputs.tf.json"), filepath.Join(dir, "versions.tf")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+	if !strings.Contains(stdout, "overridden by '"+filepath.Join(dir, "main.tofu")+"'") || !strings.Contains(stdout, "vars.tofu.json") {
+		t.Errorf("stdout = %q, want both overrides reported", stdout)
+	}
+}
+
+func TestCommandOpenTofuSkipsOverriddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	module := "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"4.0.0\"\n}\n"
+	overridden := writeTestFile(t, dir, "main.tf", module)
+	override := writeTestFile(t, dir, "main.tofu", strings.Replace(module, "terraform-aws-modules", "registry.opentofu.org/terraform-aws-modules", 1))
+	plain := writeTestFile(t, dir, "network.tf", module)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.{tf,tofu}", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0", "-opentofu"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if !strings.Contains(result.stdout, "Found 2 file(s)") {
+		t.Errorf("stdout = %q, want the overridden file dropped", result.stdout)
+	}
+	if got := readTestFile(t, overridden); got != module {
+		t.Errorf("overridden .tf file changed:\n%s", got)
+	}
+	for _, file := range []string{override, plain} {
+		if got := readTestFile(t, file); !strings.Contains(got, `version = "5.0.0"`) {
+			t.Errorf("%s not updated:\n%s", file, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// validatePolicyFlags validates the flags of policy mode.
+func validatePolicyFlags(flags *cliFlags) {
+	if flags.configFile != "" || flags.lint || flags.hasOperationFlags() {
+		fatalf("Error: Cannot use -policy with -config, -lint, or other operation flags (-module, -to, -terraform-version, -provider, -provider-source, -add-missing, -from, -ignore-version, -ignore-modules)")
+	}
+	if flags.reportFile != "" {
+		fatalf("Error: Cannot use -report-file with -policy")
+	}
+	if flags.hasUpdateBehaviourFlags() {
+		fatalf("Error: Cannot use -policy with -preserve-operator, -allow-downgrade, -prerelease, or -follow-references")
+	}
+}
+
+// runPolicyMode checks every file against the policy file, raising floors when -fix is set, and
+// prints the violations followed by a summary.
+//
+// Parameters:
+//   - files: The files to check
+//   - flags: The command-line flags, including the policy file and -fix
+//
+// Returns:
+//   - error: Non-nil when the policy cannot be loaded, a file cannot be processed, or any
+//     violation remains
+func runPolicyMode(files []string, flags *cliFlags) error {
+	policy, err := bump.LoadPolicy(flags.policyFile)
+	if err != nil {
+		//nolint:staticcheck // The capitalised prefix is user-facing CLI output.
+		return fmt.Errorf("Error loading policy file: %w", err)
+	}
+
+	var remaining, raised, fileErrors int
+	for _, file := range files {
+		violations, err := bump.CheckPolicy(file, policy, flags.fix, flags.options())
+		if err != nil {
+			log.Printf("Error processing %s: %v", file, err)
+			fileErrors++
+			continue
+		}
+		for _, violation := range violations {
+			if flags.fix && violation.Raised != "" {
+				prefix, action := "✓", "Raised"
+				if flags.dryRun {
+					prefix, action = "→", "Would raise"
+				}
+				fmt.Printf("%s %s %s from %s to %s in %s\n", prefix, action, violation.Subject, quote(violation.Value, flags.output), quote(violation.Raised, flags.output), file)
+				raised++
+				continue
+			}
+			fmt.Printf("%s:%d:%d: %s\n", file, violation.Pos.Line, violation.Pos.Column, violation.Message)
+			remaining++
+		}
+	}
+
+	printPolicySummary(raised, remaining, len(files)-fileErrors, flags)
+	if fileErrors > 0 {
+		return fmt.Errorf("%d file(s) could not be checked", fileErrors)
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d policy violation(s)", remaining)
+	}
+	return nil
+}
+
+// printPolicySummary prints the totals of a policy run.
+func printPolicySummary(raised, remaining, files int, flags *cliFlags) {
+	switch {
+	case flags.fix && flags.dryRun:
+		fmt.Printf("\nPolicy: would raise %d pin(s), %d violation(s) remaining in %d file(s)\n", raised, remaining, files)
+	case flags.fix:
+		fmt.Printf("\nPolicy: %d pin(s) raised, %d violation(s) remaining in %d file(s)\n", raised, remaining, files)
+	case remaining == 0:
+		fmt.Printf("\n✓ No policy violations in %d file(s)\n", files)
+	default:
+		fmt.Printf("\nPolicy: %d violation(s) in %d file(s)\n", remaining, files)
+	}
+}
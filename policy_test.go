@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandChecksAndFixesPolicy(t *testing.T) {
+	input := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0" # raise me
+}
+
+module "unpinned" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+module "other" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = "~> 19.0"
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+  }
+}
+`
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "main.tf", input)
+	jsonFile := writeTestFile(t, dir, "versions.tf.json", `{"terraform": {"required_providers": {"aws": {"source": "hashicorp/aws", "version": "~> 5.2"}}}}`)
+	policy := writeTestFile(t, dir, "policy.yml", `modules:
+  - source: "terraform-aws-modules/vpc/aws"
+    min_version: "5.1.0"
+providers:
+  - name: "aws"
+    min_version: "5.30"
+    max_version: "6.0.0"
+`)
+	pattern := dir + "/*.tf*"
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", pattern, "-policy", policy})
+	if result.exitCode != 1 || result.diagnostics != "4 policy violation(s)\n" {
+		t.Fatalf("result = %#v, want 4 violations", result)
+	}
+	for _, line := range []string{
+		file + ":3:13: Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '~> 5.0' permits versions below the minimum '5.1.0'",
+		file + ":6:1: Module 'unpinned' (source: 'terraform-aws-modules/vpc/aws') has no version, so it permits every version",
+		file + ":19:17: Provider 'aws' version '>= 5.0' permits versions below the minimum '5.30' and permits versions above the maximum '6.0.0'",
+		jsonFile + ":1:85: Provider 'aws' version '~> 5.2' permits versions below the minimum '5.30'",
+		"Policy: 4 violation(s) in 2 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+	if readTestFile(t, file) != input {
+		t.Fatal("check mode modified main.tf")
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "-pattern", pattern, "-policy", policy, "-fix"})
+	if result.exitCode != 1 || result.diagnostics != "2 policy violation(s)\n" {
+		t.Fatalf("result = %#v, want 2 remaining violations", result)
+	}
+	for _, line := range []string{
+		"✓ Raised Module 'vpc' (source: 'terraform-aws-modules/vpc/aws') from '~> 5.0' to '~> 5.1' in " + file,
+		"✓ Raised Provider 'aws' from '~> 5.2' to '~> 5.30' in " + jsonFile,
+		"Policy: 2 pin(s) raised, 2 violation(s) remaining in 2 file(s)",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+	if got, want := readTestFile(t, file), strings.Replace(input, `"~> 5.0" # raise me`, `"~> 5.1" # raise me`, 1); got != want {
+		t.Errorf("main.tf mismatch:\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+	if got := readTestFile(t, jsonFile); got != `{"terraform": {"required_providers": {"aws": {"source": "hashicorp/aws", "version": "~> 5.30"}}}}` {
+		t.Errorf("versions.tf.json = %s", got)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandReportsProviderMigrations(t *testing.T) {
+	dir := t.TempDir()
+	versions := "terraform {\n  required_providers {\n    widget = {\n      source  = \"example-community/widget\"\n      version = \"~> 1.4\"\n    }\n  }\n}\n"
+	file := writeTestFile(t, dir, "versions.tf", versions)
+	config := writeTestFile(t, dir, "config.yml", `provider_migrations:
+  - from_source: "example-community/widget"
+    to_source: "example/widget"
+    version: "~> 2.0"
+providers:
+  - name: "widget"
+    version: "~> 2.1"
+`)
+	report := filepath.Join(dir, "report.json")
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-config", config, "-report-file", report})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if got := readTestFile(t, file); !strings.Contains(got, `source  = "example/widget"`) || !strings.Contains(got, `version = "~> 2.1"`) {
+		t.Errorf("versions.tf not migrated and updated:\n%s", got)
+	}
+	for _, line := range []string{
+		"✓ Migrated provider 'widget' from 'example-community/widget' to 'example/widget' (version '~> 2.0') in " + file,
+		"Provider migrations: 1 migration(s) applied",
+		"Providers: 1 update(s) applied",
+	} {
+		if !strings.Contains(result.stdout, line) {
+			t.Errorf("stdout = %q, want %q", result.stdout, line)
+		}
+	}
+
+	wantReport := "{\n  \"schema_version\": 1,\n  \"module_blocks_updated\": 0,\n  \"provider_blocks_updated\": 1,\n" +
+		"  \"provider_migrations\": [\n    {\n      \"file\": \"" + file + "\",\n      \"name\": \"widget\",\n" +
+		"      \"from_source\": \"example-community/widget\",\n      \"to_source\": \"example/widget\",\n      \"version\": \"~> 2.0\"\n    }\n  ],\n" + directoryReport(dir, 2, 1)
+	if got := readTestFile(t, report); got != wantReport {
+		t.Errorf("report mismatch:\n--- got ---\n%s--- want ---\n%s", got, wantReport)
+	}
+}
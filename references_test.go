@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandDryRunReportsFollowedReferences(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", "module \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = local.vpc_version\n}\n")
+	locals := "locals {\n  vpc_version = \"4.0.0\"\n}\n"
+	localsFile := writeTestFile(t, dir, "locals.tf", locals)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "-pattern", dir + "/*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.0.0", "-follow-references", "-dry-run"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	if want := "  Would update local.vpc_version to '5.0.0' in " + localsFile; !strings.Contains(result.stdout, want) {
+		t.Errorf("stdout = %q, want %q", result.stdout, want)
+	}
+	if got := readTestFile(t, localsFile); got != locals {
+		t.Errorf("dry run modified locals.tf:\n%s", got)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+// Subcommands group the flags of each task: bump updates versions, list prints them, check and
+// lint report problems, init writes a starter config, validate-config checks config files, and
+// undo restores a -backup run.
+// A first argument that is not a subcommand is parsed as the flags of bump, so invocations written
+// before subcommands existed keep working.
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/yesdevnull/tf-version-bump/bump"
+)
+
+// subcommand is a task of the command with its own flags and help.
+type subcommand struct {
+	name    string
+	usage   string   // Arguments shown after the name in help
+	summary string   // One line, shown in the command list
+	flags   []string // Names of the flags it accepts, in help order
+	// args applies the positional arguments and the mode the subcommand selects, returning an
+	// error for arguments it does not accept
+	args func(flags *cliFlags, args []string) error
+}
+
+// Flags shared by every subcommand that reads Terraform files.
+var fileSelectionFlags = []string{"pattern", "terragrunt", "opentofu", "output"}
+
+// subcommands lists every subcommand in the order they are documented.
+var subcommands = []*subcommand{
+	{
+		name:    "bump",
+		usage:   "-pattern <glob> (-module <source> -to <version> | -provider <name> -to <constraint> | -terraform-version <constraint> | -config <file> | -align) [flags]",
+		summary: "Update module, provider, and Terraform versions (the default without a subcommand)",
+		flags: append(slices.Clone(fileSelectionFlags), "module", "to", "from", "ignore-version", "ignore-modules", "terraform-version",
+			"provider", "provider-source", "add-missing", "config", "group", "align", "align-scope", "force-add", "preserve-operator",
+			"allow-downgrade", "prerelease", "follow-references", "dry-run", "interactive", "backup", "verbose", "report-file",
+			"markdown-report", "branches", "commit-message"),
+		args: noArguments,
+	},
+	{
+		name:    "list",
+		usage:   "-pattern <glob> [flags]",
+		summary: "List the literal versions of module blocks and required_providers entries",
+		flags:   fileSelectionFlags,
+		args: func(flags *cliFlags, args []string) error {
+			flags.list = true
+			return noArguments(flags, args)
+		},
+	},
+	{
+		name:    "check",
+		usage:   "-pattern <glob> (-policy <file> | -advisories <file>) [-fix] [flags]",
+		summary: "Check versions against a policy or advisory file, optionally fixing them",
+		flags: append(slices.Clone(fileSelectionFlags), "policy", "advisories", "fix", "dry-run", "preserve-operator",
+			"allow-downgrade", "prerelease", "follow-references", "backup"),
+		args: func(flags *cliFlags, args []string) error {
+			if flags.policyFile == "" && flags.advisoryFile == "" {
+				return errors.New("Error: check requires -policy or -advisories") //nolint:staticcheck // User-facing CLI diagnostic.
+			}
+			return noArguments(flags, args)
+		},
+	},
+	{
+		name:    "lint",
+		usage:   "-pattern <glob> [-lint-rule <rule>=<severity>]... [flags]",
+		summary: "Report unpinned modules and providers without modifying files",
+		flags:   append(slices.Clone(fileSelectionFlags), "lint-rule"),
+		args: func(flags *cliFlags, args []string) error {
+			flags.lint = true
+			return noArguments(flags, args)
+		},
+	},
+	{
+		name:    "validate-config",
+		usage:   "<config-file>...",
+		summary: "Check config files without reading Terraform files",
+		args: func(flags *cliFlags, args []string) error {
+			if len(args) == 0 {
+				return errors.New("Error: validate-config requires at least one config file") //nolint:staticcheck // User-facing CLI diagnostic.
+			}
+			flags.validateConfigs = args
+			return nil
+		},
+	},
+	{
+		name:    "init",
+		usage:   "-pattern <glob> [flags] <config-file>",
+		summary: "Write a starter config of the versions in use ('-' for standard output)",
+		flags:   fileSelectionFlags,
+		args: func(flags *cliFlags, args []string) error {
+			if len(args) != 1 {
+				return errors.New("Error: init requires one config file to write, or '-' for standard output") //nolint:staticcheck // User-facing CLI diagnostic.
+			}
+			flags.exportConfig = args[0]
+			return nil
+		},
+	},
+	{
+		name:    "undo",
+		usage:   "<run-id>",
+		summary: "Restore the files saved by a -backup run, refusing any that changed since",
+		args: func(flags *cliFlags, args []string) error {
+			if len(args) != 1 {
+				return errors.New("Error: undo requires the id of one -backup run") //nolint:staticcheck // User-facing CLI diagnostic.
+			}
+			flags.undo = args[0]
+			return nil
+		},
+	},
+}
+
+// findSubcommand returns the subcommand named by the first argument, or nil when the arguments
+// start with a flag or there are none. "help" is answered here and exits.
+func findSubcommand(args []string) *subcommand {
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] == "help" {
+		runHelpCommand(args[1:])
+	}
+	for _, command := range subcommands {
+		if command.name == args[0] {
+			return command
+		}
+	}
+	return nil
+}
+
+// parseSubcommand parses the arguments of a subcommand with only its flags defined. -h prints the
+// subcommand's help and exits; an unknown flag or argument exits non-zero.
+func (flags *cliFlags) parseSubcommand(command *subcommand, args []string) {
+	set := flags.subcommandFlagSet(command)
+	set.SetOutput(flag.CommandLine.Output())
+	err := set.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		exitFunc(0)
+	}
+	if err != nil {
+		exitFunc(2)
+	}
+	flags.flagsSet = set.NFlag()
+	if err := command.args(flags, set.Args()); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// subcommandFlagSet returns a flag set with the flags of a subcommand. Every flag is defined on a
+// scratch set first, so that the flags the subcommand does not take keep their defaults.
+func (flags *cliFlags) subcommandFlagSet(command *subcommand) *flag.FlagSet {
+	all := flag.NewFlagSet(command.name, flag.ContinueOnError)
+	flags.define(all)
+	set := flag.NewFlagSet("tf-version-bump "+command.name, flag.ContinueOnError)
+	for _, name := range command.flags {
+		defined := all.Lookup(name)
+		set.Var(defined.Value, defined.Name, defined.Usage)
+	}
+	set.Usage = func() { printSubcommandUsage(set, command) }
+	return set
+}
+
+// noArguments rejects positional arguments for subcommands that take only flags.
+func noArguments(_ *cliFlags, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("Error: Unexpected argument '%s'", args[0]) //nolint:staticcheck // User-facing CLI diagnostic.
+	}
+	return nil
+}
+
+// printCommandUsage prints the subcommands and the flags accepted without one.
+func printCommandUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n  tf-version-bump <command> [flags]\n\nCommands:\n")
+	for _, command := range subcommands {
+		fmt.Fprintf(out, "  %-16s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintf(out, "\nRun 'tf-version-bump help <command>' for the flags of a command. Without a command, every flag\n")
+	fmt.Fprintf(out, "below is accepted and the command runs as bump:\n\n")
+	flag.PrintDefaults()
+}
+
+// printSubcommandUsage prints the usage line, summary, and flags of a subcommand.
+func printSubcommandUsage(set *flag.FlagSet, command *subcommand) {
+	out := set.Output()
+	fmt.Fprintf(out, "Usage:\n  tf-version-bump %s %s\n\n%s.\n", command.name, command.usage, command.summary)
+	if len(command.flags) > 0 {
+		fmt.Fprintf(out, "\nFlags:\n")
+		set.PrintDefaults()
+	}
+}
+
+// runHelpCommand prints the help of a subcommand, or the command list, to standard output and
+// exits.
+func runHelpCommand(args []string) {
+	if len(args) == 0 {
+		(&cliFlags{}).define(flag.CommandLine)
+		flag.CommandLine.SetOutput(os.Stdout)
+		printCommandUsage()
+		exitFunc(0)
+	}
+	for _, command := range subcommands {
+		if command.name == args[0] {
+			set := (&cliFlags{}).subcommandFlagSet(command)
+			set.SetOutput(os.Stdout)
+			set.Usage()
+			exitFunc(0)
+		}
+	}
+	fatalf("Error: Unknown command '%s'", args[0])
+}
+
+// runValidateConfigCommand loads each config file and reports whether it is valid, exiting
+// non-zero when any is not. Problems found by the schema rules are printed one per line as
+// file:line:column: message.
+func runValidateConfigCommand(files []string) {
+	invalid := 0
+	for _, file := range files {
+		_, err := bump.LoadConfig(file)
+		var problems bump.ConfigErrors
+		switch {
+		case errors.As(err, &problems):
+			for _, problem := range problems {
+				log.Printf("%s:%d:%d: %s", file, problem.Line, problem.Column, problem.Message)
+			}
+		case err != nil:
+			log.Printf("Error: %s: %v", file, err)
+		}
+		if err != nil {
+			invalid++
+			continue
+		}
+		fmt.Printf("✓ %s is valid\n", file)
+	}
+	if invalid > 0 {
+		fatalf("Error: %d of %d config file(s) are invalid", invalid, len(files))
+	}
+}
+
+// runListMode prints the literal version of every module block and required_providers entry.
+//
+// Returns:
+//   - error: Non-nil when a file cannot be read
+func runListMode(files []string, flags *cliFlags) error {
+	modules, providers, fileErrors := 0, 0, 0
+	for _, file := range files {
+		pins, err := bump.FindPinnedVersions(file, flags.options())
+		if err != nil {
+			log.Printf("Error processing %s: %v", file, err)
+			fileErrors++
+			continue
+		}
+		for _, pin := range pins {
+			if pin.Module {
+				fmt.Printf("%s:%d:%d: module %s (source: %s) version %s\n", file, pin.Pos.Line, pin.Pos.Column,
+					quote(pin.Block, flags.output), quote(pin.Name, flags.output), quote(pin.Version, flags.output))
+				modules++
+			} else {
+				fmt.Printf("%s:%d:%d: provider %s version %s\n", file, pin.Pos.Line, pin.Pos.Column,
+					quote(pin.Name, flags.output), quote(pin.Version, flags.output))
+				providers++
+			}
+		}
+	}
+	fmt.Printf("\n%d module block(s) and %d provider entry(ies) in %d file(s)\n", modules, providers, len(files)-fileErrors)
+	if fileErrors > 0 {
+		return fmt.Errorf("%d file(s) could not be read", fileErrors)
+	}
+	return nil
+}
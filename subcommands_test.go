@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const subcommandTestFile = `terraform {
+  required_version = ">= 1.5"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+`
+
+func TestListCommandPrintsVersions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", subcommandTestFile)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "list", "-pattern", "*.tf"})
+	if result.exitCode != -1 || result.diagnostics != "" {
+		t.Fatalf("result = %#v", result)
+	}
+	want := `Found 1 file(s) matching pattern '*.tf'
+main.tf:6:17: provider 'aws' version '~> 5.0'
+main.tf:13:13: module 'vpc' (source: 'terraform-aws-modules/vpc/aws') version '5.1.0'
+
+1 module block(s) and 1 provider entry(ies) in 1 file(s)
+`
+	if result.stdout != want {
+		t.Errorf("stdout mismatch:\n--- got ---\n%s--- want ---\n%s", result.stdout, want)
+	}
+	if got := readTestFile(t, "main.tf"); got != subcommandTestFile {
+		t.Errorf("list modified main.tf:\n%s", got)
+	}
+}
+
+func TestSubcommandsSelectTheirMode(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "main.tf", subcommandTestFile)
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "lint", "-pattern", "*.tf"})
+	if result.exitCode != -1 || !strings.Contains(result.stdout, "✓ No lint findings in 1 file(s)") {
+		t.Errorf("lint result = %#v", result)
+	}
+
+	// init writes the starter config alone to standard output
+	result = runMainCommand(t, []string{"tf-version-bump", "init", "-pattern", "*.tf", "-"})
+	if result.exitCode != -1 || !strings.HasPrefix(result.stdout, starterConfigHeader) {
+		t.Errorf("init result = %#v", result)
+	}
+
+	// bump and the flags without a subcommand make the same update
+	for _, args := range [][]string{
+		{"tf-version-bump", "bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.2.0", "-dry-run"},
+		{"tf-version-bump", "-pattern", "*.tf", "-module", "terraform-aws-modules/vpc/aws", "-to", "5.2.0", "-dry-run"},
+	} {
+		result = runMainCommand(t, args)
+		if result.exitCode != -1 || !strings.Contains(result.stdout, "→ Would update module source 'terraform-aws-modules/vpc/aws' to version '5.2.0' in main.tf") {
+			t.Errorf("%v result = %#v", args[1:], result)
+		}
+	}
+}
+
+func TestValidateConfigCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "valid.yml", "modules:\n  - source: terraform-aws-modules/vpc/aws\n    version: 5.2.0\n")
+	writeTestFile(t, dir, "invalid.yml", "modules:\n  - source: terraform-aws-modules/vpc/aws\n")
+	t.Chdir(dir)
+
+	result := runMainCommand(t, []string{"tf-version-bump", "validate-config", "valid.yml"})
+	if result.exitCode != -1 || result.stdout != "✓ valid.yml is valid\n" || result.diagnostics != "" {
+		t.Errorf("valid result = %#v", result)
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "validate-config", "valid.yml", "invalid.yml"})
+	if result.exitCode != 1 {
+		t.Fatalf("invalid result = %#v, want exit code 1", result)
+	}
+	if !strings.Contains(result.diagnostics, "invalid.yml:2:5: modules[0] is missing 'version' field\n") ||
+		!strings.Contains(result.diagnostics, "Error: 1 of 2 config file(s) are invalid") {
+		t.Errorf("diagnostics = %q", result.diagnostics)
+	}
+}
+
+func TestSubcommandArgumentErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"flag of another subcommand", []string{"list", "-pattern", "*.tf", "-module", "x"}, 2, ""},
+		{"unexpected argument", []string{"lint", "-pattern", "*.tf", "extra"}, 1, "Error: Unexpected argument 'extra'"},
+		{"check without a file", []string{"check", "-pattern", "*.tf"}, 1, "Error: check requires -policy or -advisories"},
+		{"init without a destination", []string{"init", "-pattern", "*.tf"}, 1, "Error: init requires one config file to write"},
+		{"validate-config without files", []string{"validate-config"}, 1, "Error: validate-config requires at least one config file"},
+		{"undo without a run id", []string{"undo"}, 1, "Error: undo requires the id of one -backup run"},
+		{"undo with a flag", []string{"undo", "-pattern", "*.tf", "run"}, 2, ""},
+		{"help for an unknown command", []string{"help", "nope"}, 1, "Error: Unknown command 'nope'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runMainCommand(t, append([]string{"tf-version-bump"}, tt.args...))
+			if result.exitCode != tt.code || !strings.Contains(result.diagnostics, tt.want) {
+				t.Errorf("result = %#v, want exit code %d with %q", result, tt.code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	result := runMainCommand(t, []string{"tf-version-bump", "help"})
+	if result.exitCode != 0 {
+		t.Fatalf("result = %#v, want exit code 0", result)
+	}
+	for _, command := range subcommands {
+		if !strings.Contains(result.stdout, "  "+command.name+" ") {
+			t.Errorf("help does not list %s:\n%s", command.name, result.stdout)
+		}
+	}
+
+	result = runMainCommand(t, []string{"tf-version-bump", "help", "lint"})
+	if result.exitCode != 0 || !strings.HasPrefix(result.stdout, "Usage:\n  tf-version-bump lint ") {
+		t.Fatalf("result = %#v", result)
+	}
+	if !strings.Contains(result.stdout, "-lint-rule") || strings.Contains(result.stdout, "-module") {
+		t.Errorf("lint help lists the wrong flags:\n%s", result.stdout)
+	}
+}